@@ -26,9 +26,10 @@ func (e Error) Error() string {
 }
 
 type Device struct {
-	bus     drivers.I2C
-	buf     []byte
-	Address uint8
+	bus         drivers.I2C
+	buf         []byte
+	Address     uint8
+	temperature int32
 }
 
 // New returns ADT7410 device for the provided I2C bus using default address.
@@ -64,6 +65,25 @@ func (d *Device) ReadTemperature() (temperature int32, err error) {
 	return (int32(d.readUint16(RegTempValueMSB)) * 1000) / 128, nil
 }
 
+// Update reads the temperature and stores it for the Temperature method.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature == 0 {
+		return nil
+	}
+	temperature, err := d.ReadTemperature()
+	if err != nil {
+		return err
+	}
+	d.temperature = temperature
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
 // ReadTempC returns the value in the temperature value register, in Celsius.
 func (d *Device) ReadTempC() float32 {
 	t := d.readUint16(RegTempValueMSB)
@@ -79,6 +79,27 @@ func (d *Device) Read() error {
 	return ErrTimeout
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Humidity methods. Both values come from a single measurement.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Humidity) == 0 {
+		return nil
+	}
+	return d.Read()
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return int32(int64(d.temp)*200000/0x100000) - 50000
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return int32(int64(d.humidity) * 10000 / 0x100000)
+}
+
 func (d *Device) RawHumidity() uint32 {
 	return d.humidity
 }
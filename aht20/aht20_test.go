@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
 	"tinygo.org/x/drivers/tester"
 )
 
@@ -48,6 +49,19 @@ func TestRead(t *testing.T) {
 	c.Assert(dev.DeciRelHumidity(), qt.Equals, int32(363))
 }
 
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fdev := tester.NewI2CDeviceCmd(c, Address)
+	fdev.Commands = defaultCommands()
+	bus.AddDevice(fdev)
+
+	dev := New(bus)
+	c.Assert(dev.Update(drivers.Temperature), qt.IsNil)
+	c.Assert(dev.Temperature(), qt.Equals, int32(25088))
+	c.Assert(dev.Humidity(), qt.Equals, int32(3635))
+}
+
 func defaultCommands() map[uint8]*tester.Cmd {
 	return map[uint8]*tester.Cmd{
 		CMD_INITIALIZE: {
@@ -56,6 +56,9 @@ type Device struct {
 	Address                 uint16
 	calibrationCoefficients calibrationCoefficients
 	Config                  Config
+	temperature             int32
+	pressure                int32
+	humidity                int32
 }
 
 // New creates a new BME280 connection. The I2C bus must already be
@@ -210,6 +213,42 @@ func (d *Device) ReadHumidity() (int32, error) {
 	return humidity, nil
 }
 
+// Update reads the sensor data and stores the measurements for the
+// Temperature, Pressure and Humidity methods. All three values come from a
+// single burst read, so they are updated together.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Pressure|drivers.Humidity) == 0 {
+		return nil
+	}
+	data, err := d.readData()
+	if err != nil {
+		return err
+	}
+	temp, tFine := d.calculateTemp(data)
+	d.temperature = temp
+	d.pressure = d.calculatePressure(data, tFine)
+	d.humidity = d.calculateHumidity(data, tFine)
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Pressure returns the pressure read in the last Update call, in milli
+// pascals (mPa).
+func (d *Device) Pressure() int32 {
+	return d.pressure
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return d.humidity
+}
+
 // ReadAltitude returns the current altitude in meters based on the
 // current barometric pressure and estimated pressure at sea level.
 // Calculation is based on code from Adafruit BME280 library
@@ -0,0 +1,83 @@
+package bme280
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+func TestReadings(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewBME280(c, Address)
+	fake.Temperature = 23450
+	fake.Pressure = 101325000
+	fake.Humidity = 55500
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	c.Assert(dev.Connected(), qt.IsTrue)
+	dev.Configure()
+
+	temp, err := dev.ReadTemperature()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp, qt.Equals, int32(23450))
+	pressure, err := dev.ReadPressure()
+	c.Assert(err, qt.IsNil)
+	c.Assert(pressure, qt.Equals, int32(101325000))
+	humidity, err := dev.ReadHumidity()
+	c.Assert(err, qt.IsNil)
+	c.Assert(humidity/10, qt.Equals, int32(555))
+}
+
+func TestForcedMode(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewBME280(c, Address)
+	fake.Temperature = -5000
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	dev.ConfigureWithSettings(Config{
+		Mode:        ModeForced,
+		Temperature: Sampling1X,
+		Pressure:    Sampling1X,
+		Humidity:    Sampling1X,
+	})
+	temp, err := dev.ReadTemperature()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp, qt.Equals, int32(-5000))
+
+	fake.Temperature = 30000
+	temp, err = dev.ReadTemperature()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp, qt.Equals, int32(30000))
+}
+
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewBME280(c, Address)
+	fake.Temperature = 18250
+	fake.Pressure = 99800000
+	fake.Humidity = 62000
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	dev.Configure()
+	var sensor drivers.Sensor = &dev
+	c.Assert(sensor.Update(drivers.Temperature|drivers.Humidity), qt.IsNil)
+	c.Assert(dev.Temperature(), qt.Equals, int32(18250))
+	c.Assert(dev.Pressure(), qt.Equals, int32(99800000))
+	c.Assert(dev.Humidity()/10, qt.Equals, int32(620))
+
+	// Measurements are cached until the next update.
+	fake.Temperature = 20000
+	c.Assert(dev.Temperature(), qt.Equals, int32(18250))
+	c.Assert(sensor.Update(drivers.Acceleration), qt.IsNil)
+	c.Assert(dev.Temperature(), qt.Equals, int32(18250))
+	c.Assert(sensor.Update(drivers.AllMeasurements), qt.IsNil)
+	c.Assert(dev.Temperature(), qt.Equals, int32(20000))
+}
@@ -37,6 +37,8 @@ type Device struct {
 	Address                 uint16
 	mode                    OversamplingMode
 	calibrationCoefficients calibrationCoefficients
+	temperature             int32
+	pressure                int32
 }
 
 // New creates a new BMP180 connection. The I2C bus must already be
@@ -125,6 +127,38 @@ func (d *Device) ReadPressure() (pressure int32, err error) {
 	return 1000 * (p + ((x1 + x2 + 3791) >> 4)), nil
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Pressure methods.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature != 0 {
+		temperature, err := d.ReadTemperature()
+		if err != nil {
+			return err
+		}
+		d.temperature = temperature
+	}
+	if which&drivers.Pressure != 0 {
+		pressure, err := d.ReadPressure()
+		if err != nil {
+			return err
+		}
+		d.pressure = pressure
+	}
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Pressure returns the pressure read in the last Update call, in milli
+// pascals (mPa).
+func (d *Device) Pressure() int32 {
+	return d.pressure
+}
+
 // ReadAltitude returns the current altitude in meters based on the
 // current barometric pressure and estimated pressure at sea level.
 // Calculation is based on code from Adafruit BME280 library
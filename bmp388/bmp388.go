@@ -35,6 +35,9 @@ type Device struct {
 	Address uint8
 	cali    calibrationCoefficients
 	Config  Config
+
+	temperature int32
+	pressure    int32
 }
 
 type calibrationCoefficients struct {
@@ -157,7 +160,12 @@ func (d *Device) ReadPressure() (int32, error) {
 	if err != nil {
 		return 0, err
 	}
+	return d.compensatePressure(tlin, rawPress), nil
+}
 
+// compensatePressure returns the pressure in centipascals from the raw
+// pressure reading and the linearized temperature.
+func (d *Device) compensatePressure(tlin, rawPress int64) int32 {
 	// code pulled from bmp388 C driver: https://github.com/BoschSensortec/BMP3-Sensor-API/blob/master/bmp3.c
 	partialData1 := tlin * tlin
 	partialData2 := partialData1 / 64
@@ -184,7 +192,41 @@ func (d *Device) ReadPressure() (int32, error) {
 	partialData3 = (partialData2 * rawPress) / 128
 	partialData4 = (offset / 4) + partialData1 + partialData5 + partialData3
 	compPress := ((uint64(partialData4) * 25) / uint64(1099511627776))
-	return int32(compPress), nil
+	return int32(compPress)
+}
+
+// Update reads the sensor and stores the measurements for the Temperature
+// and Pressure methods.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Pressure) == 0 {
+		return nil
+	}
+	// The pressure is compensated with the temperature, so it is always read.
+	tlin, err := d.tlinCompensate()
+	if err != nil {
+		return err
+	}
+	d.temperature = int32((tlin*25)/16384) * 10
+	if which&drivers.Pressure != 0 {
+		rawPress, err := d.readSensorData(RegPress)
+		if err != nil {
+			return err
+		}
+		d.pressure = d.compensatePressure(tlin, rawPress) * 10
+	}
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Pressure returns the pressure read in the last Update call, in milli
+// pascals (mPa).
+func (d *Device) Pressure() int32 {
+	return d.pressure
 }
 
 // SoftReset commands the BMP388 to reset of all user configuration settings
@@ -2,6 +2,10 @@ package ds3231
 
 import (
 	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
 )
 
 func TestPositiveMilliCelsius(t *testing.T) {
@@ -74,3 +78,26 @@ func TestNegativeMilliCelsius(t *testing.T) {
 		t.Fatal(t1000)
 	}
 }
+
+func TestSetReadTime(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewDS3231(c)
+	fake.Temperature = 24750
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	c.Assert(dev.IsTimeValid(), qt.IsFalse)
+	dt := time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)
+	c.Assert(dev.SetTime(dt), qt.IsNil)
+	c.Assert(dev.IsTimeValid(), qt.IsTrue)
+	c.Assert(dev.IsRunning(), qt.IsTrue)
+
+	now, err := dev.ReadTime()
+	c.Assert(err, qt.IsNil)
+	c.Assert(now.Sub(dt) >= 0 && now.Sub(dt) <= time.Second, qt.IsTrue, qt.Commentf("%v", now))
+
+	temp, err := dev.ReadTemperature()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp, qt.Equals, int32(24750))
+}
@@ -0,0 +1,22 @@
+//go:build tinygo
+
+package espat
+
+import (
+	"machine"
+
+	"tinygo.org/x/drivers"
+)
+
+type Config struct {
+	// UART config
+	Uart *machine.UART
+	Tx   machine.Pin
+	Rx   machine.Pin
+}
+
+// openUART configures the UART connected to the ESP8266/ESP32.
+func (cfg *Config) openUART() drivers.UART {
+	cfg.Uart.Configure(machine.UARTConfig{TX: cfg.Tx, RX: cfg.Rx})
+	return cfg.Uart
+}
@@ -0,0 +1,16 @@
+//go:build !tinygo
+
+package espat
+
+import "tinygo.org/x/drivers"
+
+// Config gives the UART connected to the ESP8266/ESP32. Outside of TinyGo,
+// it is used as is, for example by tests emulating the AT firmware.
+type Config struct {
+	Uart drivers.UART
+}
+
+// openUART returns the UART connected to the ESP8266/ESP32.
+func (cfg *Config) openUART() drivers.UART {
+	return cfg.Uart
+}
@@ -21,9 +21,10 @@
 package espat // import "tinygo.org/x/drivers/espat"
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"machine"
+	"io"
 	"net"
 	"net/netip"
 	"strconv"
@@ -31,50 +32,69 @@ import (
 	"sync"
 	"time"
 
+	"tinygo.org/x/drivers"
 	"tinygo.org/x/drivers/netdev"
 	"tinygo.org/x/drivers/netlink"
 )
 
-type Config struct {
-	// UART config
-	Uart *machine.UART
-	Tx   machine.Pin
-	Rx   machine.Pin
-}
+// maxLinks is the number of connections of the ESP8266/ESP32 in multiple
+// connections mode, with link IDs 0 to 4.
+const maxLinks = 5
+
+// maxSockets is the number of sockets, one for each link and one listening
+// socket for the TCP server.
+const maxSockets = maxLinks + 1
+
+// pollInterval is the time to wait for data from the ESP8266/ESP32.
+const pollInterval = 10 * time.Millisecond
 
 type socket struct {
-	inUse    bool
 	protocol int
 	laddr    netip.AddrPort
+	raddr    netip.AddrPort
+	// link is the link ID of the connection, or -1 if there is none
+	link      int
+	listening bool
+	// closed is set when the connection is closed by the peer
+	closed bool
+	// data received from the connection forwarded by the ESP8266/ESP32
+	data []byte
 }
 
 type Device struct {
 	cfg  *Config
-	uart *machine.UART
+	uart drivers.UART
 	// command responses that come back from the ESP8266/ESP32
 	response []byte
-	// data received from a TCP/UDP connection forwarded by the ESP8266/ESP32
-	data   []byte
-	socket socket
-	mu     sync.Mutex
+	// bytes read from the ESP8266/ESP32, not yet processed
+	rx []byte
+	// link ID and remaining length of the +IPD data being received
+	ipdLink int
+	ipdLeft int
+	sockets map[int]*socket // keyed by sockfd
+	links   [maxLinks]*socket
+	// server is the listening socket of the TCP server, and backlog the
+	// connections to the server waiting for Accept
+	server  *socket
+	backlog []*socket
+	// refused are the links of the connections made while no server is
+	// listening, to be closed
+	refused []int
+	mu      sync.Mutex
 }
 
 func NewDevice(cfg *Config) *Device {
 	return &Device{
 		cfg:      cfg,
-		response: make([]byte, 1500),
-		data:     make([]byte, 0, 1500),
+		response: make([]byte, 0, 1500),
+		rx:       make([]byte, 0, 512),
+		sockets:  make(map[int]*socket),
 	}
 }
 
-func (d *Device) NetConnect(params *netlink.ConnectParams) error {
-
-	if len(params.Ssid) == 0 {
-		return netlink.ErrMissingSSID
-	}
-
-	d.uart = d.cfg.Uart
-	d.uart.Configure(machine.UARTConfig{TX: d.cfg.Tx, RX: d.cfg.Rx})
+// start sets up the UART and checks the communication with the ESP8266/ESP32.
+func (d *Device) start() error {
+	d.uart = d.cfg.openUART()
 
 	// Connect to ESP8266/ESP32
 	fmt.Printf("Connecting to device...")
@@ -92,6 +112,18 @@ func (d *Device) NetConnect(params *netlink.ConnectParams) error {
 	}
 
 	fmt.Printf("CONNECTED\r\n")
+	return nil
+}
+
+func (d *Device) NetConnect(params *netlink.ConnectParams) error {
+
+	if len(params.Ssid) == 0 {
+		return netlink.ErrMissingSSID
+	}
+
+	if err := d.start(); err != nil {
+		return err
+	}
 
 	// Connect to Wifi AP
 	fmt.Printf("Connecting to Wifi SSID '%s'...", params.Ssid)
@@ -106,6 +138,12 @@ func (d *Device) NetConnect(params *netlink.ConnectParams) error {
 
 	fmt.Printf("CONNECTED\r\n")
 
+	// Sockets use the multiple connections mode, with a link ID for each
+	// connection
+	if err := d.SetMux(TCPMuxMultiple); err != nil {
+		return err
+	}
+
 	ip, err := d.Addr()
 	if err != nil {
 		return err
@@ -125,6 +163,35 @@ func (d *Device) NetNotify(cb func(netlink.Event)) {
 	fmt.Printf("\r\n%s\r\n", netlink.ErrNotSupported)
 }
 
+// NetScan scans for Wifi access points.  The ESP8266/ESP32 is started in
+// station mode if it was not connected yet.
+func (d *Device) NetScan() ([]netlink.AccessPoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.uart == nil {
+		if err := d.start(); err != nil {
+			return nil, err
+		}
+		if err := d.SetWifiMode(WifiModeClient); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := d.GetAPs()
+	if err != nil {
+		return nil, netlink.ErrScanFailed
+	}
+
+	var aps []netlink.AccessPoint
+	for _, line := range strings.Split(string(resp), "\n") {
+		if ap, ok := parseAP(strings.TrimSpace(line)); ok {
+			aps = append(aps, ap)
+		}
+	}
+	return aps, nil
+}
+
 func (d *Device) GetHostByName(name string) (netip.Addr, error) {
 	ip, err := d.GetDNS(name)
 	if err != nil {
@@ -152,6 +219,37 @@ func (d *Device) Addr() (netip.Addr, error) {
 	return netip.Addr{}, fmt.Errorf("Error getting IP address")
 }
 
+// newSockfd returns the next available sockfd
+func (d *Device) newSockfd() int {
+	for sockfd := 0; ; sockfd++ {
+		if _, ok := d.sockets[sockfd]; !ok {
+			return sockfd
+		}
+	}
+}
+
+// newLink returns a free link ID for a new connection, or -1 if none is
+// available. The ESP8266/ESP32 assigns the lowest free link IDs to the
+// connections to the server, so start from the highest one.
+func (d *Device) newLink() int {
+	for link := maxLinks - 1; link >= 0; link-- {
+		if d.links[link] == nil && !d.isRefused(link) {
+			return link
+		}
+	}
+	return -1
+}
+
+// isRefused returns whether a link is a refused connection not closed yet.
+func (d *Device) isRefused(link int) bool {
+	for _, l := range d.refused {
+		if l == link {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Device) Socket(domain int, stype int, protocol int) (int, error) {
 
 	switch domain {
@@ -168,37 +266,71 @@ func (d *Device) Socket(domain int, stype int, protocol int) (int, error) {
 		return -1, netdev.ErrProtocolNotSupported
 	}
 
-	// Only supporting single connection mode, so only one socket at a time
-	if d.socket.inUse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.sockets) >= maxSockets {
 		return -1, netdev.ErrNoMoreSockets
 	}
-	d.socket.inUse = true
-	d.socket.protocol = protocol
 
-	return 0, nil
+	sockfd := d.newSockfd()
+	d.sockets[sockfd] = &socket{
+		protocol: protocol,
+		link:     -1,
+	}
+
+	return sockfd, nil
 }
 
 func (d *Device) Bind(sockfd int, ip netip.AddrPort) error {
-	d.socket.laddr = ip
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+	socket.laddr = ip
 	return nil
 }
 
 func (d *Device) Connect(sockfd int, host string, ip netip.AddrPort) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	link := d.newLink()
+	if link == -1 {
+		return netdev.ErrNoMoreSockets
+	}
+	// Register the link first, to receive the data that may follow the
+	// connection
+	d.links[link] = socket
+	socket.link = link
+
 	var err error
 	var addr = ip.Addr().String()
 	var rport = strconv.Itoa(int(ip.Port()))
-	var lport = strconv.Itoa(int(d.socket.laddr.Port()))
+	var lport = strconv.Itoa(int(socket.laddr.Port()))
 
-	switch d.socket.protocol {
+	switch socket.protocol {
 	case netdev.IPPROTO_TCP:
-		err = d.ConnectTCPSocket(addr, rport)
+		err = d.ConnectTCPSocket(link, addr, rport)
 	case netdev.IPPROTO_UDP:
-		err = d.ConnectUDPSocket(addr, rport, lport)
+		err = d.ConnectUDPSocket(link, addr, rport, lport)
 	case netdev.IPPROTO_TLS:
-		err = d.ConnectSSLSocket(host, rport)
+		err = d.ConnectSSLSocket(link, host, rport)
 	}
 
 	if err != nil {
+		if d.links[link] == socket {
+			d.links[link] = nil
+		}
+		socket.link = -1
 		if host == "" {
 			return fmt.Errorf("Connect to %s timed out", ip)
 		} else {
@@ -206,30 +338,86 @@ func (d *Device) Connect(sockfd int, host string, ip netip.AddrPort) error {
 		}
 	}
 
+	socket.raddr = ip
 	return nil
 }
 
 func (d *Device) Listen(sockfd int, backlog int) error {
-	switch d.socket.protocol {
-	case netdev.IPPROTO_UDP:
-	default:
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	// UDP sockets receive without listening
+	if socket.protocol != netdev.IPPROTO_TCP {
 		return netdev.ErrProtocolNotSupported
 	}
+
+	// The ESP8266/ESP32 runs a single TCP server
+	if d.server != nil {
+		return netdev.ErrNoMoreSockets
+	}
+	if err := d.StartServer(strconv.Itoa(int(socket.laddr.Port()))); err != nil {
+		return err
+	}
+	socket.listening = true
+	d.server = socket
+
 	return nil
 }
 
 func (d *Device) Accept(sockfd int) (int, netip.AddrPort, error) {
-	return -1, netip.AddrPort{}, netdev.ErrNotSupported
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+	}
+	if !socket.listening {
+		return -1, netip.AddrPort{}, netdev.ErrProtocolNotSupported
+	}
+
+	for {
+		d.poll()
+
+		if len(d.backlog) > 0 {
+			client := d.backlog[0]
+			d.backlog = d.backlog[1:]
+
+			if !client.closed {
+				client.raddr, _ = d.GetLinkRemoteAddr(client.link)
+			}
+			client.laddr = socket.laddr
+
+			clientfd := d.newSockfd()
+			d.sockets[clientfd] = client
+			return clientfd, client.raddr, nil
+		}
+
+		// Check if the server was closed meanwhile
+		if d.server != socket {
+			return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+		}
+
+		// Unlock while we sleep, so others can make progress
+		d.mu.Unlock()
+		time.Sleep(pollInterval)
+		d.mu.Lock()
+	}
 }
 
-func (d *Device) sendChunk(sockfd int, buf []byte, deadline time.Time) (int, error) {
+func (d *Device) sendChunk(link int, buf []byte, deadline time.Time) (int, error) {
 	// Check if we've timed out
 	if !deadline.IsZero() {
 		if time.Now().After(deadline) {
 			return -1, netdev.ErrTimeout
 		}
 	}
-	err := d.StartSocketSend(len(buf))
+	err := d.StartSocketSend(link, len(buf))
 	if err != nil {
 		return -1, err
 	}
@@ -249,15 +437,26 @@ func (d *Device) Send(sockfd int, buf []byte, flags int, deadline time.Time) (in
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
 	// Break large bufs into chunks so we don't overrun the hw queue
 
 	chunkSize := 1436
 	for i := 0; i < len(buf); i += chunkSize {
+		// Get the connection state up to date
+		d.poll()
+		if socket.closed || socket.link == -1 {
+			return -1, io.EOF
+		}
+
 		end := i + chunkSize
 		if end > len(buf) {
 			end = len(buf)
 		}
-		_, err := d.sendChunk(sockfd, buf[i:end], deadline)
+		_, err := d.sendChunk(socket.link, buf[i:end], deadline)
 		if err != nil {
 			return -1, err
 		}
@@ -271,6 +470,11 @@ func (d *Device) Recv(sockfd int, buf []byte, flags int, deadline time.Time) (in
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
 	var length = len(buf)
 
 	// Limit length read size to chunk large read requests
@@ -279,6 +483,20 @@ func (d *Device) Recv(sockfd int, buf []byte, flags int, deadline time.Time) (in
 	}
 
 	for {
+		d.poll()
+
+		// Receive into buf, if any data available
+		if len(socket.data) > 0 {
+			n := copy(buf[:length], socket.data)
+			socket.data = socket.data[:copy(socket.data, socket.data[n:])]
+			return n, nil
+		}
+
+		// Check if the connection was closed
+		if socket.closed {
+			return -1, io.EOF
+		}
+
 		// Check if we've timed out
 		if !deadline.IsZero() {
 			if time.Now().After(deadline) {
@@ -286,18 +504,10 @@ func (d *Device) Recv(sockfd int, buf []byte, flags int, deadline time.Time) (in
 			}
 		}
 
-		n, err := d.ReadSocket(buf[:length])
-		if err != nil {
-			return -1, err
-		}
-		if n == 0 {
-			d.mu.Unlock()
-			time.Sleep(100 * time.Millisecond)
-			d.mu.Lock()
-			continue
-		}
-
-		return n, nil
+		// Unlock while we sleep, so others can make progress
+		d.mu.Unlock()
+		time.Sleep(pollInterval)
+		d.mu.Lock()
 	}
 }
 
@@ -305,8 +515,40 @@ func (d *Device) Close(sockfd int) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	d.socket.inUse = false
-	return d.DisconnectSocket()
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+	delete(d.sockets, sockfd)
+
+	var err error
+	if socket.listening {
+		err = d.StopServer()
+		// Close the connections that were not accepted
+		for _, client := range d.backlog {
+			if !client.closed {
+				d.closeLink(client)
+			}
+		}
+		d.backlog = nil
+		d.server = nil
+	}
+	if socket.link != -1 {
+		err = d.closeLink(socket)
+	}
+	return err
+}
+
+// closeLink closes the connection of a socket.
+func (d *Device) closeLink(socket *socket) error {
+	link := socket.link
+	err := d.DisconnectSocket(link)
+	// The link may have been released by a CLOSED notification meanwhile
+	if d.links[link] == socket {
+		d.links[link] = nil
+	}
+	socket.link = -1
+	return err
 }
 
 func (d *Device) SetSockOpt(sockfd int, level int, opt int, value interface{}) error {
@@ -339,27 +581,27 @@ func (d *Device) Read(b []byte) (n int, err error) {
 const pause = 300
 
 // Execute sends an AT command to the ESP8266/ESP32.
-func (d Device) Execute(cmd string) error {
+func (d *Device) Execute(cmd string) error {
 	_, err := d.Write([]byte("AT" + cmd + "\r\n"))
 	return err
 }
 
 // Query sends an AT command to the ESP8266/ESP32 that returns the
 // current value for some configuration parameter.
-func (d Device) Query(cmd string) (string, error) {
+func (d *Device) Query(cmd string) (string, error) {
 	_, err := d.Write([]byte("AT" + cmd + "?\r\n"))
 	return "", err
 }
 
 // Set sends an AT command with params to the ESP8266/ESP32 for a
 // configuration value to be set.
-func (d Device) Set(cmd, params string) error {
+func (d *Device) Set(cmd, params string) error {
 	_, err := d.Write([]byte("AT" + cmd + "=" + params + "\r\n"))
 	return err
 }
 
 // Version returns the ESP8266/ESP32 firmware version info.
-func (d Device) Version() []byte {
+func (d *Device) Version() []byte {
 	d.Execute(Version)
 	r, err := d.Response(2000)
 	if err != nil {
@@ -370,7 +612,7 @@ func (d Device) Version() []byte {
 }
 
 // Echo sets the ESP8266/ESP32 echo setting.
-func (d Device) Echo(set bool) {
+func (d *Device) Echo(set bool) {
 	if set {
 		d.Execute(EchoConfigOn)
 	} else {
@@ -383,101 +625,201 @@ func (d Device) Echo(set bool) {
 // Reset restarts the ESP8266/ESP32 firmware. Due to how the baud rate changes,
 // this messes up communication with the ESP8266/ESP32 module. So make sure you know
 // what you are doing when you call this.
-func (d Device) Reset() {
+func (d *Device) Reset() {
 	d.Execute(Restart)
 	d.Response(100)
 }
 
-// ReadSocket returns the data that has already been read in from the responses.
-func (d *Device) ReadSocket(b []byte) (n int, err error) {
-	// make sure no data in buffer
-	d.Response(300)
-
-	count := len(b)
-	if len(b) >= len(d.data) {
-		// copy it all, then clear socket data
-		count = len(d.data)
-		copy(b, d.data[:count])
-		d.data = d.data[:0]
-	} else {
-		// copy all we can, then keep the remaining socket data around
-		copy(b, d.data[:count])
-		copy(d.data, d.data[count:])
-		d.data = d.data[:len(d.data)-count]
-	}
-
-	return count, nil
-}
-
 // Response gets the next response bytes from the ESP8266/ESP32.
 // The call will retry for up to timeout milliseconds before returning nothing.
+// Data received from connections and connection notifications are processed
+// meanwhile, and are not part of the response.
 func (d *Device) Response(timeout int) ([]byte, error) {
-	// read data
-	var size int
-	var start, end int
-	pause := 100 // pause to wait for 100 ms
-	retries := timeout / pause
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	d.response = d.response[:0]
+
+	for {
+		line, ok := d.readLine()
+		if !ok {
+			// wait longer?
+			if time.Now().After(deadline) {
+				return nil, errors.New("response timeout error:" + string(d.response))
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		d.response = append(d.response, line...)
+		switch strings.TrimSpace(string(line)) {
+		case "OK", "SEND OK", ">":
+			// the command worked, or is ready to receive data
+			return d.response, nil
+		case "ERROR", "FAIL", "SEND FAIL":
+			// the command failed
+			return d.response, errors.New("response error:" + string(d.response))
+		}
+	}
+}
 
+// poll processes the data received from connections and the connection
+// notifications that are available, discarding any other response.
+func (d *Device) poll() {
 	for {
-		size = d.uart.Buffered()
+		if _, ok := d.readLine(); !ok {
+			break
+		}
+	}
+
+	// Close the refused connections, now that no command is running
+	for len(d.refused) > 0 {
+		link := d.refused[0]
+		d.refused = d.refused[1:]
+		d.DisconnectSocket(link)
+	}
+}
 
-		if size > 0 {
-			end += size
-			d.uart.Read(d.response[start:end])
+// readLine reads the bytes available from the UART, and returns the next
+// line of a command response, if any. The "+IPD" data of connections and the
+// "<link ID>,CONNECT" and "<link ID>,CLOSED" notifications are processed and
+// skipped.
+func (d *Device) readLine() ([]byte, bool) {
+	if n := d.uart.Buffered(); n > 0 {
+		if free := cap(d.rx) - len(d.rx); n > free {
+			n = free
+		}
+		n, _ = d.uart.Read(d.rx[len(d.rx) : len(d.rx)+n])
+		d.rx = d.rx[:len(d.rx)+n]
+	}
 
-			// if "+IPD" then read socket data
-			if strings.Contains(string(d.response[:end]), "+IPD") {
-				// handle socket data
-				return nil, d.parseIPD(end)
+	for len(d.rx) > 0 {
+		// data of a connection
+		if d.ipdLeft > 0 {
+			n := d.ipdLeft
+			if n > len(d.rx) {
+				n = len(d.rx)
 			}
-
-			// if "OK" then the command worked
-			if strings.Contains(string(d.response[:end]), "OK") {
-				return d.response[start:end], nil
+			if socket := d.links[d.ipdLink]; socket != nil {
+				socket.data = append(socket.data, d.rx[:n]...)
 			}
+			d.ipdLeft -= n
+			d.consume(n)
+			continue
+		}
 
-			// if "Error" then the command failed
-			if strings.Contains(string(d.response[:end]), "ERROR") {
-				return d.response[start:end], errors.New("response error:" + string(d.response[start:end]))
+		if bytes.HasPrefix(d.rx, []byte("+IPD,")) {
+			end := bytes.IndexByte(d.rx, ':')
+			if end == -1 {
+				if len(d.rx) == cap(d.rx) {
+					// not expected data here, drop it
+					d.consume(len(d.rx))
+				}
+				return nil, false
 			}
+			d.parseIPD(string(d.rx[5:end]))
+			d.consume(end + 1)
+			continue
+		}
+		if len(d.rx) < 5 && bytes.HasPrefix([]byte("+IPD,"), d.rx) {
+			// wait for the rest of the +IPD header
+			return nil, false
+		}
 
-			// if anything else, then keep reading data in?
-			start = end
+		if d.rx[0] == '>' {
+			// ready to receive data to send, without line end
+			d.consume(1)
+			return []byte(">"), true
 		}
 
-		// wait longer?
-		retries--
-		if retries == 0 {
-			return nil, errors.New("response timeout error:" + string(d.response[start:end]))
+		end := bytes.IndexByte(d.rx, '\n')
+		if end == -1 {
+			if len(d.rx) < cap(d.rx) {
+				return nil, false
+			}
+			// line longer than the buffer
+			end = len(d.rx) - 1
 		}
+		// copy the line after the response, where it is appended by Response
+		line := append(d.response[len(d.response):], d.rx[:end+1]...)
+		d.consume(end + 1)
 
-		time.Sleep(time.Duration(pause) * time.Millisecond)
+		trimmed := strings.TrimSpace(string(line))
+		if trimmed == "" || d.notification(trimmed) {
+			continue
+		}
+		return line, true
 	}
+	return nil, false
 }
 
-func (d *Device) parseIPD(end int) error {
-	// find the "+IPD," to get length
-	s := strings.Index(string(d.response[:end]), "+IPD,")
-
-	// find the ":"
-	e := strings.Index(string(d.response[:end]), ":")
-
-	// find the data length
-	val := string(d.response[s+5 : e])
+// consume removes the first n bytes read from the UART.
+func (d *Device) consume(n int) {
+	d.rx = d.rx[:copy(d.rx, d.rx[n:])]
+}
 
-	// TODO: verify count
-	v, err := strconv.Atoi(val)
+// parseIPD parses the "<link ID>,<length>[,<remote IP>,<remote port>]" header
+// of the data received from a connection.
+func (d *Device) parseIPD(header string) {
+	fields := strings.Split(header, ",")
+	if len(fields) < 2 {
+		return
+	}
+	link, err := strconv.Atoi(fields[0])
+	if err != nil || link < 0 || link >= maxLinks {
+		return
+	}
+	length, err := strconv.Atoi(fields[1])
 	if err != nil {
-		// not expected data here. what to do?
-		return err
+		return
 	}
+	d.ipdLink = link
+	d.ipdLeft = length
+}
 
-	// load up the socket data
-	d.data = append(d.data, d.response[e+1:e+1+v]...)
-	return nil
+// notification processes a "<link ID>,CONNECT" or "<link ID>,CLOSED"
+// notification, and returns whether the line is one.
+func (d *Device) notification(line string) bool {
+	if len(line) < 3 || line[0] < '0' || line[0] >= '0'+maxLinks || line[1] != ',' {
+		return false
+	}
+	link := int(line[0] - '0')
+
+	switch line[2:] {
+	case "CONNECT":
+		if d.links[link] != nil {
+			// connection started by Connect
+			return true
+		}
+		if d.server == nil {
+			// the server was stopped meanwhile
+			d.refused = append(d.refused, link)
+			return true
+		}
+		client := &socket{
+			protocol: netdev.IPPROTO_TCP,
+			link:     link,
+		}
+		d.links[link] = client
+		d.backlog = append(d.backlog, client)
+	case "CLOSED":
+		if socket := d.links[link]; socket != nil {
+			socket.closed = true
+			socket.link = -1
+			d.links[link] = nil
+		}
+	case "CONNECT FAIL":
+	default:
+		return false
+	}
+	return true
 }
 
 // IsSocketDataAvailable returns of there is socket data available
 func (d *Device) IsSocketDataAvailable() bool {
-	return len(d.data) > 0 || d.uart.Buffered() > 0
+	d.poll()
+	for _, socket := range d.links {
+		if socket != nil && len(socket.data) > 0 {
+			return true
+		}
+	}
+	return d.uart.Buffered() > 0
 }
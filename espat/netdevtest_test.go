@@ -0,0 +1,294 @@
+//go:build !tinygo
+
+package espat
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/netdev/netdevtest"
+	"tinygo.org/x/drivers/netlink"
+)
+
+// fakeESP emulates the AT firmware of an ESP8266/ESP32 in multiple
+// connections mode, making its connections on the host loopback network. It
+// is the UART of the driver.
+type fakeESP struct {
+	mu  sync.Mutex
+	rx  []byte // sent to the driver
+	cmd []byte // command line being received
+	// link and remaining length of the data being received after
+	// AT+CIPSEND
+	sendLink int
+	sendLeft int
+	sendData []byte
+	links    [maxLinks]net.Conn
+	accepted [maxLinks]bool
+	server   net.Listener
+}
+
+func newFakeESP(c *qt.C) *fakeESP {
+	esp := &fakeESP{}
+	c.Cleanup(func() {
+		esp.mu.Lock()
+		defer esp.mu.Unlock()
+		if esp.server != nil {
+			esp.server.Close()
+		}
+		for _, conn := range esp.links {
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	})
+	return esp
+}
+
+func (esp *fakeESP) Buffered() int {
+	esp.mu.Lock()
+	defer esp.mu.Unlock()
+	return len(esp.rx)
+}
+
+func (esp *fakeESP) Read(p []byte) (int, error) {
+	esp.mu.Lock()
+	defer esp.mu.Unlock()
+	n := copy(p, esp.rx)
+	esp.rx = esp.rx[n:]
+	return n, nil
+}
+
+func (esp *fakeESP) Write(p []byte) (int, error) {
+	esp.mu.Lock()
+	defer esp.mu.Unlock()
+	for _, b := range p {
+		if esp.sendLeft > 0 {
+			esp.sendData = append(esp.sendData, b)
+			esp.sendLeft--
+			if esp.sendLeft == 0 {
+				esp.sendDone()
+			}
+			continue
+		}
+		esp.cmd = append(esp.cmd, b)
+		if b == '\n' {
+			line := strings.TrimSpace(string(esp.cmd))
+			esp.cmd = esp.cmd[:0]
+			esp.command(strings.TrimPrefix(line, "AT"))
+		}
+	}
+	return len(p), nil
+}
+
+func (esp *fakeESP) reply(s string) {
+	esp.rx = append(esp.rx, s...)
+}
+
+// command runs an AT command, without its "AT" prefix.
+func (esp *fakeESP) command(cmd string) {
+	name, params, _ := strings.Cut(cmd, "=")
+	args := strings.Split(params, ",")
+	for i := range args {
+		args[i] = strings.Trim(args[i], `"`)
+	}
+
+	switch name {
+	case Test, WifiMode, ConnectAP, TCPMultiple, Disconnect:
+		esp.reply("\r\nOK\r\n")
+	case SetStationIP + "?":
+		esp.reply("+CIPSTA:ip:\"127.0.0.1\"\r\n\r\nOK\r\n")
+	case TCPConnect:
+		esp.connect(args)
+	case TCPSend:
+		link, conn := esp.link(args[0])
+		size, _ := strconv.Atoi(args[1])
+		if conn == nil || size <= 0 {
+			esp.reply("\r\nERROR\r\n")
+			return
+		}
+		esp.sendLink = link
+		esp.sendLeft = size
+		esp.sendData = esp.sendData[:0]
+		esp.reply("\r\nOK\r\n> ")
+	case TCPClose:
+		link, conn := esp.link(args[0])
+		if conn == nil {
+			esp.reply("\r\nERROR\r\n")
+			return
+		}
+		conn.Close()
+		esp.links[link] = nil
+		esp.reply(strconv.Itoa(link) + ",CLOSED\r\n\r\nOK\r\n")
+	case ServerConfig:
+		esp.serverConfig(args)
+	case TCPStatus:
+		esp.reply("STATUS:3\r\n")
+		for link, conn := range esp.links {
+			if conn == nil {
+				continue
+			}
+			raddr := conn.RemoteAddr().(*net.TCPAddr)
+			laddr := conn.LocalAddr().(*net.TCPAddr)
+			tetype := 0
+			if esp.accepted[link] {
+				tetype = 1
+			}
+			esp.reply("+CIPSTATUS:" + strconv.Itoa(link) + ",\"TCP\",\"" + raddr.IP.String() + "\"," +
+				strconv.Itoa(raddr.Port) + "," + strconv.Itoa(laddr.Port) + "," + strconv.Itoa(tetype) + "\r\n")
+		}
+		esp.reply("\r\nOK\r\n")
+	default:
+		esp.reply("\r\nERROR\r\n")
+	}
+}
+
+// link returns the link ID given as command parameter, and its connection.
+func (esp *fakeESP) link(param string) (int, net.Conn) {
+	link, err := strconv.Atoi(param)
+	if err != nil || link < 0 || link >= maxLinks {
+		return -1, nil
+	}
+	return link, esp.links[link]
+}
+
+// connect runs AT+CIPSTART=<link>,"TCP",<addr>,<port>,<keep alive> or
+// AT+CIPSTART=<link>,"UDP",<addr>,<port>,<local port>,<mode>.
+func (esp *fakeESP) connect(args []string) {
+	if len(args) < 4 {
+		esp.reply("\r\nERROR\r\n")
+		return
+	}
+	link, conn := esp.link(args[0])
+	if link == -1 || conn != nil {
+		esp.reply("ALREADY CONNECTED\r\n\r\nERROR\r\n")
+		return
+	}
+
+	var err error
+	addr := net.JoinHostPort(args[2], args[3])
+	switch args[1] {
+	case "TCP":
+		conn, err = net.Dial("tcp4", addr)
+	case "UDP":
+		var raddr *net.UDPAddr
+		raddr, err = net.ResolveUDPAddr("udp4", addr)
+		if err == nil && len(args) >= 5 {
+			lport, _ := strconv.Atoi(args[4])
+			conn, err = net.DialUDP("udp4", &net.UDPAddr{Port: lport}, raddr)
+		}
+	default:
+		err = errors.New("unsupported connection type")
+	}
+	if err != nil {
+		esp.reply("\r\nERROR\r\nCLOSED\r\n")
+		return
+	}
+
+	esp.links[link] = conn
+	esp.accepted[link] = false
+	esp.reply(strconv.Itoa(link) + ",CONNECT\r\n\r\nOK\r\n")
+	go esp.receive(link, conn)
+}
+
+// sendDone sends the data received after AT+CIPSEND.
+func (esp *fakeESP) sendDone() {
+	conn := esp.links[esp.sendLink]
+	if conn == nil {
+		esp.reply("\r\nSEND FAIL\r\n")
+		return
+	}
+	if _, err := conn.Write(esp.sendData); err != nil {
+		esp.reply("\r\nSEND FAIL\r\n")
+		return
+	}
+	esp.reply("\r\nRecv " + strconv.Itoa(len(esp.sendData)) + " bytes\r\n\r\nSEND OK\r\n")
+}
+
+// serverConfig runs AT+CIPSERVER=1,<port> or AT+CIPSERVER=0. The
+// connections to the server are kept when it is stopped.
+func (esp *fakeESP) serverConfig(args []string) {
+	switch {
+	case args[0] == "1" && len(args) == 2 && esp.server == nil:
+		server, err := net.Listen("tcp4", "127.0.0.1:"+args[1])
+		if err != nil {
+			esp.reply("\r\nERROR\r\n")
+			return
+		}
+		esp.server = server
+		go esp.accept(server)
+	case args[0] == "0" && esp.server != nil:
+		esp.server.Close()
+		esp.server = nil
+	default:
+		esp.reply("\r\nERROR\r\n")
+		return
+	}
+	esp.reply("\r\nOK\r\n")
+}
+
+// accept gives the connections to the server the lowest free link IDs.
+func (esp *fakeESP) accept(server net.Listener) {
+	for {
+		conn, err := server.Accept()
+		if err != nil {
+			return
+		}
+		esp.mu.Lock()
+		link := 0
+		for link < maxLinks && esp.links[link] != nil {
+			link++
+		}
+		if link == maxLinks {
+			conn.Close()
+		} else {
+			esp.links[link] = conn
+			esp.accepted[link] = true
+			esp.reply(strconv.Itoa(link) + ",CONNECT\r\n")
+			go esp.receive(link, conn)
+		}
+		esp.mu.Unlock()
+	}
+}
+
+// receive forwards the data received from a connection as +IPD messages,
+// until it is closed.
+func (esp *fakeESP) receive(link int, conn net.Conn) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		esp.mu.Lock()
+		if n > 0 {
+			esp.reply("+IPD," + strconv.Itoa(link) + "," + strconv.Itoa(n) + ":")
+			esp.reply(string(buf[:n]))
+		}
+		if err != nil {
+			// Closed by the peer, unless AT+CIPCLOSE was used
+			if esp.links[link] == conn {
+				conn.Close()
+				esp.links[link] = nil
+				esp.reply(strconv.Itoa(link) + ",CLOSED\r\n")
+			}
+			esp.mu.Unlock()
+			return
+		}
+		esp.mu.Unlock()
+	}
+}
+
+func TestNetdever(t *testing.T) {
+	c := qt.New(t)
+	d := NewDevice(&Config{Uart: newFakeESP(c)})
+	c.Assert(d.NetConnect(&netlink.ConnectParams{Ssid: "test"}), qt.IsNil)
+
+	netdevtest.TestNetdever(t, d, &netdevtest.Config{
+		Port:         43080,
+		MaxSockets:   maxSockets,
+		SkipTLS:      true,
+		SingleServer: true,
+	})
+}
@@ -2,6 +2,7 @@ package espat
 
 import (
 	"errors"
+	"net/netip"
 	"strconv"
 	"strings"
 )
@@ -32,11 +33,11 @@ func (d *Device) GetDNS(domain string) (string, error) {
 	return strings.Trim(res[0], `"`), nil
 }
 
-// ConnectTCPSocket creates a new TCP socket connection for the ESP8266/ESP32.
-// Currently only supports single connection mode.
-func (d *Device) ConnectTCPSocket(addr, port string) error {
+// ConnectTCPSocket creates a new TCP socket connection for the ESP8266/ESP32,
+// with the given link ID in multiple connections mode.
+func (d *Device) ConnectTCPSocket(link int, addr, port string) error {
 	protocol := "TCP"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
+	val := strconv.Itoa(link) + ",\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
 	err := d.Set(TCPConnect, val)
 	if err != nil {
 		return err
@@ -48,10 +49,11 @@ func (d *Device) ConnectTCPSocket(addr, port string) error {
 	return nil
 }
 
-// ConnectUDPSocket creates a new UDP connection for the ESP8266/ESP32.
-func (d *Device) ConnectUDPSocket(addr, sendport, listenport string) error {
+// ConnectUDPSocket creates a new UDP connection for the ESP8266/ESP32, with
+// the given link ID in multiple connections mode.
+func (d *Device) ConnectUDPSocket(link int, addr, sendport, listenport string) error {
 	protocol := "UDP"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + sendport + "," + listenport + ",0"
+	val := strconv.Itoa(link) + ",\"" + protocol + "\",\"" + addr + "\"," + sendport + "," + listenport + ",0"
 	err := d.Set(TCPConnect, val)
 	if err != nil {
 		return err
@@ -63,11 +65,11 @@ func (d *Device) ConnectUDPSocket(addr, sendport, listenport string) error {
 	return nil
 }
 
-// ConnectSSLSocket creates a new SSL socket connection for the ESP8266/ESP32.
-// Currently only supports single connection mode.
-func (d *Device) ConnectSSLSocket(addr, port string) error {
+// ConnectSSLSocket creates a new SSL socket connection for the ESP8266/ESP32,
+// with the given link ID in multiple connections mode.
+func (d *Device) ConnectSSLSocket(link int, addr, port string) error {
 	protocol := "SSL"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
+	val := strconv.Itoa(link) + ",\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
 	d.Set(TCPConnect, val)
 	// this operation takes longer, so wait up to 6 seconds to complete.
 	_, err := d.Response(6000)
@@ -77,9 +79,10 @@ func (d *Device) ConnectSSLSocket(addr, port string) error {
 	return nil
 }
 
-// DisconnectSocket disconnects the ESP8266/ESP32 from the current TCP/UDP connection.
-func (d *Device) DisconnectSocket() error {
-	err := d.Execute(TCPClose)
+// DisconnectSocket disconnects the ESP8266/ESP32 from the TCP/UDP connection
+// with the given link ID.
+func (d *Device) DisconnectSocket(link int) error {
+	err := d.Set(TCPClose, strconv.Itoa(link))
 	if err != nil {
 		return err
 	}
@@ -90,8 +93,55 @@ func (d *Device) DisconnectSocket() error {
 	return nil
 }
 
+// StartServer starts the TCP server of the ESP8266/ESP32 on the given port.
+// The server requires the multiple connections mode, and its connections are
+// notified by "<link ID>,CONNECT" responses.
+func (d *Device) StartServer(port string) error {
+	d.Set(ServerConfig, "1,"+port)
+	_, err := d.Response(pause)
+	return err
+}
+
+// StopServer stops the TCP server of the ESP8266/ESP32.
+func (d *Device) StopServer() error {
+	d.Set(ServerConfig, "0")
+	_, err := d.Response(pause)
+	return err
+}
+
+// GetLinkRemoteAddr returns the remote address of the connection with the
+// given link ID.
+func (d *Device) GetLinkRemoteAddr(link int) (netip.AddrPort, error) {
+	d.Execute(TCPStatus)
+	r, err := d.Response(pause)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	// +CIPSTATUS:<link ID>,<type>,<remote IP>,<remote port>,<local port>,<tetype>
+	prefix := "+CIPSTATUS:" + strconv.Itoa(link) + ","
+	for _, line := range strings.Split(string(r), "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields := strings.Split(strings.TrimSpace(line[len(prefix):]), ",")
+		if len(fields) < 3 {
+			break
+		}
+		ip, err := netip.ParseAddr(strings.Trim(fields[1], `"`))
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+		port, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+		return netip.AddrPortFrom(ip, uint16(port)), nil
+	}
+	return netip.AddrPort{}, errors.New("GetLinkRemoteAddr error:" + string(r))
+}
+
 // SetMux sets the ESP8266/ESP32 current client TCP/UDP configuration for concurrent connections
-// either single TCPMuxSingle or multiple TCPMuxMultiple (up to 4).
+// either single TCPMuxSingle or multiple TCPMuxMultiple (up to 5).
 func (d *Device) SetMux(mode int) error {
 	val := strconv.Itoa(mode)
 	d.Set(TCPMultiple, val)
@@ -120,9 +170,10 @@ func (d *Device) GetTCPTransferMode() ([]byte, error) {
 	return d.Response(pause)
 }
 
-// StartSocketSend gets the ESP8266/ESP32 ready to receive TCP/UDP socket data.
-func (d *Device) StartSocketSend(size int) error {
-	val := strconv.Itoa(size)
+// StartSocketSend gets the ESP8266/ESP32 ready to receive TCP/UDP socket data
+// for the connection with the given link ID.
+func (d *Device) StartSocketSend(link, size int) error {
+	val := strconv.Itoa(link) + "," + strconv.Itoa(size)
 	d.Set(TCPSend, val)
 
 	// when ">" is received, it indicates
@@ -131,6 +182,13 @@ func (d *Device) StartSocketSend(size int) error {
 	if err != nil {
 		return err
 	}
+	if !strings.Contains(string(r), ">") {
+		// "OK" comes first
+		r, err = d.Response(2000)
+		if err != nil {
+			return err
+		}
+	}
 	if strings.Contains(string(r), ">") {
 		return nil
 	}
@@ -1,7 +1,11 @@
 package espat
 
 import (
+	"net"
 	"strconv"
+	"strings"
+
+	"tinygo.org/x/drivers/netlink"
 )
 
 const (
@@ -47,6 +51,95 @@ func (d *Device) ConnectToAP(ssid, pwd string, ws int) error {
 	return err
 }
 
+// GetAPs returns the access points seen by the ESP8266/ESP32, as one
+// +CWLAP:(<ecn>,"<ssid>",<rssi>,"<mac>",<channel>,...) line for each.
+func (d *Device) GetAPs() ([]byte, error) {
+	d.Execute(ListAP)
+	return d.Response(10000)
+}
+
+// parseAP parses a +CWLAP line of the access points list.
+func parseAP(line string) (netlink.AccessPoint, bool) {
+	var ap netlink.AccessPoint
+
+	if !strings.HasPrefix(line, ListAP+":(") {
+		return ap, false
+	}
+	line = line[len(ListAP)+2:]
+
+	// <ecn>,"
+	i := strings.Index(line, ",\"")
+	if i == -1 {
+		return ap, false
+	}
+	ecn, err := strconv.Atoi(line[:i])
+	if err != nil {
+		return ap, false
+	}
+	line = line[i+2:]
+
+	// The SSID may contain quotes and commas, so look for the end of the
+	// SSID followed by ,<rssi>,"<mac>"
+	for i = strings.Index(line, "\","); i != -1; i = next(line, i) {
+		fields := strings.SplitN(line[i+2:], ",", 4)
+		if len(fields) < 3 {
+			return ap, false
+		}
+		rssi, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		mac, err := net.ParseMAC(strings.Trim(fields[1], "\""))
+		if err != nil {
+			continue
+		}
+		channel, err := strconv.Atoi(strings.TrimRight(fields[2], ")"))
+		if err != nil {
+			continue
+		}
+		ap.Ssid = ssidUnescaper.Replace(line[:i])
+		ap.Rssi = rssi
+		ap.Bssid = mac
+		ap.Channel = channel
+		ap.AuthType = toAuthType(ecn)
+		return ap, true
+	}
+
+	return ap, false
+}
+
+// The ESP32 escapes the special characters of SSIDs with a backslash
+var ssidUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\,`, `,`)
+
+// next returns the index of the next ", in s after index i, or -1.
+func next(s string, i int) int {
+	j := strings.Index(s[i+1:], "\",")
+	if j == -1 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+func toAuthType(ecn int) netlink.AuthType {
+	switch ecn {
+	case 0:
+		return netlink.AuthTypeOpen
+	case 1:
+		return netlink.AuthTypeWEP
+	case 2:
+		return netlink.AuthTypeWPA
+	case 3:
+		return netlink.AuthTypeWPA2
+	case 4:
+		return netlink.AuthTypeWPA2Mixed
+	case 6:
+		return netlink.AuthTypeWPA3
+	case 7:
+		return netlink.AuthTypeWPA3Mixed
+	}
+	return netlink.AuthTypeUnknown
+}
+
 // DisconnectFromAP disconnects the ESP8266/ESP32 from the current access point.
 func (d *Device) DisconnectFromAP() error {
 	d.Execute(Disconnect)
@@ -176,6 +176,12 @@ func port(p string) error {
 // Set ADR function of LoRaWAN module
 func adr(state string) error {
 	cmd := "ADR"
+	switch state {
+	case "ON":
+		lorawan.SetADR(true)
+	case "OFF":
+		lorawan.SetADR(false)
+	}
 	writeCommandOutput(cmd, state)
 
 	return nil
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"errors"
 	"strconv"
 	"time"
@@ -71,10 +72,26 @@ func main() {
 	// Connect the lorawan with the Lora Radio device.
 	lorawan.UseRadio(radio)
 	switch reg {
+	case "AS923", "AS923-1":
+		lorawan.UseRegionSettings(region.AS923_1())
+	case "AS923-2":
+		lorawan.UseRegionSettings(region.AS923_2())
+	case "AS923-3":
+		lorawan.UseRegionSettings(region.AS923_3())
+	case "AS923-4":
+		lorawan.UseRegionSettings(region.AS923_4())
 	case "AU915":
 		lorawan.UseRegionSettings(region.AU915())
+	case "CN470":
+		lorawan.UseRegionSettings(region.CN470Legacy())
+	case "EU433":
+		lorawan.UseRegionSettings(region.EU433())
 	case "EU868":
 		lorawan.UseRegionSettings(region.EU868())
+	case "IN865":
+		lorawan.UseRegionSettings(region.IN865())
+	case "KR920":
+		lorawan.UseRegionSettings(region.KR920())
 	case "US915":
 		lorawan.UseRegionSettings(region.US915())
 	default:
@@ -111,6 +128,9 @@ func main() {
 			println("Uplink error:", err)
 		} else {
 			println("Uplink success, msg=", payload)
+			if dl, err := lorawan.ListenDownlink(); err == nil {
+				println("Downlink received, port=", dl.FPort, "payload=", hex.EncodeToString(dl.FRMPayload))
+			}
 		}
 
 		println("Sleeping for", LORAWAN_UPLINK_DELAY_SEC, "sec")
@@ -18,6 +18,12 @@ var (
 	errInvalidGGASentence        = errors.New("invalid GGA NMEA sentence")
 	errInvalidRMCSentence        = errors.New("invalid RMC NMEA sentence")
 	errInvalidGLLSentence        = errors.New("invalid GLL NMEA sentence")
+	errInvalidGSASentence        = errors.New("invalid GSA NMEA sentence")
+	errInvalidGSVSentence        = errors.New("invalid GSV NMEA sentence")
+	errInvalidVTGSentence        = errors.New("invalid VTG NMEA sentence")
+	errInvalidZDASentence        = errors.New("invalid ZDA NMEA sentence")
+	errGSVSequence               = errors.New("GSV NMEA sentence out of sequence")
+	errReadTimeout               = errors.New("GPS read timed out")
 )
 
 type GPSError struct {
@@ -52,10 +58,13 @@ const (
 type Device struct {
 	buffer   []byte
 	bufIdx   int
+	bufLen   int
 	sentence strings.Builder
 	uart     drivers.UART
 	bus      drivers.I2C
 	address  uint16
+	ubxFrame []byte
+	ubxReply UBXMessage
 }
 
 // NewUART creates a new UART GPS connection. The UART must already be configured.
@@ -110,35 +119,66 @@ func (gps *Device) readNextSentence() (sentence string) {
 }
 
 func (gps *Device) readNextByte() (b byte) {
-	gps.bufIdx += 1
-	if gps.bufIdx >= bufferSize {
-		gps.fillBuffer()
+	b, _ = gps.readByte(time.Time{})
+	return b
+}
+
+// readByte returns the next byte from the GPS device, or errReadTimeout if
+// the deadline passes first. A zero deadline waits forever.
+func (gps *Device) readByte(deadline time.Time) (byte, error) {
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return 0, errReadTimeout
+	}
+	if gps.bufIdx+1 >= gps.bufLen {
+		if err := gps.fillBuffer(deadline); err != nil {
+			return 0, err
+		}
+	} else {
+		gps.bufIdx += 1
 	}
-	return gps.buffer[gps.bufIdx]
+	return gps.buffer[gps.bufIdx], nil
 }
 
-func (gps *Device) fillBuffer() {
+func (gps *Device) fillBuffer(deadline time.Time) error {
 	if gps.uart != nil {
-		gps.uartFillBuffer()
-	} else {
-		gps.i2cFillBuffer()
+		return gps.uartFillBuffer(deadline)
 	}
+	return gps.i2cFillBuffer(deadline)
 }
 
-func (gps *Device) uartFillBuffer() {
-	for gps.uart.Buffered() < bufferSize {
+// uartFillBuffer reads the bytes received by the UART, waiting for at least
+// one.
+func (gps *Device) uartFillBuffer(deadline time.Time) error {
+	for gps.uart.Buffered() == 0 {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return errReadTimeout
+		}
 		time.Sleep(100 * time.Millisecond)
 	}
-	gps.uart.Read(gps.buffer[0:bufferSize])
+	n, _ := gps.uart.Read(gps.buffer[0:bufferSize])
+	gps.bufLen = n
 	gps.bufIdx = 0
+	return nil
 }
 
-func (gps *Device) i2cFillBuffer() {
-	for gps.available() < bufferSize {
+// i2cFillBuffer reads the bytes available from the I2C data stream, waiting
+// for at least one.
+func (gps *Device) i2cFillBuffer(deadline time.Time) error {
+	n := gps.available()
+	for n == 0 {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return errReadTimeout
+		}
 		time.Sleep(100 * time.Millisecond)
+		n = gps.available()
 	}
-	gps.bus.Tx(gps.address, []byte{DATA_STREAM_REG}, gps.buffer[0:bufferSize])
+	if n > bufferSize {
+		n = bufferSize
+	}
+	gps.bus.Tx(gps.address, []byte{DATA_STREAM_REG}, gps.buffer[0:n])
+	gps.bufLen = n
 	gps.bufIdx = 0
+	return nil
 }
 
 // Available returns how many bytes of GPS data are currently available.
@@ -154,7 +194,7 @@ func (gps *Device) WriteBytes(bytes []byte) {
 	if gps.uart != nil {
 		gps.uart.Write(bytes)
 	} else {
-		gps.bus.Tx(gps.address, []byte{}, bytes)
+		gps.bus.Tx(gps.address, bytes, nil)
 	}
 }
 
@@ -8,6 +8,11 @@ import (
 
 // Parser for GPS NMEA sentences.
 type Parser struct {
+	// GSV message being received, that spans many sentences.
+	gsvTalker     string
+	gsvTotal      int
+	gsvNext       int
+	gsvSatellites []Satellite
 }
 
 // Fix is a GPS location fix
@@ -35,6 +40,71 @@ type Fix struct {
 
 	// Heading based on reported movement. Only returned for RMC sentences.
 	Heading float32
+
+	// Quality is the fix quality indicator. Only returned for GGA sentences.
+	Quality FixQuality
+
+	// Mode is the fix mode. Only returned for GSA sentences.
+	Mode FixMode
+
+	// PDOP is the position dilution of precision. Only returned for GSA sentences.
+	PDOP float32
+
+	// HDOP is the horizontal dilution of precision. Only returned for GGA and GSA sentences.
+	HDOP float32
+
+	// VDOP is the vertical dilution of precision. Only returned for GSA sentences.
+	VDOP float32
+
+	// SatellitesUsed are the PRNs of the satellites used for the fix. Only returned for GSA sentences.
+	SatellitesUsed []uint16
+
+	// SatellitesInView are the satellites in view of one constellation. A
+	// GSV message spans many sentences: it is only returned for the last
+	// sentence of a GSV message.
+	SatellitesInView []Satellite
+
+	// ZoneOffset is the offset of the local time zone from UTC. Only returned for ZDA sentences.
+	ZoneOffset time.Duration
+}
+
+// FixQuality is the quality of a fix, as reported by GGA sentences.
+type FixQuality uint8
+
+const (
+	QualityInvalid FixQuality = iota
+	QualityGPS
+	QualityDGPS
+	QualityPPS
+	QualityRTK
+	QualityFloatRTK
+	QualityEstimated
+	QualityManual
+	QualitySimulation
+)
+
+// FixMode is the mode of a fix, as reported by GSA sentences.
+type FixMode uint8
+
+const (
+	ModeNoFix FixMode = iota + 1
+	Mode2D
+	Mode3D
+)
+
+// Satellite is a satellite in view, as reported by GSV sentences.
+type Satellite struct {
+	// PRN is the satellite number.
+	PRN uint16
+
+	// Elevation is the elevation in degrees, from 0 to 90.
+	Elevation int16
+
+	// Azimuth is the azimuth in degrees from true north, from 0 to 359.
+	Azimuth int16
+
+	// SNR is the signal to noise ratio in dB-Hz, or -1 when the satellite is not tracked.
+	SNR int16
 }
 
 // NewParser returns a GPS NMEA Parser.
@@ -52,10 +122,16 @@ func (parser *Parser) Parse(sentence string) (Fix, error) {
 		return fix, errInvalidNMEASentenceLength
 	}
 	typ := sentence[3:6]
+	// The checksum is validated when the sentence is read, it is removed so
+	// it does not end up in the last field.
+	body := sentence
+	if i := strings.IndexByte(body, checksumDelimiter); i >= 0 {
+		body = body[:i]
+	}
 	switch typ {
 	case "GGA":
 		// https://docs.novatel.com/OEM7/Content/Logs/GPGGA.htm
-		fields := strings.Split(sentence, ",")
+		fields := strings.Split(body, ",")
 		if len(fields) != 15 {
 			return fix, errInvalidGGASentence
 		}
@@ -63,14 +139,16 @@ func (parser *Parser) Parse(sentence string) (Fix, error) {
 		fix.Time = findTime(fields[1])
 		fix.Latitude = findLatitude(fields[2], fields[3])
 		fix.Longitude = findLongitude(fields[4], fields[5])
+		fix.Quality = FixQuality(findInt(fields[6]))
 		fix.Satellites = findSatellites(fields[7])
+		fix.HDOP = findFloat(fields[8])
 		fix.Altitude = findAltitude(fields[9])
 		fix.Valid = (fix.Altitude != -99999) && (fix.Satellites > 0)
 
 		return fix, nil
 	case "GLL":
 		// https://docs.novatel.com/OEM7/Content/Logs/GPGLL.htm
-		fields := strings.Split(sentence, ",")
+		fields := strings.Split(body, ",")
 		if len(fields) != 8 {
 			return fix, errInvalidGLLSentence
 		}
@@ -84,7 +162,7 @@ func (parser *Parser) Parse(sentence string) (Fix, error) {
 		return fix, nil
 	case "RMC":
 		// https://docs.novatel.com/OEM7/Content/Logs/GPRMC.htm
-		fields := strings.Split(sentence, ",")
+		fields := strings.Split(body, ",")
 		if len(fields) != 13 {
 			return fix, errInvalidRMCSentence
 		}
@@ -95,8 +173,110 @@ func (parser *Parser) Parse(sentence string) (Fix, error) {
 		fix.Longitude = findLongitude(fields[5], fields[6])
 		fix.Speed = findSpeed(fields[7])
 		fix.Heading = findHeading(fields[8])
-		date := findDate(fields[9])
-		fix.Time = fix.Time.AddDate(date.Year(), int(date.Month()), date.Day())
+		if date := findDate(fields[9]); !date.IsZero() && !fix.Time.IsZero() {
+			fix.Time = time.Date(date.Year(), date.Month(), date.Day(),
+				fix.Time.Hour(), fix.Time.Minute(), fix.Time.Second(), fix.Time.Nanosecond(), time.UTC)
+		}
+
+		return fix, nil
+	case "GSA":
+		// https://docs.novatel.com/OEM7/Content/Logs/GPGSA.htm
+		// NMEA 4.10 adds the GNSS system ID as a last field.
+		fields := strings.Split(body, ",")
+		if len(fields) != 18 && len(fields) != 19 {
+			return fix, errInvalidGSASentence
+		}
+
+		fix.Mode = FixMode(findInt(fields[2]))
+		for _, prn := range fields[3:15] {
+			if prn != "" {
+				fix.SatellitesUsed = append(fix.SatellitesUsed, uint16(findInt(prn)))
+			}
+		}
+		fix.PDOP = findFloat(fields[15])
+		fix.HDOP = findFloat(fields[16])
+		fix.VDOP = findFloat(fields[17])
+		fix.Valid = fix.Mode == Mode2D || fix.Mode == Mode3D
+
+		return fix, nil
+	case "GSV":
+		// https://docs.novatel.com/OEM7/Content/Logs/GPGSV.htm
+		// Each sentence holds up to 4 satellites, NMEA 4.10 adds the signal
+		// ID as a last field.
+		fields := strings.Split(body, ",")
+		if len(fields) < 4 || (len(fields)-4)%4 > 1 {
+			return fix, errInvalidGSVSentence
+		}
+		total := findInt(fields[1])
+		num := findInt(fields[2])
+		if num < 1 || num > total {
+			return fix, errInvalidGSVSentence
+		}
+
+		talker := sentence[1:3]
+		if num == 1 {
+			parser.gsvTalker = talker
+			parser.gsvTotal = total
+			parser.gsvSatellites = parser.gsvSatellites[:0]
+		} else if talker != parser.gsvTalker || total != parser.gsvTotal || num != parser.gsvNext {
+			parser.gsvNext = 0
+			return fix, newGPSError(errGSVSequence, sentence, typ)
+		}
+		parser.gsvNext = num + 1
+
+		for i := 4; i+4 <= len(fields); i += 4 {
+			if fields[i] == "" {
+				continue
+			}
+			snr := int16(-1)
+			if fields[i+3] != "" {
+				snr = int16(findInt(fields[i+3]))
+			}
+			parser.gsvSatellites = append(parser.gsvSatellites, Satellite{
+				PRN:       uint16(findInt(fields[i])),
+				Elevation: int16(findInt(fields[i+1])),
+				Azimuth:   int16(findInt(fields[i+2])),
+				SNR:       snr,
+			})
+		}
+
+		if num == total {
+			fix.SatellitesInView = append([]Satellite(nil), parser.gsvSatellites...)
+			parser.gsvNext = 0
+		}
+
+		return fix, nil
+	case "VTG":
+		// https://docs.novatel.com/OEM7/Content/Logs/GPVTG.htm
+		// NMEA 2.3 adds the mode indicator as a last field.
+		fields := strings.Split(body, ",")
+		// Unit fields are empty when there is no fix.
+		if (len(fields) != 9 && len(fields) != 10) || (fields[2] != "T" && fields[2] != "") {
+			return fix, errInvalidVTGSentence
+		}
+
+		fix.Heading = findHeading(fields[1])
+		fix.Speed = findSpeed(fields[5])
+		fix.Valid = fields[2] == "T" && (len(fields) == 9 || (fields[9] != "N" && fields[9] != ""))
+
+		return fix, nil
+	case "ZDA":
+		// https://docs.novatel.com/OEM7/Content/Logs/GPZDA.htm
+		fields := strings.Split(body, ",")
+		if len(fields) != 7 {
+			return fix, errInvalidZDASentence
+		}
+
+		t := findTime(fields[1])
+		fix.Time = time.Date(findInt(fields[4]), time.Month(findInt(fields[3])), findInt(fields[2]),
+			t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		hours := findInt(fields[5])
+		minutes := time.Duration(findInt(fields[6])) * time.Minute
+		if strings.HasPrefix(fields[5], "-") {
+			minutes = -minutes
+		}
+		fix.ZoneOffset = time.Duration(hours)*time.Hour + minutes
+		fix.Valid = fields[1] != "" && fields[4] != ""
 
 		return fix, nil
 	}
@@ -210,3 +390,15 @@ func findHeading(val string) float32 {
 	}
 	return 0
 }
+
+// findInt returns an integer field from an NMEA sentence, or 0 if it is empty.
+func findInt(val string) int {
+	v, _ := strconv.Atoi(val)
+	return v
+}
+
+// findFloat returns a decimal field from an NMEA sentence, or 0 if it is empty.
+func findFloat(val string) float32 {
+	v, _ := strconv.ParseFloat(val, 32)
+	return float32(v)
+}
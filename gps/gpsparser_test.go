@@ -1,6 +1,7 @@
 package gps
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ func TestParseUnknownSentence(t *testing.T) {
 
 	p := NewParser()
 
-	val := "$GPGSV,3,1,09,07,14,317,22,08,31,284,25,10,32,133,39,16,85,232,29*7F"
+	val := "$GPTXT,01,01,02,ANTSTATUS=OK*3B"
 	_, err := p.Parse(val)
 	c.Assert(err.Error(), qt.Contains, "unsupported NMEA sentence type")
 }
@@ -36,6 +37,8 @@ func TestParseGGA(t *testing.T) {
 	c.Assert(fix.Latitude, qt.Equals, float32(41.980735778808594))
 	c.Assert(fix.Longitude, qt.Equals, float32(-91.79069519042969))
 	c.Assert(fix.Altitude, qt.Equals, int32(255))
+	c.Assert(fix.Quality, qt.Equals, QualityRTK)
+	c.Assert(fix.HDOP, qt.Equals, float32(0.9))
 }
 
 func TestParseGLL(t *testing.T) {
@@ -84,6 +87,153 @@ func TestParseRMC(t *testing.T) {
 	c.Assert(fix.Time.Second(), qt.Equals, 22)
 	c.Assert(fix.Latitude, qt.Equals, float32(51.15043640136719))
 	c.Assert(fix.Longitude, qt.Equals, float32(-114.03067779541016))
+
+	val = "$GPRMC,235959.00,A,5109.0262308,N,11401.8407342,W,0.004,133.4,280224,0.0,E,D*2B"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Time, qt.Equals, time.Date(2024, time.February, 28, 23, 59, 59, 0, time.UTC))
+}
+
+func TestParseGSA(t *testing.T) {
+	c := qt.New(t)
+
+	p := NewParser()
+
+	val := "$GPGSA,M,3,17,02,30,04,05,10"
+	_, err := p.Parse(val)
+	if err != errInvalidGSASentence {
+		t.Error("should have errInvalidGSASentence error")
+	}
+
+	val = "$GPGSA,M,3,17,02,30,04,05,10,09,06,31,12,,,1.2,0.8,0.9*35"
+	fix, err := p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsTrue)
+	c.Assert(fix.Mode, qt.Equals, Mode3D)
+	c.Assert(fix.SatellitesUsed, qt.DeepEquals, []uint16{17, 2, 30, 4, 5, 10, 9, 6, 31, 12})
+	c.Assert(fix.PDOP, qt.Equals, float32(1.2))
+	c.Assert(fix.HDOP, qt.Equals, float32(0.8))
+	c.Assert(fix.VDOP, qt.Equals, float32(0.9))
+
+	// NMEA 4.10 with the system ID
+	val = "$GNGSA,A,1,,,,,,,,,,,,,99.99,99.99,99.99,1*33"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsFalse)
+	c.Assert(fix.Mode, qt.Equals, ModeNoFix)
+	c.Assert(fix.SatellitesUsed, qt.HasLen, 0)
+}
+
+func TestParseGSV(t *testing.T) {
+	c := qt.New(t)
+
+	p := NewParser()
+
+	fix, err := p.Parse("$GPGSV,3,1,09,07,14,317,22,08,31,284,25,10,32,133,39,16,85,232,29*7F")
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.SatellitesInView, qt.IsNil)
+	fix, err = p.Parse("$GPGSV,3,2,09,21,59,075,42,26,28,040,34,27,07,069,,29,07,213,*7F")
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.SatellitesInView, qt.IsNil)
+	fix, err = p.Parse("$GPGSV,3,3,09,30,11,123,*48")
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.SatellitesInView, qt.DeepEquals, []Satellite{
+		{PRN: 7, Elevation: 14, Azimuth: 317, SNR: 22},
+		{PRN: 8, Elevation: 31, Azimuth: 284, SNR: 25},
+		{PRN: 10, Elevation: 32, Azimuth: 133, SNR: 39},
+		{PRN: 16, Elevation: 85, Azimuth: 232, SNR: 29},
+		{PRN: 21, Elevation: 59, Azimuth: 75, SNR: 42},
+		{PRN: 26, Elevation: 28, Azimuth: 40, SNR: 34},
+		{PRN: 27, Elevation: 7, Azimuth: 69, SNR: -1},
+		{PRN: 29, Elevation: 7, Azimuth: 213, SNR: -1},
+		{PRN: 30, Elevation: 11, Azimuth: 123, SNR: -1},
+	})
+
+	// Single sentence with the NMEA 4.10 signal ID
+	fix, err = p.Parse("$GLGSV,1,1,02,65,40,010,30,66,20,280,,1*7A")
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.SatellitesInView, qt.DeepEquals, []Satellite{
+		{PRN: 65, Elevation: 40, Azimuth: 10, SNR: 30},
+		{PRN: 66, Elevation: 20, Azimuth: 280, SNR: -1},
+	})
+}
+
+func TestParseGSVSequence(t *testing.T) {
+	c := qt.New(t)
+
+	p := NewParser()
+
+	_, err := p.Parse("$GPGSV,3,2,09,21,59,075,42,26,28,040,34,27,07,069,,29,07,213,*7F")
+	c.Assert(errors.Is(err, errGSVSequence), qt.IsTrue)
+
+	_, err = p.Parse("$GPGSV,2,1,05,07,14,317,22,08,31,284,25,10,32,133,39,16,85,232,29*7F")
+	c.Assert(err, qt.IsNil)
+	_, err = p.Parse("$GLGSV,2,2,05,65,40,010,30*7F")
+	c.Assert(errors.Is(err, errGSVSequence), qt.IsTrue)
+	_, err = p.Parse("$GPGSV,2,2,05,30,11,123,*48")
+	c.Assert(errors.Is(err, errGSVSequence), qt.IsTrue, qt.Commentf("sequence restarts with the first sentence"))
+
+	_, err = p.Parse("$GPGSV,2,3,05,30,11,123,*48")
+	c.Assert(err, qt.Equals, errInvalidGSVSentence)
+}
+
+func TestParseVTG(t *testing.T) {
+	c := qt.New(t)
+
+	p := NewParser()
+
+	val := "$GPVTG,054.7,034.4,005.5,010.2*48"
+	_, err := p.Parse(val)
+	if err != errInvalidVTGSentence {
+		t.Error("should have errInvalidVTGSentence error")
+	}
+
+	val = "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48"
+	fix, err := p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsTrue)
+	c.Assert(fix.Heading, qt.Equals, float32(54.7))
+	c.Assert(fix.Speed, qt.Equals, float32(5.5))
+
+	val = "$GPVTG,,T,,M,0.0,N,0.0,K,N*2C"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsFalse)
+
+	val = "$GPVTG,,,,,,,,,N*30"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsFalse)
+}
+
+func TestParseZDA(t *testing.T) {
+	c := qt.New(t)
+
+	p := NewParser()
+
+	val := "$GPZDA,201530.00,04,07*60"
+	_, err := p.Parse(val)
+	if err != errInvalidZDASentence {
+		t.Error("should have errInvalidZDASentence error")
+	}
+
+	val = "$GPZDA,201530.00,04,07,2002,00,00*60"
+	fix, err := p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsTrue)
+	c.Assert(fix.Time, qt.Equals, time.Date(2002, time.July, 4, 20, 15, 30, 0, time.UTC))
+	c.Assert(fix.ZoneOffset, qt.Equals, time.Duration(0))
+
+	val = "$GPZDA,050306.00,29,02,2024,-03,30*7A"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Time, qt.Equals, time.Date(2024, time.February, 29, 5, 3, 6, 0, time.UTC))
+	c.Assert(fix.ZoneOffset, qt.Equals, -3*time.Hour-30*time.Minute)
+
+	val = "$GPZDA,,,,,,*48"
+	fix, err = p.Parse(val)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fix.Valid, qt.IsFalse)
 }
 
 func TestTime(t *testing.T) {
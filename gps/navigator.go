@@ -0,0 +1,227 @@
+package gps
+
+import (
+	"errors"
+	"time"
+)
+
+// never is the age of a navigation state without any valid fix.
+const never = time.Duration(1<<63 - 1)
+
+// Navigation is a navigation state, merged from many NMEA sentences.
+type Navigation struct {
+	// Valid if the last reported fix was valid.
+	Valid bool
+
+	// Time of the last fix, in UTC time. The date is only known once an RMC
+	// or ZDA sentence was received, until then Time is the time of day on
+	// January 1, year 1.
+	Time time.Time
+
+	// Latitude is the decimal latitude. Negative numbers indicate S.
+	Latitude float32
+
+	// Longitude is the decimal longitude. Negative numbers indicate W.
+	Longitude float32
+
+	// Altitude above mean sea level, in meters.
+	Altitude int32
+
+	// Speed over ground, in knots.
+	Speed float32
+
+	// Heading is the course over ground, in degrees from true north.
+	Heading float32
+
+	// Satellites is the number of satellites used for the fix.
+	Satellites int16
+
+	Quality FixQuality
+	Mode    FixMode
+	PDOP    float32
+	HDOP    float32
+	VDOP    float32
+
+	// SatellitesUsed are the PRNs of the satellites used for the fix.
+	SatellitesUsed []uint16
+
+	// SatellitesInView are the satellites in view of all constellations.
+	SatellitesInView []Satellite
+
+	// Updated is the local time at which the position was last updated
+	// from a valid fix.
+	Updated time.Time
+}
+
+// Navigator merges the fixes of a stream of NMEA sentences, as returned by
+// Device.NextSentence, into a navigation state:
+//
+//	nav := gps.NewNavigator()
+//	for {
+//		s, err := ublox.NextSentence()
+//		if err == nil {
+//			nav.Update(s)
+//		}
+//		if nav.Fresh(5 * time.Second) {
+//			state := nav.Navigation()
+//			...
+//		}
+//	}
+//
+// A receiver sends many sentences for each fix, and sentences are grouped
+// by their UTC time. Satellite information reported by the GSA and GSV
+// sentences of many constellations for one fix is merged together.
+type Navigator struct {
+	parser Parser
+	nav    Navigation
+
+	// epoch is the time of day of the fix being received.
+	epoch    time.Duration
+	hasEpoch bool
+	// date is the UTC date of the fix being received, at midnight.
+	date    time.Time
+	hasDate bool
+	// Epochs of the satellites used and in view, to know whether to merge
+	// the sentences of another constellation or replace them.
+	usedEpoch   uint32
+	inViewEpoch uint32
+	epochs      uint32
+
+	now func() time.Time
+}
+
+// NewNavigator returns a Navigator without any fix.
+func NewNavigator() *Navigator {
+	return &Navigator{
+		parser: NewParser(),
+		now:    time.Now,
+	}
+}
+
+// Update parses a NMEA sentence and merges it into the navigation state.
+// Unsupported sentence types are ignored.
+func (n *Navigator) Update(sentence string) error {
+	fix, err := n.parser.Parse(sentence)
+	if err != nil {
+		if errors.Is(err, errUnknownNMEASentence) {
+			return nil
+		}
+		return err
+	}
+
+	nav := &n.nav
+	switch sentence[3:6] {
+	case "GGA":
+		n.setTime(fix.Time, false)
+		nav.Quality = fix.Quality
+		nav.Satellites = fix.Satellites
+		nav.HDOP = fix.HDOP
+		n.setPosition(fix, fix.Quality != QualityInvalid)
+		if nav.Valid && fix.Altitude != -99999 {
+			nav.Altitude = fix.Altitude
+		}
+	case "GLL":
+		n.setTime(fix.Time, false)
+		n.setPosition(fix, fix.Valid)
+	case "RMC":
+		n.setTime(fix.Time, fix.Time.Year() > 0)
+		n.setPosition(fix, fix.Valid)
+		if fix.Valid {
+			nav.Speed = fix.Speed
+			nav.Heading = fix.Heading
+		}
+	case "VTG":
+		if fix.Valid {
+			nav.Speed = fix.Speed
+			nav.Heading = fix.Heading
+		}
+	case "ZDA":
+		if fix.Valid {
+			n.setTime(fix.Time, true)
+		}
+	case "GSA":
+		nav.Mode = fix.Mode
+		nav.PDOP = fix.PDOP
+		nav.HDOP = fix.HDOP
+		nav.VDOP = fix.VDOP
+		if n.usedEpoch != n.epochs {
+			n.usedEpoch = n.epochs
+			nav.SatellitesUsed = nav.SatellitesUsed[:0]
+		}
+		nav.SatellitesUsed = append(nav.SatellitesUsed, fix.SatellitesUsed...)
+	case "GSV":
+		if fix.SatellitesInView == nil {
+			break
+		}
+		if n.inViewEpoch != n.epochs {
+			n.inViewEpoch = n.epochs
+			nav.SatellitesInView = nav.SatellitesInView[:0]
+		}
+		nav.SatellitesInView = append(nav.SatellitesInView, fix.SatellitesInView...)
+	}
+	return nil
+}
+
+// setPosition updates the position from a fix, if it is valid.
+func (n *Navigator) setPosition(fix Fix, valid bool) {
+	n.nav.Valid = valid
+	if !valid {
+		return
+	}
+	n.nav.Latitude = fix.Latitude
+	n.nav.Longitude = fix.Longitude
+	n.nav.Updated = n.now()
+}
+
+// setTime updates the time of the navigation state from the time of a
+// sentence, that includes the date or only the time of day. A new time of
+// day starts a new epoch.
+func (n *Navigator) setTime(t time.Time, withDate bool) {
+	if t.IsZero() {
+		// No time in the sentence.
+		return
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	tod := t.Sub(midnight)
+
+	if !n.hasEpoch || tod != n.epoch {
+		if n.hasDate && !withDate && n.hasEpoch && tod < n.epoch-12*time.Hour {
+			// The day changed before a sentence with the date.
+			n.date = n.date.AddDate(0, 0, 1)
+		}
+		n.epoch = tod
+		n.hasEpoch = true
+		n.epochs++
+	}
+	if withDate {
+		n.date = midnight
+		n.hasDate = true
+	}
+
+	if n.hasDate {
+		n.nav.Time = n.date.Add(tod)
+	} else {
+		n.nav.Time = time.Time{}.Add(tod)
+	}
+}
+
+// Navigation returns the current navigation state. Its slices are only
+// valid until the next update.
+func (n *Navigator) Navigation() Navigation {
+	return n.nav
+}
+
+// Age returns the time elapsed since the position was last updated from a
+// valid fix, or the maximum duration if there was none.
+func (n *Navigator) Age() time.Duration {
+	if n.nav.Updated.IsZero() {
+		return never
+	}
+	return n.now().Sub(n.nav.Updated)
+}
+
+// Fresh returns whether the current fix is valid and the position was
+// updated within timeout.
+func (n *Navigator) Fresh(timeout time.Duration) bool {
+	return n.nav.Valid && n.Age() <= timeout
+}
@@ -0,0 +1,95 @@
+package gps
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func newTestNavigator() (*Navigator, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	n := NewNavigator()
+	n.now = func() time.Time { return now }
+	return n, &now
+}
+
+func TestNavigator(t *testing.T) {
+	c := qt.New(t)
+	n, now := newTestNavigator()
+
+	c.Assert(n.Fresh(time.Hour), qt.IsFalse)
+	c.Assert(n.Age(), qt.Equals, never)
+
+	for _, s := range []string{
+		"$GPTXT,01,01,02,ANTSTATUS=OK*3B",
+		"$GPRMC,235958.00,A,5109.0262308,N,11401.8407342,W,0.5,133.4,290224,0.0,E,D*2B",
+		"$GPVTG,133.4,T,133.4,M,0.5,N,0.9,K,A*48",
+		"$GPGGA,235958.00,5109.0262308,N,11401.8407342,W,1,08,0.9,1082.1,M,-17.0,M,,*6E",
+		"$GPGSA,A,3,07,08,10,16,21,26,,,,,,,1.6,0.9,1.3*35",
+		"$GLGSA,A,3,65,66,,,,,,,,,,,1.6,0.9,1.3,2*35",
+		"$GPGSV,2,1,05,07,14,317,22,08,31,284,25,10,32,133,39,16,85,232,29*7F",
+		"$GPGSV,2,2,05,21,59,075,42*7F",
+		"$GLGSV,1,1,02,65,40,010,30,66,20,280,,1*7A",
+	} {
+		c.Assert(n.Update(s), qt.IsNil, qt.Commentf("%s", s))
+	}
+
+	nav := n.Navigation()
+	c.Assert(nav.Valid, qt.IsTrue)
+	c.Assert(nav.Time, qt.Equals, time.Date(2024, time.February, 29, 23, 59, 58, 0, time.UTC))
+	c.Assert(nav.Latitude, qt.Equals, float32(51.15043640136719))
+	c.Assert(nav.Longitude, qt.Equals, float32(-114.03067779541016))
+	c.Assert(nav.Altitude, qt.Equals, int32(1082))
+	c.Assert(nav.Speed, qt.Equals, float32(0.5))
+	c.Assert(nav.Heading, qt.Equals, float32(133.4))
+	c.Assert(nav.Satellites, qt.Equals, int16(8))
+	c.Assert(nav.Quality, qt.Equals, QualityGPS)
+	c.Assert(nav.Mode, qt.Equals, Mode3D)
+	c.Assert(nav.PDOP, qt.Equals, float32(1.6))
+	c.Assert(nav.HDOP, qt.Equals, float32(0.9))
+	c.Assert(nav.VDOP, qt.Equals, float32(1.3))
+	c.Assert(nav.SatellitesUsed, qt.DeepEquals, []uint16{7, 8, 10, 16, 21, 26, 65, 66})
+	c.Assert(nav.SatellitesInView, qt.HasLen, 7)
+	c.Assert(nav.Updated, qt.Equals, *now)
+
+	// The next fix replaces the satellites, and GGA crosses midnight
+	// before RMC brings the new date.
+	*now = now.Add(time.Second)
+	c.Assert(n.Update("$GPGGA,000000.00,5109.0300000,N,11401.8407342,W,1,07,1.0,1083.0,M,-17.0,M,,*6E"), qt.IsNil)
+	c.Assert(n.Update("$GPGSA,A,3,07,08,10,16,21,,,,,,,,1.7,1.0,1.4*35"), qt.IsNil)
+	nav = n.Navigation()
+	c.Assert(nav.Time, qt.Equals, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(nav.Latitude, qt.Equals, float32(51.150500))
+	c.Assert(nav.Altitude, qt.Equals, int32(1083))
+	c.Assert(nav.SatellitesUsed, qt.DeepEquals, []uint16{7, 8, 10, 16, 21})
+	c.Assert(nav.SatellitesInView, qt.HasLen, 7, qt.Commentf("kept until the next GSV message"))
+	c.Assert(n.Update("$GPRMC,000000.00,A,5109.0300000,N,11401.8407342,W,0.4,130.0,010324,0.0,E,D*2B"), qt.IsNil)
+	c.Assert(n.Navigation().Time, qt.Equals, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+
+	*now = now.Add(3 * time.Second)
+	c.Assert(n.Age(), qt.Equals, 3*time.Second)
+	c.Assert(n.Fresh(5*time.Second), qt.IsTrue)
+	c.Assert(n.Fresh(2*time.Second), qt.IsFalse)
+}
+
+func TestNavigatorLostFix(t *testing.T) {
+	c := qt.New(t)
+	n, now := newTestNavigator()
+
+	c.Assert(n.Update("$GPGGA,120000.00,5109.0262308,N,11401.8407342,W,1,08,0.9,1082.1,M,-17.0,M,,*6E"), qt.IsNil)
+	nav := n.Navigation()
+	c.Assert(nav.Time, qt.Equals, time.Date(1, time.January, 1, 12, 0, 0, 0, time.UTC), qt.Commentf("date is unknown"))
+	c.Assert(n.Fresh(time.Second), qt.IsTrue)
+
+	*now = now.Add(time.Second)
+	c.Assert(n.Update("$GPRMC,120001.00,V,,,,,,,010324,,,N*2B"), qt.IsNil)
+	nav = n.Navigation()
+	c.Assert(nav.Valid, qt.IsFalse)
+	c.Assert(nav.Time, qt.Equals, time.Date(2024, time.March, 1, 12, 0, 1, 0, time.UTC))
+	c.Assert(nav.Latitude, qt.Equals, float32(51.15043640136719), qt.Commentf("last known position"))
+	c.Assert(n.Age(), qt.Equals, time.Second)
+	c.Assert(n.Fresh(time.Hour), qt.IsFalse)
+
+	c.Assert(n.Update("$GPGGA,115739.00,4158.8441367,N,09147.4416929,"), qt.Equals, errInvalidGGASentence)
+}
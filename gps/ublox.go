@@ -1,10 +1,5 @@
 package gps
 
-import (
-	"errors"
-	"time"
-)
-
 // flight mode disables the GPS COCOM limits
 var flight_mode_cmd = [...]byte{
 	0xB5, 0x62, 0x06, 0x24, 0x24, 0x00, 0xFF, 0xFF, 0x06, 0x03, 0x00, 0x00, 0x00,
@@ -34,20 +29,8 @@ func SetCfgGNSS(d Device) (err error) {
 	return err
 }
 
+// sendCommand sends a framed UBX command and waits for its ACK.
 func sendCommand(d Device, command []byte) (err error) {
 	d.WriteBytes(command)
-	start := time.Now()
-	for time.Now().Sub(start) < 1000 {
-		if d.readNextByte() == '\n' {
-			if d.readNextByte() == 0xB5 {
-				d.readNextByte()
-				if d.readNextByte() == 0x05 {
-					if d.readNextByte() == 0x01 {
-						return
-					}
-				}
-			}
-		}
-	}
-	return errors.New("no ACK to GPS command")
+	return d.waitUBXAck(command[2], command[3])
 }
@@ -0,0 +1,189 @@
+package gps
+
+import (
+	"errors"
+	"time"
+)
+
+// UBX message classes.
+const (
+	UBXClassNAV = 0x01
+	UBXClassACK = 0x05
+	UBXClassCFG = 0x06
+)
+
+// UBX message IDs.
+const (
+	UBXNavStatus = 0x03 // NAV-STATUS
+	UBXNavPVT    = 0x07 // NAV-PVT
+	UBXNavSat    = 0x35 // NAV-SAT
+	UBXAckNak    = 0x00 // ACK-NAK
+	UBXAckAck    = 0x01 // ACK-ACK
+	UBXCfgPrt    = 0x00 // CFG-PRT
+	UBXCfgRate   = 0x08 // CFG-RATE
+	UBXCfgValSet = 0x8A // CFG-VALSET
+)
+
+const (
+	ubxSync1 = 0xB5
+	ubxSync2 = 0x62
+
+	// maxUBXPayload guards against corrupted length fields, it is larger
+	// than any message decoded by this package.
+	maxUBXPayload = 2048
+
+	// u-blox receivers acknowledge configuration messages within a second.
+	ubxAckTimeout = time.Second
+)
+
+var (
+	errUBXChecksum   = errors.New("invalid UBX message checksum")
+	errUBXLength     = errors.New("invalid UBX message length")
+	errUBXFrame      = errors.New("invalid UBX frame")
+	errUBXNak        = errors.New("UBX message not acknowledged")
+	errUBXAckTimeout = errors.New("no ACK to UBX message")
+)
+
+// UBXMessage is a message of the u-blox UBX binary protocol.
+type UBXMessage struct {
+	Class   uint8
+	ID      uint8
+	Payload []byte
+}
+
+// AppendFrame appends the message to buf, framed with the sync characters,
+// the length and the checksum, and returns the extended buffer.
+func (m UBXMessage) AppendFrame(buf []byte) []byte {
+	start := len(buf)
+	buf = append(buf, ubxSync1, ubxSync2, m.Class, m.ID, byte(len(m.Payload)), byte(len(m.Payload)>>8))
+	buf = append(buf, m.Payload...)
+	a, b := ubxChecksum(0, 0, buf[start+2:])
+	return append(buf, a, b)
+}
+
+// ParseUBXFrame parses a complete UBX frame. The payload of the returned
+// message refers to frame.
+func ParseUBXFrame(frame []byte) (UBXMessage, error) {
+	if len(frame) < 8 || frame[0] != ubxSync1 || frame[1] != ubxSync2 {
+		return UBXMessage{}, errUBXFrame
+	}
+	n := int(frame[4]) | int(frame[5])<<8
+	if len(frame) != n+8 {
+		return UBXMessage{}, errUBXLength
+	}
+	a, b := ubxChecksum(0, 0, frame[2:n+6])
+	if a != frame[n+6] || b != frame[n+7] {
+		return UBXMessage{}, errUBXChecksum
+	}
+	return UBXMessage{Class: frame[2], ID: frame[3], Payload: frame[6 : n+6]}, nil
+}
+
+// ubxChecksum updates the 8-bit Fletcher checksum a, b of UBX frames with
+// data.
+func ubxChecksum(a, b byte, data []byte) (byte, byte) {
+	for _, c := range data {
+		a += c
+		b += a
+	}
+	return a, b
+}
+
+// WriteUBX sends a UBX message to the GPS device.
+func (gps *Device) WriteUBX(msg UBXMessage) {
+	gps.ubxFrame = msg.AppendFrame(gps.ubxFrame[:0])
+	gps.WriteBytes(gps.ubxFrame)
+}
+
+// ReadUBX reads the next UBX message from the GPS device into msg, skipping
+// NMEA sentences and any other data in between. The payload buffer of msg
+// is reused.
+func (gps *Device) ReadUBX(msg *UBXMessage) error {
+	return gps.readUBX(msg, time.Time{})
+}
+
+// readUBX reads the next UBX message, or returns errReadTimeout if the
+// deadline passes first. A zero deadline waits forever.
+func (gps *Device) readUBX(msg *UBXMessage, deadline time.Time) error {
+	var prev byte
+	for {
+		b, err := gps.readByte(deadline)
+		if err != nil {
+			return err
+		}
+		if prev == ubxSync1 && b == ubxSync2 {
+			break
+		}
+		prev = b
+	}
+
+	var header [4]byte
+	if err := gps.readBytes(header[:], deadline); err != nil {
+		return err
+	}
+	n := int(header[2]) | int(header[3])<<8
+	if n > maxUBXPayload {
+		return errUBXLength
+	}
+	msg.Class = header[0]
+	msg.ID = header[1]
+	if cap(msg.Payload) < n {
+		msg.Payload = make([]byte, n)
+	}
+	msg.Payload = msg.Payload[:n]
+	if err := gps.readBytes(msg.Payload, deadline); err != nil {
+		return err
+	}
+
+	var checksum [2]byte
+	if err := gps.readBytes(checksum[:], deadline); err != nil {
+		return err
+	}
+	a, b := ubxChecksum(0, 0, header[:])
+	a, b = ubxChecksum(a, b, msg.Payload)
+	if checksum[0] != a || checksum[1] != b {
+		return errUBXChecksum
+	}
+	return nil
+}
+
+// readBytes fills buf with the next bytes from the GPS device.
+func (gps *Device) readBytes(buf []byte, deadline time.Time) (err error) {
+	for i := range buf {
+		if buf[i], err = gps.readByte(deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendUBX sends a UBX configuration message to the GPS device and waits for
+// it to be acknowledged. Other messages received in the meantime are
+// dropped.
+func (gps *Device) SendUBX(msg UBXMessage) error {
+	gps.WriteUBX(msg)
+	return gps.waitUBXAck(msg.Class, msg.ID)
+}
+
+// waitUBXAck waits for the ACK-ACK or ACK-NAK message of the message class
+// and id.
+func (gps *Device) waitUBXAck(class, id uint8) error {
+	reply := &gps.ubxReply
+	deadline := time.Now().Add(ubxAckTimeout)
+	for {
+		err := gps.readUBX(reply, deadline)
+		if err == errReadTimeout {
+			return errUBXAckTimeout
+		}
+		if err != nil {
+			continue
+		}
+		if reply.Class != UBXClassACK || len(reply.Payload) != 2 ||
+			reply.Payload[0] != class || reply.Payload[1] != id {
+			continue
+		}
+		if reply.ID == UBXAckAck {
+			return nil
+		}
+		return errUBXNak
+	}
+}
@@ -0,0 +1,292 @@
+package gps
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// fakeUART is a UART receiving rx and recording the written bytes.
+type fakeUART struct {
+	rx []byte
+	tx []byte
+}
+
+func (u *fakeUART) Read(p []byte) (int, error) {
+	n := copy(p, u.rx)
+	u.rx = u.rx[n:]
+	return n, nil
+}
+
+func (u *fakeUART) Write(p []byte) (int, error) {
+	u.tx = append(u.tx, p...)
+	return len(p), nil
+}
+
+func (u *fakeUART) Buffered() int {
+	return len(u.rx)
+}
+
+// fakeDDC is the DDC (I2C) port of a u-blox receiver with stream bytes
+// available.
+type fakeDDC struct {
+	stream  []byte
+	written []byte
+}
+
+func (d *fakeDDC) Tx(addr uint16, w, r []byte) error {
+	switch {
+	case len(w) == 1 && w[0] == BYTES_AVAIL_REG:
+		binary.BigEndian.PutUint16(r, uint16(len(d.stream)))
+	case len(w) == 1 && w[0] == DATA_STREAM_REG:
+		n := copy(r, d.stream)
+		d.stream = d.stream[n:]
+		for i := n; i < len(r); i++ {
+			r[i] = 0xFF
+		}
+	default:
+		d.written = append(d.written, w...)
+	}
+	return nil
+}
+
+func frame(class, id uint8, payload ...byte) []byte {
+	return UBXMessage{Class: class, ID: id, Payload: payload}.AppendFrame(nil)
+}
+
+func TestUBXFrame(t *testing.T) {
+	c := qt.New(t)
+
+	f := CfgRate(100*time.Millisecond, 1).AppendFrame(nil)
+	c.Assert(f, qt.DeepEquals, []byte{0xB5, 0x62, 0x06, 0x08, 0x06, 0x00, 0x64, 0x00, 0x01, 0x00, 0x01, 0x00, 0x7A, 0x12})
+
+	msg, err := ParseUBXFrame(f)
+	c.Assert(err, qt.IsNil)
+	c.Assert(msg.Class, qt.Equals, uint8(UBXClassCFG))
+	c.Assert(msg.ID, qt.Equals, uint8(UBXCfgRate))
+	c.Assert(msg.Payload, qt.DeepEquals, []byte{0x64, 0x00, 0x01, 0x00, 0x01, 0x00})
+
+	f[len(f)-1]++
+	_, err = ParseUBXFrame(f)
+	c.Assert(err, qt.Equals, errUBXChecksum)
+	_, err = ParseUBXFrame(f[:10])
+	c.Assert(err, qt.Equals, errUBXLength)
+	_, err = ParseUBXFrame([]byte("$GPGLL*00"))
+	c.Assert(err, qt.Equals, errUBXFrame)
+}
+
+func TestCfgMessages(t *testing.T) {
+	c := qt.New(t)
+
+	msg := CfgValSet(LayerRAM|LayerBBR,
+		ConfigValue{Key: CfgRateMeas, Value: 1000},
+		ConfigValue{Key: CfgPMOperateMode, Value: uint64(PowerSaveCyclic)},
+		ConfigValue{Key: CfgI2COutProtNMEA, Value: 0})
+	c.Assert(msg.Class, qt.Equals, uint8(UBXClassCFG))
+	c.Assert(msg.ID, qt.Equals, uint8(UBXCfgValSet))
+	c.Assert(msg.Payload, qt.DeepEquals, []byte{
+		0x00, 0x03, 0x00, 0x00,
+		0x01, 0x00, 0x21, 0x30, 0xE8, 0x03,
+		0x01, 0x00, 0xD0, 0x20, 0x02,
+		0x02, 0x00, 0x72, 0x10, 0x00,
+	})
+
+	msg = CfgPrtI2C(I2C_ADDRESS, ProtocolUBX, ProtocolUBX)
+	c.Assert(msg.Payload, qt.DeepEquals, []byte{
+		0x00, 0x00, 0x00, 0x00, 0x84, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+	})
+	msg = CfgPrtUART(1, 9600, ProtocolUBX|ProtocolNMEA, ProtocolUBX)
+	c.Assert(msg.Payload, qt.DeepEquals, []byte{
+		0x01, 0x00, 0x00, 0x00, 0xC0, 0x08, 0x00, 0x00, 0x80, 0x25, 0x00, 0x00,
+		0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+	})
+}
+
+func navPVTPayload() []byte {
+	p := make([]byte, 92)
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], 388800000)
+	le.PutUint16(p[4:], 2024)
+	p[6], p[7], p[8], p[9], p[10] = 3, 1, 12, 30, 15
+	p[11] = 0x07
+	le.PutUint32(p[12:], 25)
+	le.PutUint32(p[16:], uint32(500000000))
+	p[20] = uint8(UBXFix3D)
+	p[21] = 0x01
+	p[23] = 11
+	lon, velE := int32(-1140306778), int32(-1000)
+	le.PutUint32(p[24:], uint32(lon))
+	le.PutUint32(p[28:], 511504364)
+	le.PutUint32(p[32:], 1050123)
+	le.PutUint32(p[36:], 1082123)
+	le.PutUint32(p[40:], 1500)
+	le.PutUint32(p[44:], 2500)
+	le.PutUint32(p[48:], 1000)
+	le.PutUint32(p[52:], uint32(velE))
+	le.PutUint32(p[56:], 10)
+	le.PutUint32(p[60:], 1414)
+	le.PutUint32(p[64:], 13500000)
+	le.PutUint32(p[68:], 200)
+	le.PutUint32(p[72:], 500000)
+	le.PutUint16(p[76:], 145)
+	return p
+}
+
+func TestParseNavPVT(t *testing.T) {
+	c := qt.New(t)
+
+	pvt, err := ParseNavPVT(UBXMessage{Class: UBXClassNAV, ID: UBXNavPVT, Payload: navPVTPayload()})
+	c.Assert(err, qt.IsNil)
+	c.Assert(pvt, qt.DeepEquals, NavPVT{
+		ITOW:               388800000,
+		Time:               time.Date(2024, time.March, 1, 12, 30, 15, 500000000, time.UTC),
+		ValidDate:          true,
+		ValidTime:          true,
+		FullyResolved:      true,
+		TimeAccuracy:       25,
+		FixType:            UBXFix3D,
+		FixOK:              true,
+		NumSV:              11,
+		Longitude:          -1140306778,
+		Latitude:           511504364,
+		Height:             1050123,
+		HeightMSL:          1082123,
+		HorizontalAccuracy: 1500,
+		VerticalAccuracy:   2500,
+		VelocityNorth:      1000,
+		VelocityEast:       -1000,
+		VelocityDown:       10,
+		GroundSpeed:        1414,
+		Heading:            13500000,
+		SpeedAccuracy:      200,
+		HeadingAccuracy:    500000,
+		PDOP:               145,
+	})
+
+	_, err = ParseNavPVT(UBXMessage{Class: UBXClassNAV, ID: UBXNavPVT, Payload: make([]byte, 84)})
+	c.Assert(err, qt.Equals, errUBXLength)
+	_, err = ParseNavPVT(UBXMessage{Class: UBXClassNAV, ID: UBXNavSat, Payload: navPVTPayload()})
+	c.Assert(err, qt.Equals, errUBXLength)
+}
+
+func TestParseNavSat(t *testing.T) {
+	c := qt.New(t)
+
+	sat, err := ParseNavSat(UBXMessage{Class: UBXClassNAV, ID: UBXNavSat, Payload: []byte{
+		0x00, 0x5C, 0x26, 0x17, 0x01, 0x02, 0x00, 0x00,
+		0x00, 0x07, 0x2A, 0x0E, 0x3D, 0x01, 0xF6, 0xFF, 0x1F, 0x00, 0x00, 0x00,
+		0x06, 0x41, 0x00, 0xF5, 0x10, 0x00, 0x00, 0x00, 0x21, 0x00, 0x00, 0x00,
+	}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(sat.ITOW, qt.Equals, uint32(388389888))
+	c.Assert(sat.Satellites, qt.DeepEquals, []NavSatInfo{
+		{GNSSID: 0, SVID: 7, CNO: 42, Elevation: 14, Azimuth: 317, PseudorangeResidual: -10, Quality: 7, Used: true, Health: 1},
+		{GNSSID: 6, SVID: 65, CNO: 0, Elevation: -11, Azimuth: 16, Quality: 1, Health: 2},
+	})
+
+	_, err = ParseNavSat(UBXMessage{Class: UBXClassNAV, ID: UBXNavSat, Payload: []byte{0, 0, 0, 0, 1, 1, 0, 0}})
+	c.Assert(err, qt.Equals, errUBXLength)
+}
+
+func TestParseNavStatus(t *testing.T) {
+	c := qt.New(t)
+
+	status, err := ParseNavStatus(UBXMessage{Class: UBXClassNAV, ID: UBXNavStatus, Payload: []byte{
+		0x00, 0x5C, 0x26, 0x17, 0x03, 0x0D, 0x00, 0x02,
+		0x30, 0x75, 0x00, 0x00, 0xA0, 0xBB, 0x0D, 0x00,
+	}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.DeepEquals, NavStatus{
+		ITOW:           388389888,
+		FixType:        UBXFix3D,
+		FixOK:          true,
+		WeekValid:      true,
+		TOWValid:       true,
+		PowerSaveMode:  2,
+		TimeToFirstFix: 30 * time.Second,
+		Uptime:         900 * time.Second,
+	})
+}
+
+func TestSendUBXUART(t *testing.T) {
+	c := qt.New(t)
+
+	var rx []byte
+	rx = append(rx, "$GPGLL,5109.0262317,N,11401.8407304,W,202725.00,A,D*79\r\n"...)
+	rx = append(rx, frame(UBXClassACK, UBXAckAck, UBXClassCFG, UBXCfgPrt)...)
+	rx = append(rx, frame(UBXClassNAV, UBXNavStatus, make([]byte, 16)...)...)
+	rx = append(rx, frame(UBXClassACK, UBXAckAck, UBXClassCFG, UBXCfgRate)...)
+	rx = append(rx, frame(UBXClassACK, UBXAckNak, UBXClassCFG, UBXCfgValSet)...)
+	uart := &fakeUART{rx: rx}
+	d := NewUART(uart)
+
+	msg := CfgRate(time.Second, 1)
+	c.Assert(d.SendUBX(msg), qt.IsNil)
+	c.Assert(uart.tx, qt.DeepEquals, msg.AppendFrame(nil))
+
+	err := d.SendUBX(CfgValSet(LayerRAM, ConfigValue{Key: CfgI2COutProtNMEA}))
+	c.Assert(err, qt.Equals, errUBXNak)
+}
+
+// nmeaUART is a UART receiving NMEA sentences forever.
+type nmeaUART struct {
+	fakeUART
+	i int
+}
+
+func (u *nmeaUART) Read(p []byte) (int, error) {
+	const sentence = "$GPGLL,5109.0262317,N,11401.8407304,W,202725.00,A,D*79\r\n"
+	for i := range p {
+		p[i] = sentence[u.i%len(sentence)]
+		u.i++
+	}
+	return len(p), nil
+}
+
+func (u *nmeaUART) Buffered() int {
+	return bufferSize
+}
+
+func TestSendUBXTimeout(t *testing.T) {
+	c := qt.New(t)
+
+	// The ACK is lost, the receiver only outputs NMEA sentences
+	d := NewUART(&nmeaUART{})
+	start := time.Now()
+	c.Assert(d.SendUBX(CfgRate(time.Second, 1)), qt.Equals, errUBXAckTimeout)
+	c.Assert(time.Since(start) < 2*ubxAckTimeout, qt.IsTrue)
+
+	// The receiver is silent
+	d = NewUART(&fakeUART{})
+	start = time.Now()
+	c.Assert(d.SendUBX(CfgRate(time.Second, 1)), qt.Equals, errUBXAckTimeout)
+	c.Assert(time.Since(start) < 2*ubxAckTimeout, qt.IsTrue)
+}
+
+func TestReadUBXI2C(t *testing.T) {
+	c := qt.New(t)
+
+	var stream []byte
+	stream = append(stream, "$GPGLL,5109.0262317,N,11401.8407304,W,202725.00,A,D*79\r\n"...)
+	stream = append(stream, 0xB5)
+	stream = append(stream, frame(UBXClassNAV, UBXNavPVT, navPVTPayload()...)...)
+	corrupted := frame(UBXClassNAV, UBXNavStatus, make([]byte, 16)...)
+	corrupted[10]++
+	stream = append(stream, corrupted...)
+	bus := &fakeDDC{stream: stream}
+	d := NewI2C(bus)
+
+	var msg UBXMessage
+	c.Assert(d.ReadUBX(&msg), qt.IsNil)
+	pvt, err := ParseNavPVT(msg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pvt.NumSV, qt.Equals, uint8(11))
+
+	c.Assert(d.ReadUBX(&msg), qt.Equals, errUBXChecksum)
+
+	d.WriteUBX(CfgPrtI2C(I2C_ADDRESS, ProtocolUBX, ProtocolUBX))
+	c.Assert(bus.written, qt.DeepEquals, CfgPrtI2C(I2C_ADDRESS, ProtocolUBX, ProtocolUBX).AppendFrame(nil))
+}
@@ -0,0 +1,129 @@
+package gps
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// UBXProtocol is a mask of the protocols of a port, for CFG-PRT messages.
+type UBXProtocol uint16
+
+const (
+	ProtocolUBX   UBXProtocol = 1 << 0
+	ProtocolNMEA  UBXProtocol = 1 << 1
+	ProtocolRTCM3 UBXProtocol = 1 << 5
+)
+
+// CfgPrtUART returns a CFG-PRT message that configures the UART port (1 or
+// 2) for 8N1 at the given baud rate, with the input and output protocols.
+func CfgPrtUART(port uint8, baudRate uint32, in, out UBXProtocol) UBXMessage {
+	payload := make([]byte, 20)
+	payload[0] = port
+	// 8 data bits, no parity, 1 stop bit
+	binary.LittleEndian.PutUint32(payload[4:], 0x08C0)
+	binary.LittleEndian.PutUint32(payload[8:], baudRate)
+	binary.LittleEndian.PutUint16(payload[12:], uint16(in))
+	binary.LittleEndian.PutUint16(payload[14:], uint16(out))
+	return UBXMessage{Class: UBXClassCFG, ID: UBXCfgPrt, Payload: payload}
+}
+
+// CfgPrtI2C returns a CFG-PRT message that configures the DDC (I2C) port
+// with the device address, and the input and output protocols.
+func CfgPrtI2C(address uint8, in, out UBXProtocol) UBXMessage {
+	payload := make([]byte, 20)
+	payload[0] = 0 // DDC port
+	binary.LittleEndian.PutUint32(payload[4:], uint32(address)<<1)
+	binary.LittleEndian.PutUint16(payload[12:], uint16(in))
+	binary.LittleEndian.PutUint16(payload[14:], uint16(out))
+	return UBXMessage{Class: UBXClassCFG, ID: UBXCfgPrt, Payload: payload}
+}
+
+// CfgRate returns a CFG-RATE message that sets the measurement rate, and the
+// number of measurements for each navigation solution. Measurements are
+// aligned to GPS time.
+func CfgRate(measRate time.Duration, navRate uint16) UBXMessage {
+	payload := make([]byte, 6)
+	binary.LittleEndian.PutUint16(payload[0:], uint16(measRate/time.Millisecond))
+	binary.LittleEndian.PutUint16(payload[2:], navRate)
+	binary.LittleEndian.PutUint16(payload[4:], 1) // GPS time
+	return UBXMessage{Class: UBXClassCFG, ID: UBXCfgRate, Payload: payload}
+}
+
+// ConfigLayer is a mask of the configuration layers a CFG-VALSET message is
+// applied to.
+type ConfigLayer uint8
+
+const (
+	LayerRAM   ConfigLayer = 1 << 0
+	LayerBBR   ConfigLayer = 1 << 1
+	LayerFlash ConfigLayer = 1 << 2
+)
+
+// ConfigKey is the key of a configuration item of u-blox receivers from
+// generation 9, set with CFG-VALSET messages.
+type ConfigKey uint32
+
+// Configuration keys.
+const (
+	CfgRateMeas             ConfigKey = 0x30210001 // measurement period, in ms
+	CfgRateNav              ConfigKey = 0x30210002 // measurements per navigation solution
+	CfgPMOperateMode        ConfigKey = 0x20D00001 // power mode, see PowerMode
+	CfgI2COutProtUBX        ConfigKey = 0x10720001
+	CfgI2COutProtNMEA       ConfigKey = 0x10720002
+	CfgUART1OutProtUBX      ConfigKey = 0x10740001
+	CfgUART1OutProtNMEA     ConfigKey = 0x10740002
+	CfgMsgOutNavPVTI2C      ConfigKey = 0x20910006 // NAV-PVT rate on I2C, in navigation solutions
+	CfgMsgOutNavPVTUART1    ConfigKey = 0x20910007
+	CfgMsgOutNavSatI2C      ConfigKey = 0x20910015
+	CfgMsgOutNavSatUART1    ConfigKey = 0x20910016
+	CfgMsgOutNavStatusI2C   ConfigKey = 0x2091001A
+	CfgMsgOutNavStatusUART1 ConfigKey = 0x2091001B
+)
+
+// Size returns the size in bytes of the values of the key.
+func (k ConfigKey) Size() int {
+	switch k >> 28 & 0x07 {
+	case 1, 2:
+		return 1
+	case 3:
+		return 2
+	case 4:
+		return 4
+	case 5:
+		return 8
+	}
+	return 0
+}
+
+// PowerMode is the value of the CfgPMOperateMode configuration item.
+type PowerMode uint8
+
+const (
+	PowerFull       PowerMode = 0 // continuous tracking
+	PowerSaveOnOff  PowerMode = 1 // PSMOO, on/off operation
+	PowerSaveCyclic PowerMode = 2 // PSMCT, cyclic tracking
+)
+
+// ConfigValue is a configuration item set by a CFG-VALSET message.
+type ConfigValue struct {
+	Key   ConfigKey
+	Value uint64
+}
+
+// CfgValSet returns a CFG-VALSET message that sets configuration items in
+// the given layers. A message can hold up to 64 items. For example, to
+// switch to cyclic tracking and output NAV-PVT on I2C:
+//
+//	msg := gps.CfgValSet(gps.LayerRAM,
+//		gps.ConfigValue{Key: gps.CfgPMOperateMode, Value: uint64(gps.PowerSaveCyclic)},
+//		gps.ConfigValue{Key: gps.CfgMsgOutNavPVTI2C, Value: 1})
+func CfgValSet(layers ConfigLayer, values ...ConfigValue) UBXMessage {
+	payload := []byte{0, byte(layers), 0, 0}
+	for _, v := range values {
+		payload = append(payload, byte(v.Key), byte(v.Key>>8), byte(v.Key>>16), byte(v.Key>>24))
+		for i := 0; i < v.Key.Size(); i++ {
+			payload = append(payload, byte(v.Value>>(8*i)))
+		}
+	}
+	return UBXMessage{Class: UBXClassCFG, ID: UBXCfgValSet, Payload: payload}
+}
@@ -0,0 +1,234 @@
+package gps
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// UBXFixType is the type of a fix, as reported by NAV-PVT and NAV-STATUS
+// messages.
+type UBXFixType uint8
+
+const (
+	UBXNoFix UBXFixType = iota
+	UBXFixDeadReckoning
+	UBXFix2D
+	UBXFix3D
+	UBXFixGNSSDeadReckoning
+	UBXFixTimeOnly
+)
+
+// NavPVT is the navigation solution of a NAV-PVT message.
+type NavPVT struct {
+	// ITOW is the GPS time of week of the navigation epoch, in ms.
+	ITOW uint32
+
+	// Time is the UTC time of the solution. It is only meaningful when
+	// ValidDate and ValidTime are set.
+	Time time.Time
+
+	ValidDate     bool
+	ValidTime     bool
+	FullyResolved bool
+
+	// TimeAccuracy is the time accuracy estimate, in ns.
+	TimeAccuracy uint32
+
+	FixType UBXFixType
+
+	// FixOK is set when the fix is within the accuracy masks.
+	FixOK bool
+
+	// NumSV is the number of satellites used in the solution.
+	NumSV uint8
+
+	// Longitude and Latitude are in 1e-7 degrees.
+	Longitude int32
+	Latitude  int32
+
+	// Height is the height above the ellipsoid and HeightMSL the height
+	// above mean sea level, in mm.
+	Height    int32
+	HeightMSL int32
+
+	// HorizontalAccuracy and VerticalAccuracy are the accuracy estimates of
+	// the position, in mm.
+	HorizontalAccuracy uint32
+	VerticalAccuracy   uint32
+
+	// VelocityNorth, VelocityEast and VelocityDown are in mm/s.
+	VelocityNorth int32
+	VelocityEast  int32
+	VelocityDown  int32
+
+	// GroundSpeed is the 2D ground speed, in mm/s.
+	GroundSpeed int32
+
+	// Heading is the 2D heading of motion, in 1e-5 degrees.
+	Heading int32
+
+	// SpeedAccuracy is in mm/s and HeadingAccuracy in 1e-5 degrees.
+	SpeedAccuracy   uint32
+	HeadingAccuracy uint32
+
+	// PDOP is the position dilution of precision, in 0.01 units.
+	PDOP uint16
+}
+
+// ParseNavPVT decodes a NAV-PVT message.
+func ParseNavPVT(msg UBXMessage) (pvt NavPVT, err error) {
+	p := msg.Payload
+	if msg.Class != UBXClassNAV || msg.ID != UBXNavPVT || len(p) < 92 {
+		return pvt, errUBXLength
+	}
+	le := binary.LittleEndian
+
+	pvt.ITOW = le.Uint32(p[0:])
+	valid := p[11]
+	pvt.ValidDate = valid&0x01 != 0
+	pvt.ValidTime = valid&0x02 != 0
+	pvt.FullyResolved = valid&0x04 != 0
+	pvt.Time = time.Date(int(le.Uint16(p[4:])), time.Month(p[6]), int(p[7]),
+		int(p[8]), int(p[9]), int(p[10]), 0, time.UTC).
+		Add(time.Duration(int32(le.Uint32(p[16:]))))
+	pvt.TimeAccuracy = le.Uint32(p[12:])
+	pvt.FixType = UBXFixType(p[20])
+	pvt.FixOK = p[21]&0x01 != 0
+	pvt.NumSV = p[23]
+	pvt.Longitude = int32(le.Uint32(p[24:]))
+	pvt.Latitude = int32(le.Uint32(p[28:]))
+	pvt.Height = int32(le.Uint32(p[32:]))
+	pvt.HeightMSL = int32(le.Uint32(p[36:]))
+	pvt.HorizontalAccuracy = le.Uint32(p[40:])
+	pvt.VerticalAccuracy = le.Uint32(p[44:])
+	pvt.VelocityNorth = int32(le.Uint32(p[48:]))
+	pvt.VelocityEast = int32(le.Uint32(p[52:]))
+	pvt.VelocityDown = int32(le.Uint32(p[56:]))
+	pvt.GroundSpeed = int32(le.Uint32(p[60:]))
+	pvt.Heading = int32(le.Uint32(p[64:]))
+	pvt.SpeedAccuracy = le.Uint32(p[68:])
+	pvt.HeadingAccuracy = le.Uint32(p[72:])
+	pvt.PDOP = le.Uint16(p[76:])
+	return pvt, nil
+}
+
+// NavSat is the satellite information of a NAV-SAT message.
+type NavSat struct {
+	// ITOW is the GPS time of week of the navigation epoch, in ms.
+	ITOW uint32
+
+	Satellites []NavSatInfo
+}
+
+// NavSatInfo is the information about a satellite of a NAV-SAT message.
+type NavSatInfo struct {
+	// GNSSID is the GNSS identifier: 0 for GPS, 1 for SBAS, 2 for Galileo,
+	// 3 for BeiDou, 5 for QZSS and 6 for GLONASS.
+	GNSSID uint8
+
+	// SVID is the satellite identifier within its GNSS.
+	SVID uint8
+
+	// CNO is the carrier to noise ratio, in dB-Hz.
+	CNO uint8
+
+	// Elevation is in degrees, from -90 to 90.
+	Elevation int8
+
+	// Azimuth is in degrees, from 0 to 360.
+	Azimuth int16
+
+	// PseudorangeResidual is in 0.1 m.
+	PseudorangeResidual int16
+
+	// Quality is the signal quality indicator, from 0 (no signal) to 7
+	// (code and carrier locked).
+	Quality uint8
+
+	// Used is set when the satellite is used for navigation.
+	Used bool
+
+	// Health is 0 when unknown, 1 when healthy and 2 when unhealthy.
+	Health uint8
+}
+
+// ParseNavSat decodes a NAV-SAT message.
+func ParseNavSat(msg UBXMessage) (sat NavSat, err error) {
+	p := msg.Payload
+	if msg.Class != UBXClassNAV || msg.ID != UBXNavSat || len(p) < 8 {
+		return sat, errUBXLength
+	}
+	n := int(p[5])
+	if len(p) < 8+12*n {
+		return sat, errUBXLength
+	}
+	le := binary.LittleEndian
+
+	sat.ITOW = le.Uint32(p[0:])
+	sat.Satellites = make([]NavSatInfo, n)
+	for i := range sat.Satellites {
+		b := p[8+12*i:]
+		flags := le.Uint32(b[8:])
+		sat.Satellites[i] = NavSatInfo{
+			GNSSID:              b[0],
+			SVID:                b[1],
+			CNO:                 b[2],
+			Elevation:           int8(b[3]),
+			Azimuth:             int16(le.Uint16(b[4:])),
+			PseudorangeResidual: int16(le.Uint16(b[6:])),
+			Quality:             uint8(flags & 0x07),
+			Used:                flags&0x08 != 0,
+			Health:              uint8(flags >> 4 & 0x03),
+		}
+	}
+	return sat, nil
+}
+
+// NavStatus is the receiver navigation status of a NAV-STATUS message.
+type NavStatus struct {
+	// ITOW is the GPS time of week of the navigation epoch, in ms.
+	ITOW uint32
+
+	FixType UBXFixType
+
+	// FixOK is set when the fix is within the accuracy masks.
+	FixOK bool
+
+	// DifferentialSolution is set when differential corrections are
+	// applied.
+	DifferentialSolution bool
+
+	WeekValid bool
+	TOWValid  bool
+
+	// PowerSaveMode is the power save mode state: 0 for acquisition (or
+	// power save disabled), 1 for tracking, 2 for power optimized tracking
+	// and 3 for inactive.
+	PowerSaveMode uint8
+
+	// TimeToFirstFix is the time to first fix of the receiver.
+	TimeToFirstFix time.Duration
+
+	// Uptime is the time since the receiver startup or reset.
+	Uptime time.Duration
+}
+
+// ParseNavStatus decodes a NAV-STATUS message.
+func ParseNavStatus(msg UBXMessage) (status NavStatus, err error) {
+	p := msg.Payload
+	if msg.Class != UBXClassNAV || msg.ID != UBXNavStatus || len(p) < 16 {
+		return status, errUBXLength
+	}
+	le := binary.LittleEndian
+
+	status.ITOW = le.Uint32(p[0:])
+	status.FixType = UBXFixType(p[4])
+	status.FixOK = p[5]&0x01 != 0
+	status.DifferentialSolution = p[5]&0x02 != 0
+	status.WeekValid = p[5]&0x04 != 0
+	status.TOWValid = p[5]&0x08 != 0
+	status.PowerSaveMode = p[7] & 0x03
+	status.TimeToFirstFix = time.Duration(le.Uint32(p[8:])) * time.Millisecond
+	status.Uptime = time.Duration(le.Uint32(p[12:])) * time.Millisecond
+	return status, nil
+}
@@ -0,0 +1,240 @@
+//go:build !tinygo
+
+// Package hostnet implements the netdev.Netdever and netlink.Netlinker
+// interfaces with the networking stack of the host OS, to run and test network
+// applications and drivers on a Linux box, without hardware.
+//
+// Faults of a network device can be injected: latency, loss of UDP datagrams,
+// and loss of the network link with LinkDown.
+package hostnet // import "tinygo.org/x/drivers/hostnet"
+
+import (
+	"crypto/tls"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"tinygo.org/x/drivers/netlink"
+)
+
+// Default number of sockets, as many as wifinina
+const defaultMaxSockets = 10
+
+type Config struct {
+	// Latency delays each Connect and Send, like the time taken by a network
+	// device to process them.
+	Latency time.Duration
+
+	// Loss is the probability, from 0 to 1, that a UDP datagram is lost when
+	// sent or received.  TCP connections are not affected.
+	Loss float64
+
+	// MaxSockets is the number of sockets available.  The default zero
+	// value means 10.
+	MaxSockets int
+
+	// TLSConfig is the configuration of TLS connections.  The server name
+	// defaults to the host passed to Connect.
+	TLSConfig *tls.Config
+
+	// AccessPoints are the access points returned by NetScan, simulating
+	// the Wifi networks in range.
+	AccessPoints []netlink.AccessPoint
+}
+
+type Device struct {
+	cfg      *Config
+	notifyCb func(netlink.Event)
+	mu       sync.Mutex
+
+	params *netlink.ConnectParams
+
+	netConnected bool
+	linkUp       bool
+
+	killWatchdog chan struct{}
+
+	sockets map[int]*socket // keyed by sockfd
+}
+
+// New returns a new host network device.  The device must be connected with
+// NetConnect before connecting sockets.
+func New(cfg *Config) *Device {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Device{
+		cfg:     cfg,
+		sockets: make(map[int]*socket),
+	}
+}
+
+// notify sends an event to the NetNotify callback.  It must be called without
+// holding the lock, so the callback can use the device.
+func (d *Device) notify(event netlink.Event) {
+	d.mu.Lock()
+	cb := d.notifyCb
+	d.mu.Unlock()
+	if cb != nil {
+		cb(event)
+	}
+}
+
+func (d *Device) watchdog(timeout time.Duration, kill chan struct{}) {
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kill:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			down := d.netConnected && !d.linkUp
+			if down {
+				d.linkUp = true
+			}
+			d.mu.Unlock()
+			if down {
+				d.notify(netlink.EventNetUp)
+			}
+		}
+	}
+}
+
+// NetConnect connects the device to the network of the host.  The host has no
+// radio, so the SSID and passphrase are not used, and both the station and
+// access point modes are accepted.
+func (d *Device) NetConnect(params *netlink.ConnectParams) error {
+	d.mu.Lock()
+
+	if d.netConnected {
+		d.mu.Unlock()
+		return netlink.ErrConnected
+	}
+
+	if params == nil {
+		params = &netlink.ConnectParams{}
+	}
+	d.params = params
+	d.netConnected = true
+	d.linkUp = true
+
+	if d.params.WatchdogTimeout != 0 {
+		d.killWatchdog = make(chan struct{})
+		go d.watchdog(d.params.WatchdogTimeout, d.killWatchdog)
+	}
+
+	d.mu.Unlock()
+
+	d.notify(netlink.EventNetUp)
+	return nil
+}
+
+// NetDisconnect disconnects the device from the network, breaking the
+// connections of the sockets.
+func (d *Device) NetDisconnect() {
+	d.mu.Lock()
+
+	if !d.netConnected {
+		d.mu.Unlock()
+		return
+	}
+
+	if d.killWatchdog != nil {
+		// The watchdog may be waiting for the lock, so don't wait for it
+		close(d.killWatchdog)
+		d.killWatchdog = nil
+	}
+
+	d.breakSockets()
+	d.netConnected = false
+	d.linkUp = false
+
+	d.mu.Unlock()
+
+	d.notify(netlink.EventNetDown)
+}
+
+func (d *Device) NetNotify(cb func(netlink.Event)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifyCb = cb
+}
+
+// LinkDown simulates the loss of the network link, as when the device goes
+// out of range of the access point.  The connections of the sockets are
+// broken, new connections fail, and EventNetDown is sent to the NetNotify
+// callback.
+//
+// The link is restored by LinkUp or, if the ConnectParams have a
+// WatchdogTimeout, by the watchdog on its next tick.
+func (d *Device) LinkDown() {
+	d.mu.Lock()
+	if !d.linkUp {
+		d.mu.Unlock()
+		return
+	}
+	d.breakSockets()
+	d.linkUp = false
+	d.mu.Unlock()
+
+	d.notify(netlink.EventNetDown)
+}
+
+// LinkUp restores the network link lost with LinkDown, and sends EventNetUp
+// to the NetNotify callback.
+func (d *Device) LinkUp() {
+	d.mu.Lock()
+	if d.linkUp || !d.netConnected {
+		d.mu.Unlock()
+		return
+	}
+	d.linkUp = true
+	d.mu.Unlock()
+
+	d.notify(netlink.EventNetUp)
+}
+
+// GetHardwareAddr returns the MAC address of the first network interface of
+// the host that has one.
+func (d *Device) GetHardwareAddr() (net.HardwareAddr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback == 0 && len(iface.HardwareAddr) > 0 {
+			return iface.HardwareAddr, nil
+		}
+	}
+	return nil, netlink.ErrNotSupported
+}
+
+// Addr returns the first IPv4 address of the host that is not a loopback
+// address, or 127.0.0.1 if there is none.
+func (d *Device) Addr() (netip.Addr, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err != nil {
+			continue
+		}
+		if ip := prefix.Addr(); ip.Is4() && !ip.IsLoopback() {
+			return ip, nil
+		}
+	}
+	return netip.AddrFrom4([4]byte{127, 0, 0, 1}), nil
+}
+
+// NetScan returns the access points of the configuration
+func (d *Device) NetScan() ([]netlink.AccessPoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	aps := make([]netlink.AccessPoint, len(d.cfg.AccessPoints))
+	copy(aps, d.cfg.AccessPoints)
+	return aps, nil
+}
@@ -0,0 +1,212 @@
+//go:build !tinygo
+
+package hostnet
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/netdev"
+	"tinygo.org/x/drivers/netlink"
+)
+
+var localhost = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+
+func newTestDevice(c *qt.C, cfg *Config) *Device {
+	d := New(cfg)
+	c.Assert(d.NetConnect(nil), qt.IsNil)
+	c.Cleanup(d.NetDisconnect)
+	return d
+}
+
+// listen starts a TCP server on the device, and returns its sockfd and port.
+func listen(c *qt.C, d *Device) (int, netip.AddrPort) {
+	fd, err := d.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Bind(fd, netip.AddrPortFrom(localhost, 0)), qt.IsNil)
+	c.Assert(d.Listen(fd, 1), qt.IsNil)
+	return fd, d.sockets[fd].laddr
+}
+
+func TestTCP(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, nil)
+	server, addr := listen(c, d)
+
+	client, err := d.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Connect(client, "", addr), qt.IsNil)
+
+	conn, raddr, err := d.Accept(server)
+	c.Assert(err, qt.IsNil)
+	c.Assert(raddr, qt.Equals, d.sockets[client].laddr)
+
+	n, err := d.Send(client, []byte("hello"), 0, time.Time{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 5)
+
+	buf := make([]byte, 16)
+	n, err = d.Recv(conn, buf, 0, time.Time{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:n]), qt.Equals, "hello")
+
+	// Nothing more to receive
+	_, err = d.Recv(conn, buf, 0, time.Now().Add(10*time.Millisecond))
+	c.Assert(err, qt.Equals, netdev.ErrTimeout)
+
+	// Closed by the peer
+	c.Assert(d.Close(client), qt.IsNil)
+	n, err = d.Recv(conn, buf, 0, time.Time{})
+	c.Assert(n, qt.Equals, -1)
+	c.Assert(err, qt.Equals, io.EOF)
+
+	c.Assert(d.Close(conn), qt.IsNil)
+	c.Assert(d.Close(conn), qt.Equals, netdev.ErrInvalidSocketFd)
+	c.Assert(d.Close(server), qt.IsNil)
+}
+
+func TestUDPLoss(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, &Config{Loss: 1})
+
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	c.Assert(err, qt.IsNil)
+	defer peer.Close()
+
+	fd, err := d.Socket(netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Bind(fd, netip.AddrPortFrom(localhost, 0)), qt.IsNil)
+	c.Assert(d.Connect(fd, "", peer.LocalAddr().(*net.UDPAddr).AddrPort()), qt.IsNil)
+
+	// The datagrams are lost both ways
+	n, err := d.Send(fd, []byte("ping"), 0, time.Time{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 4)
+	peer.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, _, err = peer.ReadFromUDP(make([]byte, 16))
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	_, err = peer.WriteToUDPAddrPort([]byte("pong"), d.sockets[fd].laddr)
+	c.Assert(err, qt.IsNil)
+	_, err = d.Recv(fd, make([]byte, 16), 0, time.Now().Add(10*time.Millisecond))
+	c.Assert(err, qt.Equals, netdev.ErrTimeout)
+
+	// Until there is no loss
+	d.cfg.Loss = 0
+	peer.SetReadDeadline(time.Time{})
+	_, err = d.Send(fd, []byte("ping"), 0, time.Time{})
+	c.Assert(err, qt.IsNil)
+	buf := make([]byte, 16)
+	n, _, err = peer.ReadFromUDP(buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:n]), qt.Equals, "ping")
+}
+
+func TestLinkDown(t *testing.T) {
+	c := qt.New(t)
+	d := New(&Config{Latency: 5 * time.Millisecond})
+
+	var mu sync.Mutex
+	var events []netlink.Event
+	d.NetNotify(func(e netlink.Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+	c.Assert(d.NetConnect(nil), qt.IsNil)
+
+	server, addr := listen(c, d)
+	client, err := d.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Connect(client, "", addr), qt.IsNil)
+	conn, _, err := d.Accept(server)
+	c.Assert(err, qt.IsNil)
+
+	// A pending Recv gets EOF
+	done := make(chan error)
+	go func() {
+		_, err := d.Recv(conn, make([]byte, 16), 0, time.Time{})
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	d.LinkDown()
+	c.Assert(<-done, qt.Equals, io.EOF)
+
+	_, err = d.Send(client, []byte("hello"), 0, time.Time{})
+	c.Assert(err, qt.Equals, io.EOF)
+
+	other, err := d.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Connect(other, "", addr), qt.Not(qt.IsNil))
+
+	d.LinkUp()
+	c.Assert(d.Connect(other, "", addr), qt.IsNil)
+
+	// The watchdog brings the link back up
+	d.NetDisconnect()
+	c.Assert(d.NetConnect(&netlink.ConnectParams{WatchdogTimeout: 10 * time.Millisecond}), qt.IsNil)
+	defer d.NetDisconnect()
+	d.LinkDown()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	c.Assert(events, qt.DeepEquals, []netlink.Event{
+		netlink.EventNetUp, netlink.EventNetDown, netlink.EventNetUp, // LinkDown, LinkUp
+		netlink.EventNetDown, netlink.EventNetUp, // NetDisconnect, NetConnect
+		netlink.EventNetDown, netlink.EventNetUp, // LinkDown, watchdog
+	})
+	mu.Unlock()
+}
+
+func TestNoMoreSockets(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, &Config{MaxSockets: 2})
+
+	for i := 0; i < 2; i++ {
+		fd, err := d.Socket(netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+		c.Assert(err, qt.IsNil)
+		c.Assert(fd, qt.Equals, i)
+	}
+	_, err := d.Socket(netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	c.Assert(err, qt.Equals, netdev.ErrNoMoreSockets)
+
+	c.Assert(d.Close(0), qt.IsNil)
+	fd, err := d.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fd, qt.Equals, 0)
+}
+
+func TestUDPServer(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, nil)
+
+	fd, err := d.Socket(netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Bind(fd, netip.AddrPortFrom(localhost, 0)), qt.IsNil)
+	c.Assert(d.Listen(fd, 1), qt.IsNil)
+
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	c.Assert(err, qt.IsNil)
+	defer peer.Close()
+
+	_, err = peer.WriteToUDPAddrPort([]byte("ping"), d.sockets[fd].laddr)
+	c.Assert(err, qt.IsNil)
+
+	buf := make([]byte, 16)
+	n, from, err := d.RecvFrom(fd, buf, 0, time.Time{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:n]), qt.Equals, "ping")
+	c.Assert(from, qt.Equals, peer.LocalAddr().(*net.UDPAddr).AddrPort())
+
+	n, err = d.SendTo(fd, []byte("pong"), 0, from, time.Time{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 4)
+	n, _, err = peer.ReadFromUDP(buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:n]), qt.Equals, "pong")
+}
@@ -0,0 +1,605 @@
+//go:build !tinygo
+
+package hostnet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+
+	"tinygo.org/x/drivers/netdev"
+)
+
+type socket struct {
+	protocol int
+	laddr    netip.AddrPort // Set in Bind()
+	raddr    netip.AddrPort // Set in Connect() or Accept()
+
+	conn     net.Conn         // TCP and TLS connection
+	listener *net.TCPListener // TCP server, set in Listen()
+	udp      *net.UDPConn     // UDP socket, set in Bind() or Connect()
+
+	// The connection was broken by the loss of the link
+	broken bool
+}
+
+// close closes the host sockets of the socket
+func (s *socket) close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.udp != nil {
+		s.udp.Close()
+	}
+}
+
+// breakSockets breaks the connections of the sockets, which then only return
+// io.EOF.  The servers keep listening.
+func (d *Device) breakSockets() {
+	for _, socket := range d.sockets {
+		if socket.conn != nil || socket.udp != nil {
+			socket.broken = true
+			if socket.conn != nil {
+				socket.conn.Close()
+			}
+			if socket.udp != nil {
+				socket.udp.Close()
+			}
+		}
+	}
+}
+
+// lost returns whether a UDP datagram is lost
+func (d *Device) lost() bool {
+	return d.cfg.Loss > 0 && rand.Float64() < d.cfg.Loss
+}
+
+// sleep waits for the latency of the device, without holding the lock
+func (d *Device) sleep() {
+	if d.cfg.Latency > 0 {
+		d.mu.Unlock()
+		time.Sleep(d.cfg.Latency)
+		d.mu.Lock()
+	}
+}
+
+// addrPort returns the IPv4 address and port of a host socket address
+func addrPort(addr net.Addr) netip.AddrPort {
+	var ip netip.AddrPort
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.AddrPort()
+	case *net.UDPAddr:
+		ip = addr.AddrPort()
+	}
+	return netip.AddrPortFrom(ip.Addr().Unmap(), ip.Port())
+}
+
+// hostErr converts the errors of the host sockets to the errors of netdev:
+// netdev.ErrTimeout when the deadline is exceeded, and io.EOF when the
+// connection is closed.
+func hostErr(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return netdev.ErrTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return netdev.ErrTimeout
+	case errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+		return io.EOF
+	case errors.Is(err, os.ErrClosed):
+		return io.EOF
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "read" || opErr.Op == "write") {
+		// Connection reset by peer, broken pipe, ...
+		return io.EOF
+	}
+	return err
+}
+
+func (d *Device) GetHostByName(name string) (netip.Addr, error) {
+
+	// If it's already in dotted-decimal notation, return a copy
+	// per gethostbyname(3).
+	if ip, err := netip.ParseAddr(name); err == nil {
+		return ip, nil
+	}
+
+	d.mu.Lock()
+	up := d.linkUp
+	d.mu.Unlock()
+	if !up {
+		return netip.Addr{}, netdev.ErrHostUnknown
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip4", name)
+	if err != nil || len(ips) == 0 {
+		return netip.Addr{}, netdev.ErrHostUnknown
+	}
+
+	return ips[0].Unmap(), nil
+}
+
+// newSockfd returns the next available sockfd, or -1 if none available
+func (d *Device) newSockfd() int {
+	max := d.cfg.MaxSockets
+	if max == 0 {
+		max = defaultMaxSockets
+	}
+	if len(d.sockets) >= max {
+		return -1
+	}
+	// Search for the next available sockfd starting at 0
+	for sockfd := 0; ; sockfd++ {
+		if _, ok := d.sockets[sockfd]; !ok {
+			return sockfd
+		}
+	}
+}
+
+// See man socket(2) for standard Berkely sockets for Socket, Bind, etc.
+// The driver strives to meet the function and semantics of socket(2).
+
+func (d *Device) Socket(domain int, stype int, protocol int) (int, error) {
+
+	switch domain {
+	case netdev.AF_INET:
+	default:
+		return -1, netdev.ErrFamilyNotSupported
+	}
+
+	switch {
+	case protocol == netdev.IPPROTO_TCP && stype == netdev.SOCK_STREAM:
+	case protocol == netdev.IPPROTO_TLS && stype == netdev.SOCK_STREAM:
+	case protocol == netdev.IPPROTO_UDP && stype == netdev.SOCK_DGRAM:
+	default:
+		return -1, netdev.ErrProtocolNotSupported
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sockfd := d.newSockfd()
+	if sockfd == -1 {
+		return -1, netdev.ErrNoMoreSockets
+	}
+
+	d.sockets[sockfd] = &socket{
+		protocol: protocol,
+	}
+
+	return sockfd, nil
+}
+
+func (d *Device) Bind(sockfd int, ip netip.AddrPort) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	if socket.protocol == netdev.IPPROTO_UDP {
+		udp, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(ip))
+		if err != nil {
+			return err
+		}
+		socket.udp = udp
+		ip = addrPort(udp.LocalAddr())
+	}
+
+	socket.laddr = ip
+
+	return nil
+}
+
+func (d *Device) Connect(sockfd int, host string, ip netip.AddrPort) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	if !d.linkUp {
+		if host == "" {
+			return fmt.Errorf("Connect to %s failed", ip)
+		} else {
+			return fmt.Errorf("Connect to %s:%d failed", host, ip.Port())
+		}
+	}
+
+	if socket.protocol == netdev.IPPROTO_UDP {
+		if socket.udp == nil {
+			udp, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(socket.laddr))
+			if err != nil {
+				return err
+			}
+			socket.udp = udp
+			socket.laddr = addrPort(udp.LocalAddr())
+		}
+		socket.raddr = ip
+		return nil
+	}
+
+	// Unlock while connecting, so others can make progress
+	d.sleep()
+	d.mu.Unlock()
+	conn, err := d.dial(socket, host, ip)
+	d.mu.Lock()
+
+	if err != nil {
+		if host == "" {
+			return fmt.Errorf("Connect to %s failed: %w", ip, err)
+		} else {
+			return fmt.Errorf("Connect to %s:%d failed: %w", host, ip.Port(), err)
+		}
+	}
+
+	// Check if the socket was closed, or the link lost, meanwhile
+	if d.sockets[sockfd] != socket {
+		conn.Close()
+		return netdev.ErrInvalidSocketFd
+	}
+	if !d.linkUp {
+		conn.Close()
+		return fmt.Errorf("Connect to %s failed", ip)
+	}
+
+	socket.conn = conn
+	socket.laddr = addrPort(conn.LocalAddr())
+	socket.raddr = addrPort(conn.RemoteAddr())
+	socket.broken = false
+
+	return nil
+}
+
+func (d *Device) dial(socket *socket, host string, ip netip.AddrPort) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if socket.laddr.Port() != 0 {
+		dialer.LocalAddr = net.TCPAddrFromAddrPort(socket.laddr)
+	}
+
+	if socket.protocol != netdev.IPPROTO_TLS {
+		return dialer.Dial("tcp4", ip.String())
+	}
+
+	config := &tls.Config{}
+	if d.cfg.TLSConfig != nil {
+		config = d.cfg.TLSConfig.Clone()
+	}
+	addr := ip.String()
+	if host != "" {
+		addr = net.JoinHostPort(host, strconv.Itoa(int(ip.Port())))
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+	}
+	return tls.DialWithDialer(dialer, "tcp4", addr, config)
+}
+
+func (d *Device) Listen(sockfd int, backlog int) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	switch socket.protocol {
+	case netdev.IPPROTO_TCP:
+	case netdev.IPPROTO_UDP:
+		return nil
+	default:
+		return netdev.ErrProtocolNotSupported
+	}
+
+	listener, err := net.ListenTCP("tcp4", net.TCPAddrFromAddrPort(socket.laddr))
+	if err != nil {
+		return err
+	}
+	socket.listener = listener
+	socket.laddr = addrPort(listener.Addr())
+
+	return nil
+}
+
+func (d *Device) Accept(sockfd int) (int, netip.AddrPort, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	server, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+	}
+
+	listener := server.listener
+	if listener == nil {
+		return -1, netip.AddrPort{}, netdev.ErrProtocolNotSupported
+	}
+
+	for {
+		// Unlock while waiting for a client, so others can make progress
+		d.mu.Unlock()
+		conn, err := listener.AcceptTCP()
+		d.mu.Lock()
+
+		// Check if the socket was closed meanwhile
+		if d.sockets[sockfd] != server {
+			if conn != nil {
+				conn.Close()
+			}
+			return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+		}
+		if err != nil {
+			return -1, netip.AddrPort{}, err
+		}
+
+		// Clients can't reach the device without a link
+		if !d.linkUp {
+			conn.Close()
+			continue
+		}
+
+		clientfd := d.newSockfd()
+		if clientfd == -1 {
+			conn.Close()
+			return -1, netip.AddrPort{}, netdev.ErrNoMoreSockets
+		}
+
+		raddr := addrPort(conn.RemoteAddr())
+		d.sockets[clientfd] = &socket{
+			protocol: netdev.IPPROTO_TCP,
+			laddr:    addrPort(conn.LocalAddr()),
+			raddr:    raddr,
+			conn:     conn,
+		}
+
+		return clientfd, raddr, nil
+	}
+}
+
+func (d *Device) Send(sockfd int, buf []byte, flags int,
+	deadline time.Time) (int, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
+	d.sleep()
+
+	if socket.broken {
+		return -1, io.EOF
+	}
+
+	switch {
+	case socket.conn != nil:
+		conn := socket.conn
+		conn.SetWriteDeadline(deadline)
+
+		// Unlock while sending, so others can make progress
+		d.mu.Unlock()
+		n, err := conn.Write(buf)
+		d.mu.Lock()
+
+		if err != nil {
+			if n > 0 {
+				return n, hostErr(err)
+			}
+			return -1, hostErr(err)
+		}
+		return n, nil
+
+	case socket.udp != nil:
+		if !socket.raddr.IsValid() {
+			return -1, fmt.Errorf("Must Connect before Sending")
+		}
+		return d.sendTo(socket, buf, socket.raddr, deadline)
+	}
+
+	return -1, fmt.Errorf("Must Connect before Sending")
+}
+
+// sendTo sends a datagram on the UDP socket, unless it's lost
+func (d *Device) sendTo(socket *socket, buf []byte, to netip.AddrPort,
+	deadline time.Time) (int, error) {
+
+	if d.lost() {
+		return len(buf), nil
+	}
+	socket.udp.SetWriteDeadline(deadline)
+	n, err := socket.udp.WriteToUDPAddrPort(buf, to)
+	if err != nil {
+		return -1, hostErr(err)
+	}
+	return n, nil
+}
+
+// SendTo sends a datagram on a UDP socket to any address.  The socket must be
+// bound or connected.
+func (d *Device) SendTo(sockfd int, buf []byte, flags int, to netip.AddrPort,
+	deadline time.Time) (int, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netdev.ErrProtocolNotSupported
+	}
+
+	d.sleep()
+
+	if socket.broken {
+		return -1, io.EOF
+	}
+	if socket.udp == nil {
+		return -1, fmt.Errorf("Must Bind or Connect before Sending")
+	}
+
+	return d.sendTo(socket, buf, to, deadline)
+}
+
+func (d *Device) Recv(sockfd int, buf []byte, flags int,
+	deadline time.Time) (int, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
+	if socket.broken {
+		return -1, io.EOF
+	}
+
+	switch {
+	case socket.conn != nil:
+		conn := socket.conn
+		conn.SetReadDeadline(deadline)
+
+		// Unlock while receiving, so others can make progress
+		d.mu.Unlock()
+		n, err := conn.Read(buf)
+		d.mu.Lock()
+
+		if n > 0 {
+			return n, nil
+		}
+		return -1, hostErr(err)
+
+	case socket.udp != nil:
+		n, _, err := d.recvFrom(socket, buf, deadline)
+		return n, err
+	}
+
+	return -1, fmt.Errorf("Must Bind or Connect before Receiving")
+}
+
+// recvFrom receives the next datagram on the UDP socket that is not lost
+func (d *Device) recvFrom(socket *socket, buf []byte,
+	deadline time.Time) (int, netip.AddrPort, error) {
+
+	udp := socket.udp
+	udp.SetReadDeadline(deadline)
+	for {
+		// Unlock while receiving, so others can make progress
+		d.mu.Unlock()
+		n, from, err := udp.ReadFromUDPAddrPort(buf)
+		d.mu.Lock()
+
+		if err != nil {
+			return -1, netip.AddrPort{}, hostErr(err)
+		}
+		if !d.lost() {
+			return n, netip.AddrPortFrom(from.Addr().Unmap(), from.Port()), nil
+		}
+	}
+}
+
+// RecvFrom receives a datagram from any address on a UDP socket, and returns
+// its source address.  The socket must be bound or connected.
+func (d *Device) RecvFrom(sockfd int, buf []byte, flags int,
+	deadline time.Time) (int, netip.AddrPort, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+	}
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netip.AddrPort{}, netdev.ErrProtocolNotSupported
+	}
+
+	if socket.broken {
+		return -1, netip.AddrPort{}, io.EOF
+	}
+	if socket.udp == nil {
+		return -1, netip.AddrPort{}, fmt.Errorf("Must Bind or Connect before Receiving")
+	}
+
+	return d.recvFrom(socket, buf, deadline)
+}
+
+func (d *Device) Close(sockfd int) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	socket.close()
+	delete(d.sockets, sockfd)
+
+	return nil
+}
+
+func (d *Device) SetSockOpt(sockfd int, level int, opt int, value interface{}) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	socket, ok := d.sockets[sockfd]
+	if !ok {
+		return netdev.ErrInvalidSocketFd
+	}
+
+	conn, ok := socket.conn.(*net.TCPConn)
+	if !ok {
+		return netdev.ErrNotSupported
+	}
+
+	switch {
+	case level == netdev.SOL_SOCKET && opt == netdev.SO_KEEPALIVE:
+		switch v := value.(type) {
+		case bool:
+			return conn.SetKeepAlive(v)
+		case int:
+			return conn.SetKeepAlive(v != 0)
+		}
+	case level == netdev.SOL_TCP && opt == netdev.TCP_KEEPINTVL:
+		switch v := value.(type) {
+		case time.Duration:
+			return conn.SetKeepAlivePeriod(v)
+		case int:
+			return conn.SetKeepAlivePeriod(time.Duration(v) * time.Second)
+		}
+	}
+
+	return netdev.ErrNotSupported
+}
@@ -0,0 +1,20 @@
+//go:build !tinygo
+
+package hostnet
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/netdev/netdevtest"
+)
+
+func TestNetdever(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, &Config{MaxSockets: 8})
+	netdevtest.TestNetdever(t, d, &netdevtest.Config{
+		Addr:       localhost,
+		Port:       42080,
+		MaxSockets: 8,
+	})
+}
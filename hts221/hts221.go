@@ -19,6 +19,8 @@ type Device struct {
 	humidityZero     float32
 	temperatureSlope float32
 	temperatureZero  float32
+	temperature      int32
+	humidity         int32
 }
 
 // New creates a new HTS221 connection. The I2C bus must already be
@@ -82,6 +84,38 @@ func (d *Device) ReadTemperature() (temperature int32, err error) {
 	return int32(tValueCalib * 1000), nil
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Humidity methods. Returns an error if the device is not turned on.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature != 0 {
+		temperature, err := d.ReadTemperature()
+		if err != nil {
+			return err
+		}
+		d.temperature = temperature
+	}
+	if which&drivers.Humidity != 0 {
+		humidity, err := d.ReadHumidity()
+		if err != nil {
+			return err
+		}
+		d.humidity = humidity
+	}
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return d.humidity
+}
+
 // Resolution sets the HTS221's resolution mode.
 // The higher resolutions are more accurate but comsume more power (see datasheet).
 // The number of averaged samples will be (h + 2) ^ 2, (t + 1) ^ 2
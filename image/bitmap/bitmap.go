@@ -0,0 +1,178 @@
+// Package bitmap decodes PNG and JPEG images into pixel.Image buffers, or
+// straight to displays that draw bitmaps in their native pixel format, such as
+// st7789, ssd1306 and uc8151.
+//
+// The images are converted from the RGB565 data of the png and jpeg decoders,
+// optionally with dithering for targets with few colors such as monochrome
+// displays and e-paper.
+package bitmap
+
+import (
+	"image"
+	"io"
+
+	"tinygo.org/x/drivers/image/jpeg"
+	"tinygo.org/x/drivers/image/png"
+	"tinygo.org/x/drivers/pixel"
+)
+
+// Drawer is a display that draws bitmaps in its native pixel format.
+type Drawer[T pixel.Color] interface {
+	DrawBitmap(x, y int16, bitmap pixel.Image[T]) error
+}
+
+// Options are the options of the decoding of an image. A nil *Options
+// decodes the whole image without dithering.
+type Options struct {
+	// Bounds crops the image, in the coordinates of the original image. An
+	// empty rectangle decodes the whole image.
+	Bounds image.Rectangle
+
+	// Scale downscales the image by an integer factor. 0 and 1 do not
+	// downscale the image.
+	Scale int
+
+	// Dither is the dithering of the colors to the pixel format.
+	Dither Dither
+}
+
+// callback is the callback of the png and jpeg decoders.
+type callback = func(data []uint16, x, y, w, h, width, height int16)
+
+// decoder is implemented by png.Decoder and jpeg.Decoder.
+type decoder interface {
+	SetBounds(r image.Rectangle)
+	SetScale(scale int)
+	Decode(r io.Reader) error
+}
+
+func decode(dec decoder, r io.Reader, o *Options) error {
+	if o != nil {
+		dec.SetBounds(o.Bounds)
+		dec.SetScale(o.Scale)
+	}
+	return dec.Decode(r)
+}
+
+// DecodePNG decodes a PNG image from r into img, with its top left corner at
+// 0, 0. The parts of the image outside of img are discarded.
+func DecodePNG[T pixel.Color](r io.Reader, img pixel.Image[T], o *Options) error {
+	c := newConverter[T](o)
+	return decode(png.NewDecoder(nil, c.toImage(img)), r, o)
+}
+
+// DecodeJPEG decodes a JPEG image from r into img, with its top left corner
+// at 0, 0. The parts of the image outside of img are discarded.
+func DecodeJPEG[T pixel.Color](r io.Reader, img pixel.Image[T], o *Options) error {
+	c := newConverter[T](o)
+	return decode(jpeg.NewDecoder(nil, c.toImage(img)), r, o)
+}
+
+// DrawPNG decodes a PNG image from r and draws it on d, with its top left
+// corner at x, y. The image is drawn one row at a time, and must fit the
+// display, for example by cropping or downscaling it.
+func DrawPNG[T pixel.Color](d Drawer[T], x, y int16, r io.Reader, o *Options) error {
+	c := newConverter[T](o)
+	fn, err := c.toDrawer(d, x, y)
+	if decodeErr := decode(png.NewDecoder(nil, fn), r, o); decodeErr != nil {
+		return decodeErr
+	}
+	return *err
+}
+
+// DrawJPEG decodes a JPEG image from r and draws it on d, with its top left
+// corner at x, y. The image is drawn in blocks of up to 16 x 16 pixels, and
+// must fit the display, for example by cropping or downscaling it.
+func DrawJPEG[T pixel.Color](d Drawer[T], x, y int16, r io.Reader, o *Options) error {
+	c := newConverter[T](o)
+	fn, err := c.toDrawer(d, x, y)
+	if decodeErr := decode(jpeg.NewDecoder(nil, fn), r, o); decodeErr != nil {
+		return decodeErr
+	}
+	return *err
+}
+
+// converter converts the RGB565 data of the decoders to the pixel format T.
+type converter[T pixel.Color] struct {
+	dither Dither
+
+	// Errors of the Floyd-Steinberg dithering, for the columns of the rows
+	// of the band of the image from errY, and of the row below it.
+	errs   [][3]int16
+	width  int
+	errY   int
+	errRow int
+}
+
+func newConverter[T pixel.Color](o *Options) *converter[T] {
+	c := &converter[T]{}
+	if o != nil {
+		c.dither = o.Dither
+	}
+	return c
+}
+
+// convert converts a portion of the image passed to a callback, calling set
+// for each pixel in order.
+func (c *converter[T]) convert(data []uint16, x, y, w, h, width int16, set func(i, j int, color T)) {
+	if c.dither == FloydSteinberg {
+		c.startBand(int(y), int(h), int(width))
+	}
+	for j := 0; j < int(h); j++ {
+		for i := 0; i < int(w); i++ {
+			r, g, b := rgb(data[j*int(w)+i])
+			px, py := int(x)+i, int(y)+j
+			var color T
+			switch c.dither {
+			case FloydSteinberg:
+				color = c.floydSteinberg(px, py, r, g, b)
+			case Ordered:
+				color = ordered[T](px, py, r, g, b)
+			default:
+				color = pixel.NewColor[T](r, g, b)
+			}
+			set(i, j, color)
+		}
+	}
+}
+
+func (c *converter[T]) toImage(img pixel.Image[T]) callback {
+	imgWidth, imgHeight := img.Size()
+	return func(data []uint16, x, y, w, h, width, height int16) {
+		c.convert(data, x, y, w, h, width, func(i, j int, color T) {
+			if px, py := int(x)+i, int(y)+j; px < imgWidth && py < imgHeight {
+				img.Set(px, py, color)
+			}
+		})
+	}
+}
+
+// toDrawer returns a callback drawing the image on d at x0, y0, and the
+// first error returned by d.
+func (c *converter[T]) toDrawer(d Drawer[T], x0, y0 int16) (callback, *error) {
+	var (
+		buf pixel.Image[T]
+		err error
+	)
+	return func(data []uint16, x, y, w, h, width, height int16) {
+		if err != nil {
+			return
+		}
+		if int(w)*int(h) > buf.Len() {
+			buf = pixel.NewImage[T](int(w), int(h))
+		}
+		img := buf.Rescale(int(w), int(h))
+		c.convert(data, x, y, w, h, width, func(i, j int, color T) {
+			img.Set(i, j, color)
+		})
+		err = d.DrawBitmap(x0+x, y0+y, img)
+	}, &err
+}
+
+// rgb returns the 8-bit components of a RGB565 color.
+func rgb(c uint16) (r, g, b uint8) {
+	r = uint8(c>>11) << 3
+	g = uint8(c>>5) << 2
+	b = uint8(c) << 3
+	return r | r>>5, g | g>>6, b | b>>5
+}
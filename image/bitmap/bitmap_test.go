@@ -0,0 +1,141 @@
+package bitmap_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"tinygo.org/x/drivers/image/bitmap"
+	"tinygo.org/x/drivers/image/jpeg"
+	"tinygo.org/x/drivers/image/png"
+	"tinygo.org/x/drivers/pixel"
+)
+
+func encodePNG(t *testing.T, m image.Image) *bytes.Buffer {
+	var b bytes.Buffer
+	if err := png.Encode(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+func gray(width, height int, y uint8) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range m.Pix {
+		m.Pix[i] = y
+		if i%4 == 3 {
+			m.Pix[i] = 0xff
+		}
+	}
+	return m
+}
+
+func TestDecodePNG(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			m.SetRGBA(x, y, color.RGBA{uint8(x * 32), uint8(y * 64), 0xff, 0xff})
+		}
+	}
+	img := pixel.NewImage[pixel.RGB888](3, 4)
+	err := bitmap.DecodePNG(encodePNG(t, m), img, &bitmap.Options{Scale: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			// The colors are converted from RGB565.
+			want := color.RGBA{[]uint8{0, 0x42, 0x84}[x], []uint8{0, 0x82}[y], 0xff, 0xff}
+			if got := img.Get(x, y).RGBA(); got != want {
+				t.Errorf("pixel %d, %d = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestDither(t *testing.T) {
+	m := gray(32, 32, 0x78)
+	for _, test := range []struct {
+		dither   bitmap.Dither
+		min, max int
+	}{
+		{bitmap.NoDither, 0, 0},
+		{bitmap.FloydSteinberg, 440, 540},
+		{bitmap.Ordered, 512, 512},
+	} {
+		img := pixel.NewImage[pixel.Monochrome](32, 32)
+		err := bitmap.DecodePNG(encodePNG(t, m), img, &bitmap.Options{Dither: test.dither})
+		if err != nil {
+			t.Fatal(err)
+		}
+		white := 0
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				if img.Get(x, y) {
+					white++
+				}
+			}
+		}
+		if white < test.min || white > test.max {
+			t.Errorf("dither %d: %d white pixels, want %d to %d", test.dither, white, test.min, test.max)
+		}
+	}
+}
+
+// display records the bitmaps drawn on it.
+type display struct {
+	width, height int16
+	pixels        map[image.Point]pixel.Monochrome
+	err           error
+}
+
+func (d *display) DrawBitmap(x, y int16, bitmap pixel.Image[pixel.Monochrome]) error {
+	width, height := bitmap.Size()
+	if x < 0 || x+int16(width) > d.width || y < 0 || y+int16(height) > d.height {
+		return d.err
+	}
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			d.pixels[image.Pt(int(x)+i, int(y)+j)] = bitmap.Get(i, j)
+		}
+	}
+	return nil
+}
+
+func TestDrawJPEG(t *testing.T) {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, gray(64, 48, 0x78), nil); err != nil {
+		t.Fatal(err)
+	}
+	d := &display{width: 40, height: 30, pixels: map[image.Point]pixel.Monochrome{}, err: errors.New("out of range")}
+	err := bitmap.DrawJPEG[pixel.Monochrome](d, 8, 6, bytes.NewReader(b.Bytes()), &bitmap.Options{
+		Scale:  2,
+		Dither: bitmap.FloydSteinberg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.pixels) != 32*24 {
+		t.Errorf("%d pixels drawn, want %d", len(d.pixels), 32*24)
+	}
+	white := 0
+	for p, c := range d.pixels {
+		if p.X < 8 || p.Y < 6 {
+			t.Errorf("pixel %v drawn", p)
+		}
+		if c {
+			white++
+		}
+	}
+	if white < 32*24*2/5 || white > 32*24*3/5 {
+		t.Errorf("%d white pixels", white)
+	}
+
+	// The image doesn't fit the display.
+	err = bitmap.DrawJPEG[pixel.Monochrome](d, 8, 6, bytes.NewReader(b.Bytes()), nil)
+	if err != d.err {
+		t.Errorf("got error %v, want %v", err, d.err)
+	}
+}
@@ -0,0 +1,117 @@
+package bitmap
+
+import "tinygo.org/x/drivers/pixel"
+
+// Dither is the dithering of the colors of an image to a pixel format with
+// fewer colors.
+type Dither uint8
+
+const (
+	// NoDither converts each pixel to the nearest color.
+	NoDither Dither = iota
+
+	// FloydSteinberg diffuses the error of each pixel to its neighbors. The
+	// errors of a band of rows of the image are kept in memory: one row for
+	// PNG images, and 16 rows for JPEG images, where the error diffused to
+	// the block on the left of each block is lost.
+	FloydSteinberg
+
+	// Ordered adds a 4 x 4 Bayer threshold map to the image. It doesn't use
+	// any memory, and doesn't depend on the order in which the image is
+	// decoded.
+	Ordered
+)
+
+// startBand starts a band of h rows of the image at y, of the given width, if
+// y is below the current band. The errors diffused to the row below the
+// current band are kept for the first row of the new band.
+func (c *converter[T]) startBand(y, h, width int) {
+	if c.errs != nil && y < c.errY+c.errRow {
+		return
+	}
+	var below [][3]int16
+	if c.errs != nil && y == c.errY+c.errRow {
+		below = c.errs[c.errRow*c.width : (c.errRow+1)*c.width]
+	}
+	if n := (h + 1) * width; n > len(c.errs) {
+		errs := make([][3]int16, n)
+		copy(errs, below)
+		c.errs = errs
+	} else {
+		copy(c.errs, below)
+		for i := len(below); i < len(c.errs); i++ {
+			c.errs[i] = [3]int16{}
+		}
+	}
+	c.width = width
+	c.errY = y
+	c.errRow = h
+}
+
+// floydSteinberg returns the color of the pixel at x, y, with the errors
+// diffused to it, and diffuses its own error.
+func (c *converter[T]) floydSteinberg(x, y int, r, g, b uint8) T {
+	e := c.errs[(y-c.errY)*c.width+x]
+	want := [3]int{
+		clamp(int(r) + int(e[0])),
+		clamp(int(g) + int(e[1])),
+		clamp(int(b) + int(e[2])),
+	}
+	color := pixel.NewColor[T](uint8(want[0]), uint8(want[1]), uint8(want[2]))
+	got := color.RGBA()
+	diff := [3]int{want[0] - int(got.R), want[1] - int(got.G), want[2] - int(got.B)}
+	c.diffuse(x+1, y, diff, 7)
+	c.diffuse(x-1, y+1, diff, 3)
+	c.diffuse(x, y+1, diff, 5)
+	c.diffuse(x+1, y+1, diff, 1)
+	return color
+}
+
+// diffuse adds weight/16 of diff to the error of the pixel at x, y.
+func (c *converter[T]) diffuse(x, y int, diff [3]int, weight int) {
+	if x < 0 || x >= c.width || y-c.errY > c.errRow {
+		return
+	}
+	e := &c.errs[(y-c.errY)*c.width+x]
+	for i := range diff {
+		e[i] += int16(diff[i] * weight / 16)
+	}
+}
+
+func clamp(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+// bayer is the 4 x 4 Bayer threshold map.
+var bayer = [4][4]int8{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// ordered returns the color of the pixel at x, y, offset by the threshold map
+// scaled to the step between the levels of the pixel format.
+func ordered[T pixel.Color](x, y int, r, g, b uint8) T {
+	var zeroColor T
+	step := 0
+	switch zeroColor.BitsPerPixel() {
+	case 1:
+		step = 255
+	case 12:
+		step = 17
+	case 15, 16:
+		step = 8
+	}
+	offset := (2*int(bayer[y%4][x%4]) - 15) * step / 32
+	return pixel.NewColor[T](
+		uint8(clamp(int(r)+offset)),
+		uint8(clamp(int(g)+offset)),
+		uint8(clamp(int(b)+offset)))
+}
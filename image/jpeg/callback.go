@@ -1,5 +1,11 @@
 package jpeg
 
+import (
+	"image"
+	"image/color"
+	"io"
+)
+
 var (
 	callback    Callback = func(data []uint16, x, y, w, h, width, height int16) {}
 	callbackBuf []uint16
@@ -13,7 +19,119 @@ type Callback func(data []uint16, x, y, w, h, width, height int16)
 
 // SetCallback registers the buffer and fn required for Callback. Callback can
 // be called multiple times by calling Decode().
+//
+// The callback is shared by the whole package, use a Decoder instead to
+// decode images concurrently.
 func SetCallback(buf []uint16, fn Callback) {
 	callbackBuf = buf
 	callback = fn
 }
+
+// Decoder decodes JPEG images with its own buffer and callback, so that many
+// images can be decoded at the same time.
+//
+// The decoded image can be cropped to a rectangle, and downscaled by an
+// integer factor, which keeps one pixel out of scale in each direction. The
+// coordinates and the size of the image passed to the callback are those of
+// the cropped and downscaled image, so a large image can be previewed on a
+// small display:
+//
+//	dec := jpeg.NewDecoder(buf[:], func(data []uint16, x, y, w, h, width, height int16) {
+//		display.DrawRGBBitmap(x, y, data[:w*h], w, h)
+//	})
+//	dec.SetScale(4)
+//	err := dec.Decode(r)
+type Decoder struct {
+	buf    []uint16
+	fn     Callback
+	bounds image.Rectangle
+	scale  int
+}
+
+// NewDecoder returns a Decoder that passes the decoded image to fn, in
+// blocks of up to 16 x 16 pixels. The buffer must hold 256 pixels, if it is
+// nil a buffer is allocated by each call to Decode.
+func NewDecoder(buf []uint16, fn Callback) *Decoder {
+	return &Decoder{
+		buf:   buf,
+		fn:    fn,
+		scale: 1,
+	}
+}
+
+// SetBounds crops the decoded image to r, in the coordinates of the original
+// image. The blocks outside of r are skipped. An empty rectangle decodes the
+// whole image.
+func (dec *Decoder) SetBounds(r image.Rectangle) {
+	dec.bounds = r
+}
+
+// SetScale downscales the decoded image by scale, keeping the first pixel of
+// each scale x scale block. The default scale is 1.
+func (dec *Decoder) SetScale(scale int) {
+	if scale < 1 {
+		scale = 1
+	}
+	dec.scale = scale
+}
+
+// Decode reads a JPEG image from r and passes it to the callback of the
+// Decoder.
+func (dec *Decoder) Decode(r io.Reader) error {
+	d := &decoder{dec: dec}
+	_, err := d.decode(r, false)
+	return err
+}
+
+// setOutput computes the rectangle of the image that is passed to the
+// callback, and the size of the downscaled image.
+func (d *decoder) setOutput() {
+	d.rect = image.Rect(0, 0, d.width, d.height)
+	if !d.dec.bounds.Empty() {
+		d.rect = d.rect.Intersect(d.dec.bounds)
+	}
+	d.outWidth = (d.rect.Dx() + d.dec.scale - 1) / d.dec.scale
+	d.outHeight = (d.rect.Dy() + d.dec.scale - 1) / d.dec.scale
+	d.buf = d.dec.buf
+	if d.buf == nil {
+		d.buf = make([]uint16, 256)
+	}
+}
+
+// firstSample returns the first column or row from start that is kept when
+// downscaling from min.
+func firstSample(start, min, scale int) int {
+	if start <= min {
+		return min
+	}
+	return min + (start-min+scale-1)/scale*scale
+}
+
+// emitBlock converts the pixels of the output image of the 16 x 16 YCbCr
+// block of sosBuf at x0, y0 and passes them to the callback.
+func (d *decoder) emitBlock(x0, y0 int) {
+	scale := d.dec.scale
+	sx := firstSample(x0, d.rect.Min.X, scale)
+	sy := firstSample(y0, d.rect.Min.Y, scale)
+	ex := min(x0+16, d.rect.Max.X)
+	ey := min(y0+16, d.rect.Max.Y)
+	if sx >= ex || sy >= ey {
+		return
+	}
+
+	buf := d.buf
+	n := 0
+	for y := sy; y < ey; y += scale {
+		for x := sx; x < ex; x += scale {
+			i := ((y-y0)*16 + x - x0) * 3
+			r, g, b := color.YCbCrToRGB(d.sosBuf[i+0], d.sosBuf[i+1], d.sosBuf[i+2])
+			buf[n] = uint16(((uint16(r) << 8) & 0xF800) +
+				(((uint16(g) << 8) & 0xFC00) >> 5) +
+				(((uint16(b) << 8) & 0xF800) >> 11))
+			n++
+		}
+	}
+	w := (ex - sx + scale - 1) / scale
+	d.dec.fn(buf[:n], int16((sx-d.rect.Min.X)/scale), int16((sy-d.rect.Min.Y)/scale),
+		int16(w), int16(n/w), int16(d.outWidth), int16(d.outHeight))
+}
@@ -0,0 +1,53 @@
+package jpeg
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestDecoderBoundsScale(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 40, 24))
+	for i := range m.Pix {
+		m.Pix[i] = 0x80
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, m, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every pixel of the cropped and downscaled image is passed once.
+	var seen [12][16]int
+	buf := make([]uint16, 256)
+	dec := NewDecoder(buf, func(data []uint16, x, y, w, h, width, height int16) {
+		if width != 16 || height != 12 {
+			t.Errorf("image size %d x %d, want 16 x 12", width, height)
+		}
+		for j := int16(0); j < h; j++ {
+			for i := int16(0); i < w; i++ {
+				seen[y+j][x+i]++
+				c := data[j*w+i]
+				r, g, b := uint8(c>>11<<3), uint8(c>>5<<2), uint8(c<<3)
+				if !near(r, 0x80) || !near(g, 0x80) || !near(b, 0x80) {
+					t.Errorf("pixel %d, %d = %04x", x+i, y+j, c)
+				}
+			}
+		}
+	})
+	dec.SetBounds(image.Rect(8, 0, 100, 100))
+	dec.SetScale(2)
+	if err := dec.Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+	for y, row := range seen {
+		for x, n := range row {
+			if n != 1 {
+				t.Errorf("pixel %d, %d passed %d times", x, y, n)
+			}
+		}
+	}
+}
+
+func near(c, want uint8) bool {
+	return c+8 >= want && c <= want+8
+}
@@ -146,6 +146,18 @@ type decoder struct {
 	huff       [maxTc + 1][maxTh + 1]huffman
 	quant      [maxTq + 1]block // Quantization tables, in zig-zag order.
 	tmp        [2 * blockSize]byte
+
+	// sosBuf holds the four 8 x 8 pix 24bit YCbCr blocks of a MCU, and
+	// blockBuf the 8 x 8 pix data processed by reconstructBlock.
+	sosBuf   [3 * 8 * 8 * 4]byte
+	blockBuf [64]byte
+
+	// dec holds the callback and buf its buffer, and rect is the rectangle
+	// of the image passed to it, downscaled to outWidth x outHeight.
+	dec                 *Decoder
+	buf                 []uint16
+	rect                image.Rectangle
+	outWidth, outHeight int
 }
 
 // fill fills up the d.bytes.buf buffer from the underlying io.Reader. It
@@ -774,9 +786,7 @@ func (d *decoder) convertToRGB() (image.Image, error) {
 // Decode reads a JPEG image from r. Different from the standard package, the
 // decoded result will be received by the callback set by SetCallback().
 func Decode(r io.Reader) (image.Image, error) {
-	var d decoder
-	_, err := d.decode(r, false)
-	return nil, err
+	return nil, NewDecoder(callbackBuf, callback).Decode(r)
 }
 
 // DecodeConfig returns the color model and dimensions of a JPEG image without
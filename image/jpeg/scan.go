@@ -6,7 +6,6 @@ package jpeg
 
 import (
 	"image"
-	"image/color"
 )
 
 // makeImg allocates and initializes the destination image.
@@ -48,10 +47,6 @@ func (d *decoder) makeImg(mxx, myy int) {
 	}
 }
 
-// processSOSBuf is a Buffer for creating RGBBitmap in processSOS. It needs to
-// hold four 8 x 8 pix 24bit color images.
-var processSOSBuf [3 * 8 * 8 * 4]byte
-
 // Specified in section B.2.3.
 func (d *decoder) processSOS(n int) error {
 	if d.nComp == 0 {
@@ -156,6 +151,7 @@ func (d *decoder) processSOS(n int) error {
 		// the amount of code changes down, the image is created as a 1 x 1
 		// image at this point.
 		d.makeImg(1, 1)
+		d.setOutput()
 	}
 	if d.progressive {
 		for i := 0; i < nComp; i++ {
@@ -318,7 +314,7 @@ func (d *decoder) processSOS(n int) error {
 							by16 := by8 % 16
 							for cy := 0; cy < 8; cy++ {
 								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy+by16)*16+(cx+bx16))*3+0] = dst[cy*8+cx]
+									d.sosBuf[((cy+by16)*16+(cx+bx16))*3+0] = dst[cy*8+cx]
 								}
 							}
 						case 1: // Cb
@@ -329,10 +325,10 @@ func (d *decoder) processSOS(n int) error {
 
 							for cy := 0; cy < 8; cy++ {
 								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
 								}
 							}
 						case 2: // Cr
@@ -343,25 +339,14 @@ func (d *decoder) processSOS(n int) error {
 
 							for cy := 0; cy < 8; cy++ {
 								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
+									d.sosBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
 								}
 							}
 
-							for cy := 0; cy < 16; cy++ {
-								for cx := 0; cx < 16; cx++ {
-									yy := processSOSBuf[(cy*16+cx)*3+0]
-									cb := processSOSBuf[(cy*16+cx)*3+1]
-									cr := processSOSBuf[(cy*16+cx)*3+2]
-									r, g, b := color.YCbCrToRGB(yy, cb, cr)
-									callbackBuf[cy*16+cx] = uint16(((uint16(r) << 8) & 0xF800) +
-										(((uint16(g) << 8) & 0xFC00) >> 5) +
-										(((uint16(b) << 8) & 0xF800) >> 11))
-								}
-							}
-							callback(callbackBuf[:8*8*4], int16(bx8-bx16), int16(by8-by16), 16, 16, int16(d.width), int16(d.height))
+							d.emitBlock(bx8-bx16, by8-by16)
 						}
 					}
 				} // for j
@@ -541,11 +526,6 @@ func (d *decoder) reconstructProgressiveImage() error {
 	return nil
 }
 
-// reconstructBlockBuf is a Buffer for the 8x8 pix data to be processed by
-// reconstructBlock. It is defined and used as a package variable to reduce
-// memory usage.
-var reconstructBlockBuf [64]byte
-
 // reconstructBlock dequantizes, performs the inverse DCT and stores the block
 // to the image.
 // In the original Go source, it was expanded to a position that matched the
@@ -558,7 +538,7 @@ func (d *decoder) reconstructBlock(b *block, bx, by, compIndex int) ([]byte, err
 	}
 	idct(b)
 	// Level shift by +128, clip to [0, 255], and write to dst.
-	var buf = reconstructBlockBuf[:]
+	var buf = d.blockBuf[:]
 	for y := 0; y < 8; y++ {
 		y8 := y * 8
 		for x := 0; x < 8; x++ {
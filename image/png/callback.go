@@ -1,5 +1,11 @@
 package png
 
+import (
+	"hash/crc32"
+	"image"
+	"io"
+)
+
 var (
 	callback    Callback = func(data []uint16, x, y, w, h, width, height int16) {}
 	callbackBuf []uint16
@@ -11,7 +17,126 @@ type Callback func(data []uint16, x, y, w, h, width, height int16)
 
 // SetCallback registers the buffer and fn required for Callback. Callback can
 // be called multiple times by calling Decode().
+//
+// The callback is shared by the whole package, use a Decoder instead to
+// decode images concurrently.
 func SetCallback(buf []uint16, fn Callback) {
 	callbackBuf = buf
 	callback = fn
 }
+
+// Decoder decodes PNG images with its own buffer and callback, so that many
+// images can be decoded at the same time.
+//
+// The decoded image can be cropped to a rectangle, and downscaled by an
+// integer factor, which keeps one pixel out of scale in each direction. The
+// coordinates and the size of the image passed to the callback are those of
+// the cropped and downscaled image, so a large image can be previewed on a
+// small display:
+//
+//	dec := png.NewDecoder(buf[:], func(data []uint16, x, y, w, h, width, height int16) {
+//		display.DrawRGBBitmap(x, y, data[:w*h], w, h)
+//	})
+//	dec.SetScale(4)
+//	err := dec.Decode(r)
+type Decoder struct {
+	buf    []uint16
+	fn     Callback
+	bounds image.Rectangle
+	scale  int
+}
+
+// NewDecoder returns a Decoder that passes the decoded image to fn, one row
+// at a time. The buffer must hold a row of the decoded image, if it is nil
+// a buffer is allocated by each call to Decode.
+func NewDecoder(buf []uint16, fn Callback) *Decoder {
+	return &Decoder{
+		buf:   buf,
+		fn:    fn,
+		scale: 1,
+	}
+}
+
+// SetBounds crops the decoded image to r, in the coordinates of the original
+// image. The rows and columns outside of r are skipped. An empty rectangle
+// decodes the whole image.
+func (dec *Decoder) SetBounds(r image.Rectangle) {
+	dec.bounds = r
+}
+
+// SetScale downscales the decoded image by scale, keeping the first pixel of
+// each scale x scale block. The default scale is 1.
+func (dec *Decoder) SetScale(scale int) {
+	if scale < 1 {
+		scale = 1
+	}
+	dec.scale = scale
+}
+
+// Decode reads a PNG image from r and passes it to the callback of the
+// Decoder.
+func (dec *Decoder) Decode(r io.Reader) error {
+	d := &decoder{
+		r:   r,
+		crc: crc32.NewIEEE(),
+		dec: dec,
+	}
+	if err := d.checkHeader(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	for d.stage != dsSeenIEND {
+		if err := d.parseChunk(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// setOutput computes the rectangle of the image that is passed to the
+// callback, and the size of the downscaled image.
+func (d *decoder) setOutput() {
+	d.rect = image.Rect(0, 0, d.width, d.height)
+	if !d.dec.bounds.Empty() {
+		d.rect = d.rect.Intersect(d.dec.bounds)
+	}
+	d.outWidth = (d.rect.Dx() + d.dec.scale - 1) / d.dec.scale
+	d.outHeight = (d.rect.Dy() + d.dec.scale - 1) / d.dec.scale
+	d.buf = d.dec.buf
+	if d.buf == nil {
+		d.buf = make([]uint16, d.outWidth)
+	}
+}
+
+// outputRow returns the row of the output image of row y of the image, and
+// whether the row is part of the output image.
+func (d *decoder) outputRow(y int) (int, bool) {
+	if y < d.rect.Min.Y || y >= d.rect.Max.Y || (y-d.rect.Min.Y)%d.dec.scale != 0 {
+		return 0, false
+	}
+	return (y - d.rect.Min.Y) / d.dec.scale, true
+}
+
+// emitRow converts the columns of the output image of a row of 8-bit RGB
+// pixels, of bytesPerPixel bytes each, and passes them to the callback.
+func (d *decoder) emitRow(cdat []byte, bytesPerPixel, y int) {
+	oy, ok := d.outputRow(y)
+	if !ok {
+		return
+	}
+	buf := d.buf
+	n := 0
+	for x := d.rect.Min.X; x < d.rect.Max.X; x += d.dec.scale {
+		r := uint16(cdat[x*bytesPerPixel+0]) << 8
+		g := uint16(cdat[x*bytesPerPixel+1]) << 8
+		b := uint16(cdat[x*bytesPerPixel+2]) << 8
+		buf[n] = uint16((r & 0xF800) + ((g & 0xFC00) >> 5) + ((b & 0xF800) >> 11))
+		n++
+	}
+	d.dec.fn(buf[:n], 0, int16(oy), int16(n), 1, int16(d.outWidth), int16(d.outHeight))
+}
@@ -0,0 +1,57 @@
+package png
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func rgb565(r, g, b uint8) uint16 {
+	return uint16(r)>>3<<11 | uint16(g)>>2<<5 | uint16(b)>>3
+}
+
+func TestDecoderBoundsScale(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 10, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 10; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x * 20), uint8(y * 40), 0xff, 0xff})
+		}
+	}
+	for _, alpha := range []bool{false, true} {
+		if alpha {
+			m.Pix[3] = 0x80
+		}
+		var b bytes.Buffer
+		if err := Encode(&b, m); err != nil {
+			t.Fatal(err)
+		}
+
+		var rows [][]uint16
+		buf := make([]uint16, 4)
+		dec := NewDecoder(buf, func(data []uint16, x, y, w, h, width, height int16) {
+			if x != 0 || int(y) != len(rows) || h != 1 || width != 4 || height != 3 {
+				t.Errorf("alpha %v: unexpected callback %d, %d, %d x %d of %d x %d", alpha, x, y, w, h, width, height)
+			}
+			rows = append(rows, append([]uint16(nil), data[:w]...))
+		})
+		dec.SetBounds(image.Rect(1, 1, 9, 20))
+		dec.SetScale(2)
+		if err := dec.Decode(&b); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rows) != 3 {
+			t.Fatalf("alpha %v: got %d rows, want 3", alpha, len(rows))
+		}
+		for i, row := range rows {
+			y := 1 + 2*i
+			for j, c := range row {
+				x := 1 + 2*j
+				if want := rgb565(uint8(x*20), uint8(y*40), 0xff); c != want {
+					t.Errorf("alpha %v: pixel %d, %d = %04x, want %04x", alpha, x, y, c, want)
+				}
+			}
+		}
+	}
+}
@@ -119,6 +119,13 @@ type decoder struct {
 	// transparency, as opposed to palette transparency.
 	useTransparent bool
 	transparent    [6]byte
+
+	// dec holds the callback and buf its buffer, and rect is the rectangle
+	// of the image passed to it, downscaled to outWidth x outHeight.
+	dec                 *Decoder
+	buf                 []uint16
+	rect                image.Rectangle
+	outWidth, outHeight int
 }
 
 // A FormatError reports that the input is not a valid PNG.
@@ -370,6 +377,7 @@ func (d *decoder) decode() (image.Image, error) {
 		return nil, err
 	}
 	defer r.Close()
+	d.setOutput()
 	var img image.Image
 	if d.interlace == itNone {
 		img, err = d.readImagePass(r, 0, false)
@@ -672,13 +680,7 @@ func (d *decoder) readImagePass(r io.Reader, pass int, allocateOnly bool) (image
 				}
 				pixOffset += nrgba.Stride
 			} else {
-				for x := 0; x < width; x++ {
-					r := uint16(cdat[x*3+0]) << 8
-					g := uint16(cdat[x*3+1]) << 8
-					b := uint16(cdat[x*3+2]) << 8
-					callbackBuf[x] = uint16((r & 0xF800) + ((g & 0xFC00) >> 5) + ((b & 0xF800) >> 11))
-				}
-				callback(callbackBuf[:width*1], 0, int16(y), int16(width), 1, int16(width), int16(height))
+				d.emitRow(cdat, 3, y)
 				pixOffset += rgba.Stride
 			}
 		case cbP1:
@@ -728,14 +730,7 @@ func (d *decoder) readImagePass(r io.Reader, pass int, allocateOnly bool) (image
 			copy(paletted.Pix[pixOffset:], cdat)
 			pixOffset += paletted.Stride
 		case cbTCA8:
-			copy(nrgba.Pix[:], cdat)
-			for x := 0; x < width; x++ {
-				r := uint16(cdat[x*3+0]) << 8
-				g := uint16(cdat[x*3+1]) << 8
-				b := uint16(cdat[x*3+2]) << 8
-				callbackBuf[x] = uint16((r & 0xF800) + ((g & 0xFC00) >> 5) + ((b & 0xF800) >> 11))
-			}
-			callback(callbackBuf[:width*1], 0, int16(y), int16(width), 1, int16(width), int16(height))
+			d.emitRow(cdat, 4, y)
 			pixOffset += nrgba.Stride
 		case cbG16:
 			if d.useTransparent {
@@ -968,25 +963,7 @@ func (d *decoder) checkHeader() error {
 // Decode reads a PNG image from r. Different from the standard package, the
 // decoded result will be received by the callback set by SetCallback().
 func Decode(r io.Reader) (image.Image, error) {
-	d := &decoder{
-		r:   r,
-		crc: crc32.NewIEEE(),
-	}
-	if err := d.checkHeader(); err != nil {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-		return nil, err
-	}
-	for d.stage != dsSeenIEND {
-		if err := d.parseChunk(); err != nil {
-			if err == io.EOF {
-				err = io.ErrUnexpectedEOF
-			}
-			return nil, err
-		}
-	}
-	return nil, nil
+	return nil, NewDecoder(callbackBuf, callback).Decode(r)
 }
 
 // DecodeConfig returns the color model and dimensions of a PNG image without
@@ -230,3 +230,56 @@ func TestMeasurements(t *testing.T) {
 	c.Assert(p, qt.Equals, pVal)
 
 }
+
+func TestBusErrors(t *testing.T) {
+	t.Run("nack", func(t *testing.T) {
+		c := qt.New(t)
+		bus := tester.NewI2CBus(c)
+		bus.NACK = true
+
+		dev := New(bus)
+		_, _, _, _, err := dev.Measurements()
+		c.Assert(err, qt.Equals, tester.ErrNACK)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		c := qt.New(t)
+		bus := tester.NewI2CBus(c)
+		fake := tester.NewI2CDevice16(c, Address)
+		fake.Registers = map[uint8]uint16{
+			RegBusVoltage:   (4200 << 3) / 4,
+			RegShuntVoltage: 0x1234,
+			RegCurrent:      0,
+			RegPower:        0,
+		}
+		bus.AddDevice(fake)
+		bus.Faults = []tester.I2CFault{tester.FailRegister(Address, RegCurrent, tester.ErrNACK)}
+
+		dev := New(bus)
+		busVoltage, shuntVoltage, _, _, err := dev.Measurements()
+		c.Assert(err, qt.Equals, tester.ErrNACK)
+		c.Assert(busVoltage, qt.Equals, int16(4200))
+		c.Assert(shuntVoltage, qt.Equals, int16(0x1234))
+	})
+}
+
+func TestSimulatedDevice(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewINA219(c, Address)
+	fake.BusVoltage = 12000
+	fake.ShuntVoltage = 5000
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	dev.SetConfig(Config32V1A)
+	c.Assert(dev.Configure(), qt.IsNil)
+
+	busVoltage, shuntVoltage, current, power, err := dev.Measurements()
+	c.Assert(err, qt.IsNil)
+	c.Assert(busVoltage, qt.Equals, int16(12000))
+	c.Assert(shuntVoltage, qt.Equals, int16(500))
+	// 50 mV across the 0.1 Ω shunt of the Adafruit breakout
+	c.Assert(current, qt.Equals, float32(50))
+	c.Assert(power, qt.Equals, float32(600))
+}
@@ -0,0 +1,41 @@
+package lora
+
+import "time"
+
+// bandwidthHz gives the bandwidth in Hz of each Bandwidth_* setting
+var bandwidthHz = [...]int64{7812, 10417, 15625, 20833, 31250, 41667, 62500, 125000, 250000, 500000}
+
+// TimeOnAir returns the transmission duration of a LoRa packet with given
+// payload length and modulation parameters. Low data rate optimization is
+// assumed to be enabled when symbol duration reaches 16 ms.
+func TimeOnAir(payloadLen int, sf, bw, cr uint8, preambleLen uint16, headerType, crc uint8) time.Duration {
+	if int(bw) >= len(bandwidthHz) {
+		return 0
+	}
+	tSym := time.Duration((int64(1) << sf) * int64(time.Second) / bandwidthHz[bw])
+
+	de := 0
+	if tSym >= 16*time.Millisecond {
+		de = 1
+	}
+	ih := 0
+	if headerType == HeaderImplicit {
+		ih = 1
+	}
+	crcBits := 0
+	if crc == CRCOn {
+		crcBits = 16
+	}
+
+	// Semtech AN1200.13 payload symbols count
+	nPayload := 8
+	num := 8*payloadLen - 4*int(sf) + 28 + crcBits - 20*ih
+	den := 4 * (int(sf) - 2*de)
+	if num > 0 && den > 0 {
+		nPayload += (num + den - 1) / den * (int(cr) + 4)
+	}
+
+	// Preamble lasts preambleLen + 4.25 symbols
+	tPreamble := time.Duration(4*int(preambleLen)+17) * tSym / 4
+	return tPreamble + time.Duration(nPayload)*tSym
+}
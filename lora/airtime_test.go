@@ -0,0 +1,31 @@
+package lora
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTimeOnAir(t *testing.T) {
+	c := qt.New(t)
+
+	// Expected values from Semtech LoRa calculator, 8 symbols preamble,
+	// explicit header, CRC on
+	tests := []struct {
+		payloadLen int
+		sf, bw, cr uint8
+		want       time.Duration
+	}{
+		{13, SpreadingFactor7, Bandwidth_125_0, CodingRate4_5, 46336 * time.Microsecond},
+		{23, SpreadingFactor9, Bandwidth_125_0, CodingRate4_5, 205824 * time.Microsecond},
+		{23, SpreadingFactor10, Bandwidth_125_0, CodingRate4_5, 370688 * time.Microsecond},
+		{51, SpreadingFactor12, Bandwidth_125_0, CodingRate4_5, 2465792 * time.Microsecond},
+		{13, SpreadingFactor8, Bandwidth_500_0, CodingRate4_5, 20608 * time.Microsecond},
+		{0, SpreadingFactor7, Bandwidth_250_0, CodingRate4_8, 14464 * time.Microsecond},
+	}
+	for _, tt := range tests {
+		got := TimeOnAir(tt.payloadLen, tt.sf, tt.bw, tt.cr, 8, HeaderExplicit, CRCOn)
+		c.Assert(got, qt.Equals, tt.want, qt.Commentf("SF%d BW%d %d bytes", tt.sf, tt.bw, tt.payloadLen))
+	}
+}
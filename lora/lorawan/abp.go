@@ -0,0 +1,43 @@
+package lorawan
+
+// ActivateABP configures a session for Activation By Personalization, using
+// the DevAddr and session keys provisioned on the network server. DevAddr is
+// given MSB first, as displayed by network servers.
+//
+// For LoRaWAN 1.1, the session Version and network session keys must be set
+// before activation. ResetInd is then sent in every uplink until network
+// answers with ResetConf.
+func ActivateABP(session *Session, devAddr []uint8, nwkSKey []uint8, appSKey []uint8) error {
+	if regionSettings == nil {
+		return ErrUndefinedRegionSettings
+	}
+
+	if len(devAddr) != 4 {
+		return ErrInvalidDevAddrLength
+	}
+	if err := session.SetNwkSKey(nwkSKey); err != nil {
+		return err
+	}
+	if err := session.SetAppSKey(appSKey); err != nil {
+		return err
+	}
+	// DevAddr is stored as sent over the air (LSB first)
+	copy(session.DevAddr[:], reverseBytes(devAddr))
+
+	// Regional defaults until network changes them with MAC commands
+	session.DLSettings = regionSettings.Rx2DefaultDataRate()
+	session.RXDelay = 1
+	session.CFList = [16]uint8{}
+
+	// Reset counters
+	session.FCntDown = 0
+	session.FCntUp = 0
+	session.AFCntDown = 0
+
+	session.versionInd = 0
+	if session.Version == Version1_1 {
+		session.versionInd = CIDReset
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package lorawan
 
 import (
 	"errors"
+	"time"
 
 	"tinygo.org/x/drivers/lora"
 	"tinygo.org/x/drivers/lora/lorawan/region"
@@ -20,17 +21,44 @@ var (
 	ErrInvalidNwkSKeyLength    = errors.New("invalid NwkSKey length")
 	ErrInvalidAppSKeyLength    = errors.New("invalid AppSKey length")
 	ErrUndefinedRegionSettings = errors.New("undefined Regionnal Settings ")
+	ErrNoDownlinkReceived      = errors.New("no downlink received")
+	ErrUnexpectedMType         = errors.New("unexpected message type")
+	ErrDevAddrMismatch         = errors.New("DevAddr mismatch")
+	ErrInvalidFCntDown         = errors.New("invalid downlink frame counter")
+	ErrNoAckReceived           = errors.New("no ACK received for confirmed uplink")
+	ErrUnsupportedVersion      = errors.New("unsupported LoRaWAN version")
+	ErrInvalidRejoinType       = errors.New("invalid rejoin request type")
+	ErrInvalidJoinNonce        = errors.New("invalid JoinNonce")
+	ErrDutyCycleExceeded       = errors.New("duty cycle exceeded")
+	ErrDwellTimeExceeded       = errors.New("uplink exceeds maximum dwell time")
 )
 
 const (
 	LORA_TX_TIMEOUT = 2000
 	LORA_RX_TIMEOUT = 10000
+
+	// LORA_RX_WINDOW_TIMEOUT is the RX2 window duration, long enough to
+	// receive the largest frame at the slowest data rate
+	LORA_RX_WINDOW_TIMEOUT = 3000
+
+	// ACK_TIMEOUT_MIN and ACK_TIMEOUT_MAX bound the random delay between
+	// confirmed uplink retransmissions, counted from RX2 window end
+	ACK_TIMEOUT_MIN = 1000
+	ACK_TIMEOUT_MAX = 3000
 )
 
 var (
-	ActiveRadio    lora.Radio
-	Retries        = 15
-	regionSettings region.Settings
+	ActiveRadio lora.Radio
+	Retries     = 15
+	// ConfirmedRetries is the number of retransmissions of a confirmed
+	// uplink not acknowledged by network
+	ConfirmedRetries = 7
+	regionSettings   region.Settings
+
+	// sleep waits for receive windows and retransmissions, and timeNow
+	// reads the clock, both replaced by tests
+	sleep   = time.Sleep
+	timeNow = time.Now
 )
 
 // UseRegionSettings sets current Lorawan Regional parameters
@@ -88,13 +116,18 @@ func Join(otaa *Otaa, session *Session) error {
 		joinRequestChannel := regionSettings.JoinRequestChannel()
 		joinAcceptChannel := regionSettings.JoinAcceptChannel()
 
+		// Join requests are retransmitted once the duty cycle allows it
+		sleep(regionSettings.DutyCycleWait(joinRequestChannel.Frequency(), timeNow()))
+
 		// Prepare radio for Join Tx
 		applyChannelConfig(joinRequestChannel)
 		ActiveRadio.SetIqMode(lora.IQStandard)
+		txStart := timeNow()
 		ActiveRadio.Tx(payload, LORA_TX_TIMEOUT)
 		if err != nil {
 			return err
 		}
+		regionSettings.RegisterTx(joinRequestChannel.Frequency(), txStart, timeOnAir(joinRequestChannel, len(payload)))
 
 		// Wait for JoinAccept
 		if joinAcceptChannel.Frequency() != 0 {
@@ -118,27 +151,145 @@ func Join(otaa *Otaa, session *Session) error {
 	return nil
 }
 
-// SendUplink sends Lorawan Uplink message
+// SendUplink sends Lorawan Uplink message, then opens Class A receive windows.
+// A downlink received in these windows is returned by ListenDownlink.
+// A *DutyCycleError is returned, and nothing is sent, if the uplink would
+// exceed the duty cycle.
 func SendUplink(data []uint8, session *Session) error {
 
 	if regionSettings == nil {
 		return ErrUndefinedRegionSettings
 	}
 
-	payload, err := session.GenMessage(0, []byte(data))
+	if err := checkPayloadSize(data); err != nil {
+		return err
+	}
+
+	uplinkChannel := regionSettings.UplinkChannel()
+	if err := checkAirTime(uplinkChannel, session.uplinkLen(data), session); err != nil {
+		return err
+	}
+
+	frame, fCnt, err := session.genFrame(MTypeUnconfirmedDataUp, 0, []byte(data))
 	if err != nil {
 		return err
 	}
 
-	applyChannelConfig(regionSettings.UplinkChannel())
-	ActiveRadio.SetIqMode(lora.IQStandard)
-	ActiveRadio.Tx(payload, LORA_TX_TIMEOUT)
+	err = transmit(uplinkChannel, frame, fCnt, session)
+	if err == nil && lastDownlink == nil {
+		session.adrBackoff()
+	}
+	return err
+}
+
+// SendConfirmedUplink sends a Lorawan Confirmed Uplink message, and waits for
+// network acknowledgement in the receive windows. The message is sent again
+// up to ConfirmedRetries times, after ACK_TIMEOUT, until it is acknowledged.
+// The data rate is lowered every two transmissions, as long as the payload
+// fits. Retransmissions are delayed as needed by the duty cycle and by the
+// retransmission back-off, which bounds the time on air of unacknowledged
+// confirmed uplinks, but a *DutyCycleError is returned if the first
+// transmission is not allowed.
+func SendConfirmedUplink(data []uint8, session *Session) error {
+
+	if regionSettings == nil {
+		return ErrUndefinedRegionSettings
+	}
+
+	if err := checkPayloadSize(data); err != nil {
+		return err
+	}
+
+	uplinkChannel := regionSettings.UplinkChannel()
+	if err := checkConfirmedAirTime(uplinkChannel, session.uplinkLen(data), session); err != nil {
+		return err
+	}
+
+	frame, fCnt, err := session.genFrame(MTypeConfirmedDataUp, 0, []byte(data))
 	if err != nil {
 		return err
 	}
+	session.confFCntUp = uint16(fCnt)
+
+	for i := 0; i <= ConfirmedRetries; i++ {
+		if i > 0 {
+			sleep(ackTimeout())
+			if i%2 == 0 {
+				regionSettings.LowerDataRate(len(data))
+			}
+			uplinkChannel = regionSettings.UplinkChannel()
+			err = checkConfirmedAirTime(uplinkChannel, len(frame)+4, session)
+			if dc, ok := err.(*DutyCycleError); ok {
+				sleep(dc.RetryAfter)
+			} else if err != nil {
+				return err
+			}
+		}
+		txStart := timeNow()
+		err = transmit(uplinkChannel, frame, fCnt, session)
+		if err != nil {
+			return err
+		}
+		session.registerBackoff(timeOnAir(uplinkChannel, len(frame)+4), txStart)
+		if lastDownlink != nil && lastDownlink.ACK {
+			session.backoffStart = time.Time{}
+			return nil
+		}
+	}
+
+	if lastDownlink == nil {
+		session.adrBackoff()
+	}
+	return ErrNoAckReceived
+}
+
+// checkPayloadSize returns ErrFrmPayloadTooLarge if data does not fit in an
+// uplink at current data rate
+func checkPayloadSize(data []uint8) error {
+	dr, ok := regionSettings.DataRate(regionSettings.UplinkDataRate())
+	if ok && len(data) > int(dr.MaxPayload) {
+		return ErrFrmPayloadTooLarge
+	}
 	return nil
 }
 
-func ListenDownlink() error {
+// transmit sends an uplink frame on given uplink channel, then opens receive
+// windows and handles the received downlink, if any
+func transmit(uplinkChannel region.Channel, frame []uint8, fCnt uint32, session *Session) error {
+	var err error
+
+	lastDownlink = nil
+	payload := session.appendMIC(frame, 0, fCnt, regionSettings.UplinkDataRate(), regionSettings.UplinkChannelIndex())
+	applyChannelConfig(uplinkChannel)
+	ActiveRadio.SetIqMode(lora.IQStandard)
+	txStart := timeNow()
+	err = ActiveRadio.Tx(payload, LORA_TX_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	registerAirTime(uplinkChannel, len(payload), txStart, session)
+
+	lastDownlink, err = receiveWindows(uplinkChannel, session, timeNow())
+	if err != nil || lastDownlink == nil {
+		return err
+	}
+
+	// Answers of sticky MAC commands are sent until a downlink is received
+	session.stickyMACAnswers = session.stickyMACAnswers[:0]
+	session.adrAckCnt = 0
+	session.processMACCommands(lastDownlink.FOpts)
+	if lastDownlink.FPort == 0 {
+		session.processMACCommands(lastDownlink.FRMPayload)
+	}
+
+	// Confirmed downlinks are acknowledged in next uplink
+	session.ackDownlink = lastDownlink.Confirmed
 	return nil
 }
+
+// ackTimeout returns a random delay between ACK_TIMEOUT_MIN and ACK_TIMEOUT_MAX
+func ackTimeout() time.Duration {
+	rnd, _ := GetRand16()
+	ms := ACK_TIMEOUT_MIN + (uint32(rnd[0])|uint32(rnd[1])<<8)%(ACK_TIMEOUT_MAX-ACK_TIMEOUT_MIN)
+	return time.Duration(ms) * time.Millisecond
+}
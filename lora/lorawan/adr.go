@@ -0,0 +1,37 @@
+package lorawan
+
+const (
+	// ADR_ACK_LIMIT is the number of uplinks without downlink after which
+	// the device requests an answer from network
+	ADR_ACK_LIMIT = 64
+	// ADR_ACK_DELAY is the number of further uplinks without downlink
+	// between two ADR back-off steps
+	ADR_ACK_DELAY = 32
+)
+
+var adrEnabled bool
+
+// SetADR enables or disables Adaptive Data Rate. When enabled, the network
+// controls uplink data rate and TX power, and the device falls back to
+// lower data rates when it does not hear from network anymore.
+func SetADR(enabled bool) {
+	adrEnabled = enabled
+}
+
+// adrBackoff lowers TX power or data rate after an uplink left unanswered,
+// when network did not answer ADR acknowledgement requests for
+// ADR_ACK_DELAY uplinks
+func (s *Session) adrBackoff() {
+	if !adrEnabled || s.adrAckCnt < ADR_ACK_LIMIT+ADR_ACK_DELAY {
+		return
+	}
+	if (s.adrAckCnt-ADR_ACK_LIMIT)%ADR_ACK_DELAY == 0 {
+		regionSettings.ADRBackoff()
+	}
+}
+
+// adrAckReq returns true if next uplink must request an answer from network
+func (s *Session) adrAckReq() bool {
+	return adrEnabled && regionSettings != nil &&
+		s.adrAckCnt >= ADR_ACK_LIMIT && !regionSettings.ADRDefaults()
+}
@@ -0,0 +1,193 @@
+package lorawan
+
+import (
+	"bytes"
+	"time"
+
+	"tinygo.org/x/drivers/lora"
+	"tinygo.org/x/drivers/lora/lorawan/region"
+)
+
+const (
+	MTypeUnconfirmedDataDown = 0b011
+	MTypeConfirmedDataDown   = 0b101
+
+	// MAX_FCNT_GAP is the maximum accepted gap between two downlink frame counters
+	MAX_FCNT_GAP = 16384
+)
+
+// Downlink holds a decoded LoRaWAN downlink data message
+type Downlink struct {
+	Confirmed  bool    // Confirmed data down message
+	ACK        bool    // Last confirmed uplink is acknowledged
+	FPending   bool    // Network has more data pending
+	FCnt       uint32  // Downlink frame counter
+	FOpts      []uint8 // MAC commands piggybacked in frame header
+	FPort      uint8   // Application port, 0 for MAC commands only
+	FRMPayload []uint8 // Decrypted frame payload
+}
+
+var lastDownlink *Downlink
+
+// ListenDownlink returns the downlink received in the receive windows
+// opened after the last uplink, if any.
+func ListenDownlink() (*Downlink, error) {
+	if lastDownlink == nil {
+		return nil, ErrNoDownlinkReceived
+	}
+	dl := lastDownlink
+	lastDownlink = nil
+	return dl, nil
+}
+
+// rxDelay returns the delay between the end of uplink and RX1 window opening
+func (s *Session) rxDelay() time.Duration {
+	d := s.RXDelay & 0x0F
+	if d == 0 {
+		d = 1
+	}
+	return time.Duration(d) * time.Second
+}
+
+// rx1DROffset returns the RX1 data rate offset from DLSettings
+func (s *Session) rx1DROffset() uint8 {
+	return (s.DLSettings >> 4) & 0x07
+}
+
+// rx2DataRate returns the RX2 data rate from DLSettings
+func (s *Session) rx2DataRate() uint8 {
+	return s.DLSettings & 0x0F
+}
+
+// receiveWindows opens Class A RX1 and RX2 receive windows following an
+// uplink transmitted on given channel and ended at txEnd
+func receiveWindows(uplink region.Channel, session *Session, txEnd time.Time) (*Downlink, error) {
+	rx1Start := txEnd.Add(session.rxDelay())
+	rx2Start := rx1Start.Add(time.Second)
+
+	// RX1 window lasts until RX2 opens
+	rx1 := regionSettings.Rx1Channel(uplink, session.rx1DROffset())
+	dl, err := receiveWindow(rx1, session, rx1Start, uint32(rx2Start.Sub(rx1Start)/time.Millisecond))
+	if err != nil || dl != nil {
+		return dl, err
+	}
+
+	rx2 := regionSettings.Rx2Channel(session.rx2DataRate())
+	if session.Rx2Frequency != 0 {
+		rx2.SetFrequency(session.Rx2Frequency)
+	}
+	return receiveWindow(rx2, session, rx2Start, LORA_RX_WINDOW_TIMEOUT)
+}
+
+// receiveWindow waits for window opening, then listens on given channel.
+// Frames not intended to this session are silently dropped.
+func receiveWindow(ch region.Channel, session *Session, start time.Time, timeoutMs uint32) (*Downlink, error) {
+	applyChannelConfig(ch)
+	ActiveRadio.SetIqMode(lora.IQInverted)
+
+	sleep(start.Sub(timeNow()))
+	resp, err := ActiveRadio.Rx(timeoutMs)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	dl, err := session.DecodeDownlink(resp)
+	if err != nil {
+		return nil, nil
+	}
+	return dl, nil
+}
+
+// DecodeDownlink checks and decrypts a downlink data message, then updates
+// session downlink frame counter
+func (s *Session) DecodeDownlink(phyPload []uint8) (*Downlink, error) {
+	// MHDR | DevAddr | FCtrl | FCnt | FOpts | FPort | FRMPayload | MIC
+	if len(phyPload) < 12 {
+		return nil, ErrInvalidPacketLength
+	}
+
+	mType := phyPload[0] >> 5
+	if mType != MTypeUnconfirmedDataDown && mType != MTypeConfirmedDataDown {
+		return nil, ErrUnexpectedMType
+	}
+
+	if !bytes.Equal(phyPload[1:5], s.DevAddr[:]) {
+		return nil, ErrDevAddrMismatch
+	}
+
+	fCtrl := phyPload[5]
+	fOptsLen := int(fCtrl & 0x0F)
+	if len(phyPload) < 12+fOptsLen {
+		return nil, ErrInvalidPacketLength
+	}
+	msg := phyPload[:len(phyPload)-4]
+	rxMic := phyPload[len(phyPload)-4:]
+	hasFPort := len(msg) > 8+fOptsLen
+	ack := fCtrl&0x20 != 0
+
+	// LoRaWAN 1.1 uses distinct counters for application and network downlinks
+	counter := &s.FCntDown
+	if s.Version == Version1_1 && hasFPort && msg[8+fOptsLen] != 0 {
+		counter = &s.AFCntDown
+	}
+
+	// Rebuild 32 bits frame counter from its 16 LSB
+	fCnt := *counter&0xFFFF0000 | uint32(phyPload[6]) | uint32(phyPload[7])<<8
+	if fCnt < *counter {
+		fCnt += 0x10000
+	}
+	if fCnt-*counter >= MAX_FCNT_GAP {
+		return nil, ErrInvalidFCntDown
+	}
+
+	var mic [4]uint8
+	if s.Version == Version1_1 {
+		var confFCnt uint16
+		if ack {
+			confFCnt = s.confFCntUp
+		}
+		mic = calcDownlinkMIC11(msg, s.SNwkSIntKey, confFCnt, s.DevAddr[:], fCnt, uint8(len(msg)))
+	} else {
+		mic = calcMessageMIC(msg, s.NwkSKey, 1, s.DevAddr[:], fCnt, uint8(len(msg)))
+	}
+	if !bytes.Equal(mic[:], rxMic) {
+		return nil, ErrInvalidMic
+	}
+
+	dl := &Downlink{
+		Confirmed: mType == MTypeConfirmedDataDown,
+		ACK:       ack,
+		FPending:  fCtrl&0x10 != 0,
+		FCnt:      fCnt,
+		FOpts:     append([]uint8{}, msg[8:8+fOptsLen]...),
+	}
+
+	// FOpts are encrypted since LoRaWAN 1.1
+	nwkSEncKey := s.NwkSKey
+	if s.Version == Version1_1 {
+		nwkSEncKey = s.NwkSEncKey
+		if fOptsLen > 0 {
+			dl.FOpts = s.encryptFOpts(1, counter == &s.AFCntDown, fCnt, dl.FOpts)
+		}
+	}
+
+	if hasFPort {
+		dl.FPort = msg[8+fOptsLen]
+		key := s.AppSKey
+		if dl.FPort == 0 {
+			key = nwkSEncKey
+		}
+		data, err := s.genFRMPayload(key, 1, fCnt, msg[9+fOptsLen:], false)
+		if err != nil {
+			return nil, err
+		}
+		dl.FRMPayload = data
+	}
+
+	if dl.Confirmed {
+		s.confFCntDown = uint16(fCnt)
+	}
+	*counter = fCnt + 1
+
+	return dl, nil
+}
@@ -0,0 +1,133 @@
+package lorawan
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers/lora"
+	"tinygo.org/x/drivers/lora/lorawan/region"
+)
+
+// DutyCycleError is returned when an uplink would exceed the regional duty
+// cycle, or the aggregated duty cycle set by network. It matches
+// ErrDutyCycleExceeded with errors.Is.
+type DutyCycleError struct {
+	RetryAfter time.Duration // Wait time until transmission is allowed
+}
+
+func (e *DutyCycleError) Error() string {
+	return "duty cycle exceeded, retry after " + e.RetryAfter.String()
+}
+
+func (e *DutyCycleError) Is(target error) bool {
+	return target == ErrDutyCycleExceeded
+}
+
+// timeOnAir returns the transmission duration of a frame on given channel
+func timeOnAir(ch region.Channel, frameLen int) time.Duration {
+	return lora.TimeOnAir(frameLen, ch.SpreadingFactor(), ch.Bandwidth(), ch.CodingRate(),
+		ch.PreambleLength(), lora.HeaderExplicit, lora.CRCOn)
+}
+
+// uplinkLen returns the length of the frame carrying given uplink payload,
+// including the pending MAC answers
+func (s *Session) uplinkLen(payload []uint8) int {
+	// MHDR | DevAddr | FCtrl | FCnt | FOpts | FPort | FRMPayload | MIC
+	return 13 + len(s.pendingMACAnswers()) + len(payload)
+}
+
+// checkAirTime returns an error if a frame of given length sent now on given
+// channel would exceed the maximum dwell time, the regional duty cycle or the
+// aggregated duty cycle
+func checkAirTime(ch region.Channel, frameLen int, session *Session) error {
+	airTime := timeOnAir(ch, frameLen)
+	if max := regionSettings.MaxDwellTime(); max != 0 && airTime > max {
+		return ErrDwellTimeExceeded
+	}
+
+	now := timeNow()
+	wait := regionSettings.DutyCycleWait(ch.Frequency(), now)
+	if w := session.txAvailableAt.Sub(now); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return &DutyCycleError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// registerAirTime accounts a frame transmitted from start on given channel
+// in regional and aggregated duty cycles
+func registerAirTime(ch region.Channel, frameLen int, start time.Time, session *Session) {
+	airTime := timeOnAir(ch, frameLen)
+	regionSettings.RegisterTx(ch.Frequency(), start, airTime)
+	if session.MaxDutyCycle != 0 {
+		session.txAvailableAt = start.Add(airTime << session.MaxDutyCycle)
+	}
+}
+
+// checkConfirmedAirTime is checkAirTime for confirmed uplinks, which also
+// respect the retransmission back-off
+func checkConfirmedAirTime(ch region.Channel, frameLen int, session *Session) error {
+	err := checkAirTime(ch, frameLen, session)
+	dc, ok := err.(*DutyCycleError)
+	if err != nil && !ok {
+		return err
+	}
+
+	wait := session.backoffWait(timeOnAir(ch, frameLen), timeNow())
+	if ok && dc.RetryAfter > wait {
+		wait = dc.RetryAfter
+	}
+	if wait > 0 {
+		return &DutyCycleError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// backoffPeriod returns the bounds of the retransmission back-off period
+// containing t, counted from first unacknowledged confirmed uplink, and the
+// maximum aggregated time on air in this period: 36 s in first hour, 36 s in
+// next 10 hours, then 8.7 s per 24 hours
+func backoffPeriod(first, t time.Time) (start, end time.Time, maxAirTime time.Duration) {
+	elapsed := t.Sub(first)
+	switch {
+	case elapsed < time.Hour:
+		return first, first.Add(time.Hour), 36 * time.Second
+	case elapsed < 11*time.Hour:
+		return first.Add(time.Hour), first.Add(11 * time.Hour), 36 * time.Second
+	}
+	days := (elapsed - 11*time.Hour) / (24 * time.Hour)
+	start = first.Add(11*time.Hour + days*24*time.Hour)
+	return start, start.Add(24 * time.Hour), 8700 * time.Millisecond
+}
+
+// backoffWait returns the time to wait before a confirmed uplink of given
+// time on air can be sent, according to the retransmission back-off
+func (s *Session) backoffWait(airTime time.Duration, now time.Time) time.Duration {
+	if s.backoffStart.IsZero() {
+		return 0
+	}
+	start, end, maxAirTime := backoffPeriod(s.backoffStart, now)
+	used := time.Duration(0)
+	if start.Equal(s.backoffPeriod) {
+		used = s.backoffAirTime
+	}
+	if used+airTime <= maxAirTime {
+		return 0
+	}
+	return end.Sub(now)
+}
+
+// registerBackoff accounts a confirmed uplink transmitted from start in the
+// retransmission back-off, until an acknowledgement is received
+func (s *Session) registerBackoff(airTime time.Duration, start time.Time) {
+	if s.backoffStart.IsZero() {
+		s.backoffStart = start
+	}
+	period, _, _ := backoffPeriod(s.backoffStart, start)
+	if !period.Equal(s.backoffPeriod) {
+		s.backoffPeriod = period
+		s.backoffAirTime = 0
+	}
+	s.backoffAirTime += airTime
+}
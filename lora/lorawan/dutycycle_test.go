@@ -0,0 +1,54 @@
+package lorawan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/lora/lorawan/region"
+)
+
+func TestCheckAirTime(t *testing.T) {
+	c := qt.New(t)
+	UseRegionSettings(region.EU868())
+	defer UseRegionSettings(nil)
+	s := &Session{}
+
+	ch := regionSettings.UplinkChannel()
+	c.Assert(checkAirTime(ch, 20, s), qt.IsNil)
+
+	// 1 % duty cycle after a transmission on the same sub-band
+	registerAirTime(ch, 20, time.Now(), s)
+	err := checkAirTime(regionSettings.UplinkChannel(), 20, s)
+	c.Assert(errors.Is(err, ErrDutyCycleExceeded), qt.IsTrue)
+	var dc *DutyCycleError
+	c.Assert(errors.As(err, &dc), qt.IsTrue)
+	c.Assert(dc.RetryAfter > 15*time.Second && dc.RetryAfter <= timeOnAir(ch, 20)*100, qt.IsTrue, qt.Commentf("%v", dc.RetryAfter))
+}
+
+func TestCheckDwellTime(t *testing.T) {
+	c := qt.New(t)
+	UseRegionSettings(region.AS923_1())
+	defer UseRegionSettings(nil)
+	s := &Session{}
+
+	// DR2 (SF10): 11 bytes payload fit in 400 ms, 60 bytes do not
+	ch := regionSettings.UplinkChannel()
+	c.Assert(checkAirTime(ch, s.uplinkLen(make([]uint8, 11)), s), qt.IsNil)
+	c.Assert(checkAirTime(ch, s.uplinkLen(make([]uint8, 60)), s), qt.Equals, ErrDwellTimeExceeded)
+}
+
+func TestAggregatedDutyCycle(t *testing.T) {
+	c := qt.New(t)
+	UseRegionSettings(region.US915())
+	defer UseRegionSettings(nil)
+
+	// No regional duty cycle, but network limits it to 1/2^4
+	s := &Session{MaxDutyCycle: 4}
+	ch := regionSettings.UplinkChannel()
+	start := time.Now()
+	registerAirTime(ch, 20, start, s)
+	c.Assert(s.txAvailableAt, qt.Equals, start.Add(16*timeOnAir(ch, 20)))
+	c.Assert(errors.Is(checkAirTime(ch, 20, s), ErrDutyCycleExceeded), qt.IsTrue)
+}
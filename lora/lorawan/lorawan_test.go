@@ -0,0 +1,452 @@
+package lorawan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/lora"
+	"tinygo.org/x/drivers/lora/lorawan/region"
+	"tinygo.org/x/drivers/tester"
+)
+
+// LoRaWAN 1.0 join procedure test vectors
+const (
+	vectorAppKey     = "b6b53f4a168a7a88bdf7ea135ce9cfca"
+	vectorJoinReq    = "00dc0000d07ed5b3701e6fedf57ceeaf00c886030af2c9"
+	vectorJoinAccept = "204dd85ae608b87fc4889970b7d2042c9e72959b0057aed6094b16003df12de145"
+)
+
+// LoRaWAN 1.0 uplink "test" on port 1, FCnt 2
+const (
+	vectorNwkSKey = "44024241ed4ce9a68c6a8bc055233fd3"
+	vectorAppSKey = "ec925802ae430ca77fd3dd73cb2cc588"
+	vectorUplink  = "40f17dbe4900020001954378762b11ff0d"
+)
+
+// setupRadio attaches a mock radio and given regional settings. Receive
+// windows are opened without waiting.
+func setupRadio(c *qt.C, rs region.Settings) *tester.LoraRadio {
+	radio := tester.NewLoraRadio(c)
+	ActiveRadio = nil
+	UseRadio(radio)
+	UseRegionSettings(rs)
+	sleep = func(time.Duration) {}
+	c.Cleanup(func() {
+		ActiveRadio = nil
+		regionSettings = nil
+		lastDownlink = nil
+		sleep = time.Sleep
+	})
+	return radio
+}
+
+// fakeClock replaces the clock with a fake one, which only advances when
+// sleeping, so that waits are instant and exactly known
+func fakeClock(c *qt.C) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	sleep = func(d time.Duration) {
+		if d > 0 {
+			now = now.Add(d)
+		}
+	}
+	c.Cleanup(func() { timeNow = time.Now })
+}
+
+// airTime returns the time on air of a sent packet
+func airTime(p tester.LoraPacket) time.Duration {
+	return lora.TimeOnAir(len(p.Data), p.Config.Sf, p.Config.Bw, p.Config.Cr, p.Config.Preamble,
+		lora.HeaderExplicit, lora.CRCOn)
+}
+
+// vectorOtaa returns the device of the join procedure test vectors, ready to
+// send its join request
+func vectorOtaa() *Otaa {
+	req := mustHex(vectorJoinReq)
+	o := &Otaa{}
+	o.Set(reverseBytes(req[1:9]), reverseBytes(req[9:17]), mustHex(vectorAppKey))
+	// DevNonce is incremented before being sent
+	o.devNonce = [2]uint8{req[17] - 1, req[18]}
+	return o
+}
+
+// vectorSession returns the session of the uplink test vector
+func vectorSession() *Session {
+	s := &Session{
+		NwkSKey: key16(vectorNwkSKey),
+		AppSKey: key16(vectorAppSKey),
+		FCntUp:  2,
+	}
+	copy(s.DevAddr[:], mustHex(vectorUplink)[1:5])
+	return s
+}
+
+// downlinkFrame builds a downlink as a network server would do. LoRaWAN 1.1
+// downlinks must not acknowledge an uplink.
+func downlinkFrame(s *Session, mType, fCtrl uint8, fCnt uint32, fOpts []uint8, fPort uint8, payload []uint8) []uint8 {
+	v11 := s.Version == Version1_1
+	msg := []uint8{mType << 5}
+	msg = append(msg, s.DevAddr[:]...)
+	msg = append(msg, fCtrl|uint8(len(fOpts)), uint8(fCnt), uint8(fCnt>>8))
+	if v11 && len(fOpts) > 0 {
+		fOpts = s.encryptFOpts(1, payload != nil && fPort != 0, fCnt, fOpts)
+	}
+	msg = append(msg, fOpts...)
+	if payload != nil {
+		key := s.AppSKey
+		if fPort == 0 {
+			key = s.NwkSKey
+			if v11 {
+				key = s.NwkSEncKey
+			}
+		}
+		enc, _ := s.genFRMPayload(key, 1, fCnt, payload, false)
+		msg = append(msg, fPort)
+		msg = append(msg, enc...)
+	}
+	mic := calcMessageMIC(msg, s.NwkSKey, 1, s.DevAddr[:], fCnt, uint8(len(msg)))
+	if v11 {
+		mic = calcDownlinkMIC11(msg, s.SNwkSIntKey, 0, s.DevAddr[:], fCnt, uint8(len(msg)))
+	}
+	return append(msg, mic[:]...)
+}
+
+func TestJoin(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+	radio.QueueRx(mustHex(vectorJoinAccept))
+
+	o := vectorOtaa()
+	s := &Session{}
+	c.Assert(Join(o, s), qt.IsNil)
+
+	c.Assert(radio.Sent, qt.HasLen, 1)
+	tx := radio.Sent[0]
+	c.Assert(tx.Data, qt.DeepEquals, mustHex(vectorJoinReq))
+	c.Assert(tx.Config.Freq, qt.Equals, uint32(lora.MHz_868_1))
+	c.Assert(tx.Config.Sf, qt.Equals, uint8(lora.SpreadingFactor9))
+	c.Assert(tx.Config.Bw, qt.Equals, uint8(lora.Bandwidth_125_0))
+	c.Assert(tx.Config.Iq, qt.Equals, uint8(lora.IQStandard))
+	c.Assert(tx.Config.Crc, qt.Equals, uint8(lora.CRCOn))
+
+	c.Assert(radio.Listened, qt.HasLen, 1)
+	c.Assert(radio.Listened[0].Config.Iq, qt.Equals, uint8(lora.IQInverted))
+
+	c.Assert(s.DevAddr, qt.Equals, [4]uint8{0x43, 0x2e, 0x01, 0x26})
+	var in [16]uint8
+	in[0] = 0x01
+	copy(in[1:4], o.appNonce[:])
+	copy(in[4:7], o.NetID[:])
+	copy(in[7:9], o.devNonce[:])
+	c.Assert(s.NwkSKey, qt.Equals, aesBlock(o.AppKey, in[:]))
+	in[0] = 0x02
+	c.Assert(s.AppSKey, qt.Equals, aesBlock(o.AppKey, in[:]))
+}
+
+func TestJoinNoAnswer(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+
+	c.Assert(Join(vectorOtaa(), &Session{}), qt.Equals, ErrNoJoinAcceptReceived)
+	c.Assert(radio.Sent, qt.HasLen, 1)
+}
+
+func TestDecodeJoinAccept(t *testing.T) {
+	c := qt.New(t)
+
+	o := vectorOtaa()
+	_, err := o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+
+	s := &Session{}
+	c.Assert(o.DecodeJoinAccept(mustHex(vectorJoinAccept), s), qt.IsNil)
+	c.Assert(s.GetDevAddr(), qt.Equals, "432e0126")
+	c.Assert(s.FCntUp, qt.Equals, uint32(0))
+
+	// Join accept not matching AppKey
+	o.SetAppKey(mustHex(vectorNwkSKey))
+	c.Assert(o.DecodeJoinAccept(mustHex(vectorJoinAccept), s), qt.Equals, ErrInvalidMic)
+}
+
+func TestSendUplink(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+
+	s := vectorSession()
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(s.FCntUp, qt.Equals, uint32(3))
+
+	c.Assert(radio.Sent, qt.HasLen, 1)
+	tx := radio.Sent[0]
+	c.Assert(tx.Data, qt.DeepEquals, mustHex(vectorUplink))
+	c.Assert(tx.Config.Sf, qt.Equals, uint8(lora.SpreadingFactor9))
+	c.Assert(tx.Config.Iq, qt.Equals, uint8(lora.IQStandard))
+
+	// Nothing received in RX1 (uplink channel), then RX2
+	c.Assert(radio.Listened, qt.HasLen, 2)
+	rx1, rx2 := radio.Listened[0].Config, radio.Listened[1].Config
+	c.Assert(rx1.Freq, qt.Equals, tx.Config.Freq)
+	c.Assert(rx1.Sf, qt.Equals, uint8(lora.SpreadingFactor9))
+	c.Assert(rx1.Iq, qt.Equals, uint8(lora.IQInverted))
+	c.Assert(rx2.Freq, qt.Equals, uint32(lora.MHz_869_525))
+	c.Assert(rx2.Sf, qt.Equals, uint8(lora.SpreadingFactor12))
+	c.Assert(rx2.Iq, qt.Equals, uint8(lora.IQInverted))
+
+	_, err := ListenDownlink()
+	c.Assert(err, qt.Equals, ErrNoDownlinkReceived)
+
+	// Next uplink would exceed the 1 % duty cycle
+	err = SendUplink([]uint8("test"), s)
+	c.Assert(errors.Is(err, ErrDutyCycleExceeded), qt.IsTrue)
+	c.Assert(radio.Sent, qt.HasLen, 1)
+	c.Assert(s.FCntUp, qt.Equals, uint32(3))
+
+	// Too large for DR3
+	c.Assert(SendUplink(make([]uint8, 116), s), qt.Equals, ErrFrmPayloadTooLarge)
+}
+
+func TestSendUplinkDownlink(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+
+	// Confirmed downlink with a DevStatusReq, received in RX2
+	s := vectorSession()
+	radio.QueueRx(nil)
+	radio.QueueRx(downlinkFrame(s, MTypeConfirmedDataDown, 0, 5, []uint8{CIDDevStatus}, 2, []uint8("hello")))
+	s.FCntDown = 5
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+
+	dl, err := ListenDownlink()
+	c.Assert(err, qt.IsNil)
+	c.Assert(dl.Confirmed, qt.IsTrue)
+	c.Assert(dl.FPort, qt.Equals, uint8(2))
+	c.Assert(string(dl.FRMPayload), qt.Equals, "hello")
+	c.Assert(s.FCntDown, qt.Equals, uint32(6))
+
+	// Next uplink acknowledges the downlink and answers DevStatusReq
+	UseRegionSettings(region.EU868())
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(radio.Sent, qt.HasLen, 2)
+	up := radio.Sent[1].Data
+	c.Assert(up[5], qt.Equals, uint8(0x20|3))
+	c.Assert(up[8:11], qt.DeepEquals, []uint8{CIDDevStatus, 255, 0})
+}
+
+func TestMACAnswersOverflow(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.US915())
+
+	// Six DevStatusAns don't fit in the 15 bytes of FOpts
+	s := vectorSession()
+	var cmds []uint8
+	for i := 0; i < 6; i++ {
+		cmds = append(cmds, CIDDevStatus)
+	}
+	s.processMACCommands(cmds)
+
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(radio.Sent, qt.HasLen, 3)
+	c.Assert(radio.Sent[0].Data[5]&0x0F, qt.Equals, uint8(15))
+	c.Assert(radio.Sent[1].Data[5]&0x0F, qt.Equals, uint8(3))
+	c.Assert(radio.Sent[1].Data[8:11], qt.DeepEquals, []uint8{CIDDevStatus, 255, 0})
+	c.Assert(radio.Sent[2].Data[5]&0x0F, qt.Equals, uint8(0))
+}
+
+func TestRekeyInd(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.US915())
+
+	// Join of the LoRaWAN 1.1 test vectors
+	o := &Otaa{Version: Version1_1}
+	o.Set(mustHex("0807060504030201"), mustHex("0102030405060708"), make([]uint8, 16))
+	o.SetNwkKey(mustHex(vector11NwkKey))
+	o.Init()
+	o.nextNonce = 258
+	_, err := o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	s := &Session{}
+	c.Assert(o.DecodeJoinAccept(mustHex(vector11JoinAccept), s), qt.IsNil)
+	c.Assert(s.Version, qt.Equals, uint8(Version1_1))
+
+	// RekeyInd is sent until RekeyConf is received, even by uplinks
+	// answering downlinks without it
+	radio.QueueRx(nil)
+	radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0, 0, []uint8{CIDDevStatus}, 0, nil))
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	radio.QueueRx(nil)
+	radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0, 1, []uint8{CIDRekey, minorVersion1_1}, 0, nil))
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+
+	c.Assert(radio.Sent, qt.HasLen, 3)
+	fOpts := func(i int) []uint8 {
+		up := radio.Sent[i].Data
+		return s.encryptFOpts(0, false, uint32(i), up[8:8+up[5]&0x0F])
+	}
+	c.Assert(fOpts(0), qt.DeepEquals, []uint8{CIDRekey, minorVersion1_1})
+	c.Assert(fOpts(1), qt.DeepEquals, []uint8{CIDRekey, minorVersion1_1, CIDDevStatus, 255, 0})
+	c.Assert(fOpts(2), qt.HasLen, 0)
+}
+
+func TestResetInd(t *testing.T) {
+	c := qt.New(t)
+	setupRadio(c, region.EU868())
+
+	s := &Session{Version: Version1_1}
+	c.Assert(ActivateABP(s, mustHex("01020304"), make([]uint8, 16), make([]uint8, 16)), qt.IsNil)
+	c.Assert(s.pendingMACAnswers(), qt.DeepEquals, []uint8{CIDReset, minorVersion1_1})
+
+	// The indication is kept in a saved session
+	data, err := s.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	var r Session
+	c.Assert(r.UnmarshalBinary(data), qt.IsNil)
+	c.Assert(r.pendingMACAnswers(), qt.DeepEquals, []uint8{CIDReset, minorVersion1_1})
+
+	// Only ResetConf ends it
+	r.processMACCommands([]uint8{CIDRekey, minorVersion1_1})
+	c.Assert(r.pendingMACAnswers(), qt.DeepEquals, []uint8{CIDReset, minorVersion1_1})
+	r.processMACCommands([]uint8{CIDReset, minorVersion1_1})
+	c.Assert(r.pendingMACAnswers(), qt.HasLen, 0)
+
+	// LoRaWAN 1.0 devices don't send it
+	s = &Session{}
+	c.Assert(ActivateABP(s, mustHex("01020304"), make([]uint8, 16), make([]uint8, 16)), qt.IsNil)
+	c.Assert(s.pendingMACAnswers(), qt.HasLen, 0)
+}
+
+func TestSendConfirmedUplink(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.US915())
+	s := vectorSession()
+
+	// Acknowledged in RX1 of the second transmission
+	radio.OnTx = func(p tester.LoraPacket) {
+		if len(radio.Sent) == 2 {
+			radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0x20, 0, nil, 0, nil))
+		}
+	}
+	c.Assert(SendConfirmedUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(radio.Sent, qt.HasLen, 2)
+	c.Assert(radio.Sent[0].Data[0]>>5, qt.Equals, uint8(MTypeConfirmedDataUp))
+	c.Assert(radio.Sent[1].Data, qt.DeepEquals, radio.Sent[0].Data)
+
+	// Never acknowledged
+	radio.OnTx = nil
+	c.Assert(SendConfirmedUplink([]uint8("test"), s), qt.Equals, ErrNoAckReceived)
+	c.Assert(radio.Sent, qt.HasLen, 2+1+ConfirmedRetries)
+}
+
+func TestConfirmedUplinkRetransmissions(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+	fakeClock(c)
+	s := vectorSession()
+
+	var sent []time.Time
+	radio.OnTx = func(p tester.LoraPacket) {
+		sent = append(sent, timeNow())
+	}
+	c.Assert(SendConfirmedUplink([]uint8("test"), s), qt.Equals, ErrNoAckReceived)
+	c.Assert(radio.Sent, qt.HasLen, 1+ConfirmedRetries)
+
+	// Data rate is lowered every two transmissions, from DR3 to DR0
+	sf := []uint8{9, 9, 10, 10, 11, 11, 12, 12}
+	for i, p := range radio.Sent {
+		c.Assert(p.Config.Sf, qt.Equals, sf[i], qt.Commentf("transmission %d", i+1))
+	}
+
+	// The default channels share a 1 % sub-band, which sets the delay
+	// between retransmissions rather than ACK_TIMEOUT
+	for i := 1; i < len(sent); i++ {
+		c.Assert(sent[i].Sub(sent[i-1]), qt.Equals, 100*airTime(radio.Sent[i-1]), qt.Commentf("transmission %d", i+1))
+	}
+}
+
+func TestConfirmedUplinkBackoff(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.EU868())
+	fakeClock(c)
+	retries := ConfirmedRetries
+	ConfirmedRetries = 15
+	c.Cleanup(func() { ConfirmedRetries = retries })
+	s := vectorSession()
+
+	// DR0, the largest payload: about 3.6 s on air
+	_, drOK, _ := regionSettings.ApplyLinkADR(0, 0, nil, nil)
+	c.Assert(drOK, qt.IsTrue)
+	var sent []time.Time
+	radio.OnTx = func(p tester.LoraPacket) {
+		sent = append(sent, timeNow())
+	}
+	c.Assert(SendConfirmedUplink(make([]uint8, 51), s), qt.Equals, ErrNoAckReceived)
+	c.Assert(radio.Sent, qt.HasLen, 1+ConfirmedRetries)
+
+	// 9 transmissions fit in the 36 s allowed in the first hour, then the
+	// 10th waits for the next period
+	onAir := airTime(radio.Sent[0])
+	c.Assert(9*onAir <= 36*time.Second && 10*onAir > 36*time.Second, qt.IsTrue, qt.Commentf("%v", onAir))
+	for i := 1; i < 9; i++ {
+		c.Assert(sent[i].Sub(sent[i-1]), qt.Equals, 100*onAir)
+	}
+	c.Assert(sent[8].Add(100*onAir).Sub(sent[0]) < time.Hour, qt.IsTrue)
+	c.Assert(sent[9].Sub(sent[0]), qt.Equals, time.Hour)
+	c.Assert(sent[10].Sub(sent[9]), qt.Equals, 100*onAir)
+
+	// An acknowledgement ends the back-off
+	sleep(100 * onAir)
+	radio.OnTx = func(p tester.LoraPacket) {
+		radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0x20, 0, nil, 0, nil))
+	}
+	c.Assert(SendConfirmedUplink(make([]uint8, 51), s), qt.IsNil)
+	c.Assert(s.backoffStart.IsZero(), qt.IsTrue)
+}
+
+func TestADR(t *testing.T) {
+	c := qt.New(t)
+	radio := setupRadio(c, region.US915())
+	SetADR(true)
+	c.Cleanup(func() { SetADR(false) })
+	s := vectorSession()
+
+	// LinkADRReq: DR3, TX power index 2, channels 0 to 7
+	radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0, 0, []uint8{CIDLinkADR, 0x32, 0xFF, 0x00, 0x01}, 0, nil))
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(radio.Sent[0].Data[5]&0xC0, qt.Equals, uint8(0x80))
+	c.Assert(radio.Sent[0].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor8))
+	c.Assert(radio.Sent[0].Config.Bw, qt.Equals, uint8(lora.Bandwidth_500_0))
+
+	// Next uplinks use network settings, without any answer from network
+	for i := 0; i < 2*ADR_ACK_LIMIT+1; i++ {
+		c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	}
+	tx := radio.Sent[1]
+	c.Assert(tx.Data[8:10], qt.DeepEquals, []uint8{CIDLinkADR, 0x07})
+	c.Assert(tx.Config.Sf, qt.Equals, uint8(lora.SpreadingFactor7))
+	c.Assert(tx.Config.Bw, qt.Equals, uint8(lora.Bandwidth_125_0))
+	c.Assert(tx.Config.LoraTxPowerDBm, qt.Equals, int8(region.US915_DEFAULT_TX_POWER_DBM-4))
+
+	// ADRACKReq is set after ADR_ACK_LIMIT uplinks without downlink
+	c.Assert(radio.Sent[ADR_ACK_LIMIT].Data[5]&0x40, qt.Equals, uint8(0))
+	c.Assert(radio.Sent[ADR_ACK_LIMIT+1].Data[5]&0x40, qt.Equals, uint8(0x40))
+
+	// Then TX power is restored, then data rate lowered, every ADR_ACK_DELAY uplinks
+	n := ADR_ACK_LIMIT + ADR_ACK_DELAY
+	c.Assert(radio.Sent[n].Config.LoraTxPowerDBm, qt.Equals, int8(region.US915_DEFAULT_TX_POWER_DBM-4))
+	c.Assert(radio.Sent[n+1].Config.LoraTxPowerDBm, qt.Equals, int8(region.US915_DEFAULT_TX_POWER_DBM))
+	c.Assert(radio.Sent[n+1].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor7))
+	n += ADR_ACK_DELAY
+	c.Assert(radio.Sent[n].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor7))
+	c.Assert(radio.Sent[n+1].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor8))
+
+	// A downlink stops ADR acknowledgement requests
+	radio.QueueRx(downlinkFrame(s, MTypeUnconfirmedDataDown, 0, 1, nil, 0, nil))
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(SendUplink([]uint8("test"), s), qt.IsNil)
+	c.Assert(radio.Sent[len(radio.Sent)-2].Data[5]&0x40, qt.Equals, uint8(0x40))
+	c.Assert(radio.Sent[len(radio.Sent)-1].Data[5]&0x40, qt.Equals, uint8(0))
+}
@@ -0,0 +1,226 @@
+package lorawan
+
+// MAC command identifiers (CID)
+const (
+	CIDReset         = 0x01 // LoRaWAN 1.1 ABP
+	CIDLinkCheck     = 0x02
+	CIDLinkADR       = 0x03
+	CIDDutyCycle     = 0x04
+	CIDRXParamSetup  = 0x05
+	CIDDevStatus     = 0x06
+	CIDNewChannel    = 0x07
+	CIDRXTimingSetup = 0x08
+	CIDTxParamSetup  = 0x09
+	CIDDlChannel     = 0x0A
+	CIDRekey         = 0x0B // LoRaWAN 1.1 OTAA
+	CIDDeviceTime    = 0x0D
+)
+
+// minorVersion1_1 is the LoRaWAN minor version sent in ResetInd and RekeyInd
+const minorVersion1_1 = 0x01
+
+// MAX_FOPTS_LEN is the maximum size of MAC commands piggybacked in FOpts
+const MAX_FOPTS_LEN = 15
+
+// macRequestLen gives the payload length of MAC commands sent by network
+var macRequestLen = map[uint8]int{
+	CIDReset:         1,
+	CIDLinkCheck:     2,
+	CIDLinkADR:       4,
+	CIDDutyCycle:     1,
+	CIDRXParamSetup:  4,
+	CIDDevStatus:     0,
+	CIDNewChannel:    5,
+	CIDRXTimingSetup: 1,
+	CIDTxParamSetup:  1,
+	CIDDlChannel:     4,
+	CIDRekey:         1,
+	CIDDeviceTime:    5,
+}
+
+// macAnswerLen gives the payload length of MAC commands sent by device
+var macAnswerLen = map[uint8]int{
+	CIDReset:         1,
+	CIDLinkADR:       1,
+	CIDDutyCycle:     0,
+	CIDRXParamSetup:  1,
+	CIDDevStatus:     2,
+	CIDNewChannel:    1,
+	CIDRXTimingSetup: 0,
+	CIDTxParamSetup:  0,
+	CIDDlChannel:     1,
+	CIDRekey:         1,
+}
+
+// BatteryLevel is called to answer DevStatusReq. It returns 0 for external
+// power source, 1 to 254 for battery level, or 255 if not measurable.
+var BatteryLevel = func() uint8 {
+	return 255
+}
+
+// processMACCommands applies MAC commands received from network, and queues
+// the matching answers for next uplink
+func (s *Session) processMACCommands(cmds []uint8) {
+	for len(cmds) > 0 {
+		cid := cmds[0]
+		n, ok := macRequestLen[cid]
+		if !ok || len(cmds) < 1+n {
+			// Unknown command: remaining ones cannot be parsed
+			return
+		}
+
+		switch cid {
+		case CIDReset, CIDRekey:
+			// ResetConf or RekeyConf ends the indication sent since
+			// activation
+			if cid == s.versionInd {
+				s.versionInd = 0
+			}
+
+		case CIDLinkADR:
+			// Contiguous LinkADRReq are handled as one atomic block
+			n = 0
+			for n+5 <= len(cmds) && cmds[n] == CIDLinkADR {
+				n += 5
+			}
+			s.linkADRReq(cmds[:n])
+			cmds = cmds[n:]
+			continue
+
+		case CIDDutyCycle:
+			s.MaxDutyCycle = cmds[1] & 0x0F
+			s.queueMACAnswer(false, CIDDutyCycle)
+
+		case CIDRXParamSetup:
+			s.rxParamSetupReq(cmds[1], decodeFrequency(cmds[2:5]))
+
+		case CIDDevStatus:
+			// Demodulation margin is not available from lora.Radio
+			s.queueMACAnswer(false, CIDDevStatus, BatteryLevel(), 0)
+
+		case CIDNewChannel:
+			freqOK, drOK := regionSettings.SetChannel(cmds[1], decodeFrequency(cmds[2:5]), cmds[5]&0x0F, cmds[5]>>4)
+			s.queueMACAnswer(false, CIDNewChannel, ackBits(drOK, freqOK))
+
+		case CIDRXTimingSetup:
+			s.RXDelay = cmds[1] & 0x0F
+			s.queueMACAnswer(true, CIDRXTimingSetup)
+
+		case CIDTxParamSetup:
+			// Only answered by regions supporting it
+			p := cmds[1]
+			if regionSettings.SetTxParams(p&0x10 != 0, p&0x20 != 0, p&0x0F) {
+				s.queueMACAnswer(false, CIDTxParamSetup)
+			}
+
+		case CIDDlChannel:
+			freqOK, uplinkExists := regionSettings.SetDownlinkFrequency(cmds[1], decodeFrequency(cmds[2:5]))
+			s.queueMACAnswer(true, CIDDlChannel, ackBits(uplinkExists, freqOK))
+		}
+
+		cmds = cmds[1+n:]
+	}
+}
+
+// linkADRReq applies a block of LinkADRReq commands. The last command gives
+// data rate and TX power, each command gives a channel mask.
+func (s *Session) linkADRReq(cmds []uint8) {
+	count := len(cmds) / 5
+	chMaskCntl := make([]uint8, count)
+	chMask := make([]uint16, count)
+	for i := 0; i < count; i++ {
+		c := cmds[i*5:]
+		chMask[i] = uint16(c[2]) | uint16(c[3])<<8
+		chMaskCntl[i] = (c[4] >> 4) & 0x07
+	}
+	last := cmds[(count-1)*5:]
+	dataRate := last[1] >> 4
+	txPower := last[1] & 0x0F
+
+	powerOK, drOK, chMaskOK := regionSettings.ApplyLinkADR(dataRate, txPower, chMaskCntl, chMask)
+	for i := 0; i < count; i++ {
+		s.queueMACAnswer(false, CIDLinkADR, ackBits(powerOK, drOK, chMaskOK))
+	}
+}
+
+// rxParamSetupReq applies RX1 data rate offset, RX2 data rate and frequency
+func (s *Session) rxParamSetupReq(dlSettings uint8, freq uint32) {
+	_, drOK := regionSettings.DataRate(dlSettings & 0x0F)
+	offsetOK := (dlSettings>>4)&0x07 <= 5
+	freqOK := regionSettings.ValidFrequency(freq)
+
+	if drOK && offsetOK && freqOK {
+		s.DLSettings = dlSettings & 0x7F
+		s.Rx2Frequency = freq
+	}
+	s.queueMACAnswer(true, CIDRXParamSetup, ackBits(offsetOK, drOK, freqOK))
+}
+
+// queueMACAnswer adds a MAC command to next uplink. Sticky answers are
+// repeated in every uplink until a downlink is received.
+func (s *Session) queueMACAnswer(sticky bool, cid uint8, payload ...uint8) {
+	if sticky {
+		s.stickyMACAnswers = append(s.stickyMACAnswers, cid)
+		s.stickyMACAnswers = append(s.stickyMACAnswers, payload...)
+	} else {
+		s.macAnswers = append(s.macAnswers, cid)
+		s.macAnswers = append(s.macAnswers, payload...)
+	}
+}
+
+// versionIndication returns the ResetInd or RekeyInd to be sent in every
+// uplink until network confirms it, if any
+func (s *Session) versionIndication() []uint8 {
+	if s.versionInd == 0 {
+		return nil
+	}
+	return []uint8{s.versionInd, minorVersion1_1}
+}
+
+// pendingMACAnswers returns the MAC commands to be sent in next uplink FOpts.
+// Non-sticky answers remain queued until the uplink frame is generated.
+func (s *Session) pendingMACAnswers() []uint8 {
+	var fOpts []uint8
+	fOpts = append(fOpts, s.versionIndication()...)
+	fOpts = append(fOpts, s.stickyMACAnswers...)
+	fOpts = append(fOpts, s.macAnswers...)
+
+	// Answers not fitting in FOpts are left for next uplinks
+	n := 0
+	for n < len(fOpts) {
+		next := n + 1 + macAnswerLen[fOpts[n]]
+		if next > MAX_FOPTS_LEN {
+			break
+		}
+		n = next
+	}
+	return fOpts[:n]
+}
+
+// dequeueMACAnswers removes the non-sticky answers sent in fOpts from the
+// queue. The answers that did not fit remain queued for next uplink.
+func (s *Session) dequeueMACAnswers(fOpts []uint8) {
+	n := len(fOpts) - len(s.versionIndication()) - len(s.stickyMACAnswers)
+	if n <= 0 {
+		return
+	}
+	s.macAnswers = append(s.macAnswers[:0], s.macAnswers[n:]...)
+}
+
+// ackBits packs ACK flags in a MAC answer status byte, first flag being the
+// most significant one
+func ackBits(flags ...bool) uint8 {
+	var status uint8
+	for _, f := range flags {
+		status <<= 1
+		if f {
+			status |= 1
+		}
+	}
+	return status
+}
+
+// decodeFrequency decodes a 24 bits little endian frequency in 100 Hz steps
+func decodeFrequency(b []uint8) uint32 {
+	return (uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16) * 100
+}
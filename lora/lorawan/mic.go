@@ -15,8 +15,31 @@ func genPayloadMIC(payload []uint8, key [16]uint8) [4]uint8 {
 }
 
 func calcMessageMIC(payload []uint8, key [16]uint8, dir uint8, addr []byte, fCnt uint32, lenMessage uint8) [4]uint8 {
-	var b0 []byte
-	b0 = append(b0, 0x49, 0x00, 0x00, 0x00, 0x00)
+	b0 := micBlock(0, 0, 0, dir, addr, fCnt, lenMessage)
+	return genPayloadMIC(append(b0, payload...), key)
+}
+
+// calcUplinkMIC11 computes a LoRaWAN 1.1 uplink MIC, made of 2 bytes from
+// SNwkSIntKey (B1 block) and 2 bytes from FNwkSIntKey (B0 block)
+func calcUplinkMIC11(payload []uint8, fNwkSIntKey, sNwkSIntKey [16]uint8, confFCnt uint16, txDr, txCh uint8, addr []byte, fCnt uint32, lenMessage uint8) [4]uint8 {
+	b0 := micBlock(0, 0, 0, 0, addr, fCnt, lenMessage)
+	b1 := micBlock(confFCnt, txDr, txCh, 0, addr, fCnt, lenMessage)
+	cmacF := genPayloadMIC(append(b0, payload...), fNwkSIntKey)
+	cmacS := genPayloadMIC(append(b1, payload...), sNwkSIntKey)
+	return [4]uint8{cmacS[0], cmacS[1], cmacF[0], cmacF[1]}
+}
+
+// calcDownlinkMIC11 computes a LoRaWAN 1.1 downlink MIC
+func calcDownlinkMIC11(payload []uint8, sNwkSIntKey [16]uint8, confFCnt uint16, addr []byte, fCnt uint32, lenMessage uint8) [4]uint8 {
+	b0 := micBlock(confFCnt, 0, 0, 1, addr, fCnt, lenMessage)
+	return genPayloadMIC(append(b0, payload...), sNwkSIntKey)
+}
+
+// micBlock returns the B0 (or LoRaWAN 1.1 B1) block prepended to messages
+// for MIC computation
+func micBlock(confFCnt uint16, txDr, txCh uint8, dir uint8, addr []byte, fCnt uint32, lenMessage uint8) []byte {
+	b0 := make([]byte, 0, 16)
+	b0 = append(b0, 0x49, uint8(confFCnt), uint8(confFCnt>>8), txDr, txCh)
 	b0 = append(b0, dir)
 	b0 = append(b0, addr[:]...)
 	var b [4]byte
@@ -24,15 +47,5 @@ func calcMessageMIC(payload []uint8, key [16]uint8, dir uint8, addr []byte, fCnt
 	b0 = append(b0, b[:]...)
 	b0 = append(b0, 0x00)
 	b0 = append(b0, lenMessage)
-
-	var full []byte
-	full = append(full, b0...)
-	full = append(full, payload...)
-
-	var mic [4]uint8
-	hash, _ := NewCmac(key[:])
-	hash.Write(full)
-	hb := hash.Sum([]byte{})
-	copy(mic[:], hb[0:4])
-	return mic
+	return b0
 }
@@ -0,0 +1,253 @@
+package lorawan
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func mustHex(s string) []uint8 {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func key16(s string) [16]uint8 {
+	var k [16]uint8
+	copy(k[:], mustHex(s))
+	return k
+}
+
+// AES-CMAC test vectors from RFC 4493
+func TestCmac(t *testing.T) {
+	c := qt.New(t)
+
+	key := key16("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := mustHex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	for _, tc := range []struct {
+		len int
+		mac string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	} {
+		h, err := NewCmac(key[:])
+		c.Assert(err, qt.IsNil)
+		h.Write(msg[:tc.len])
+		c.Assert(hex.EncodeToString(h.Sum(nil)), qt.Equals, tc.mac)
+	}
+}
+
+// LoRaWAN 1.0 uplink "test" on port 1, FCnt 2
+func TestMessageMIC10(t *testing.T) {
+	c := qt.New(t)
+
+	pkt := mustHex("40f17dbe4900020001954378762b11ff0d")
+	s := &Session{
+		NwkSKey: key16("44024241ed4ce9a68c6a8bc055233fd3"),
+		AppSKey: key16("ec925802ae430ca77fd3dd73cb2cc588"),
+	}
+	copy(s.DevAddr[:], pkt[1:5])
+	msg := pkt[:len(pkt)-4]
+
+	mic := calcMessageMIC(msg, s.NwkSKey, 0, s.DevAddr[:], 2, uint8(len(msg)))
+	c.Assert(mic[:], qt.DeepEquals, pkt[len(pkt)-4:])
+
+	data, err := s.genFRMPayload(s.AppSKey, 0, 2, pkt[9:len(pkt)-4], false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "test")
+
+	s.FCntUp = 2
+	frame, fCnt, err := s.genFrame(MTypeUnconfirmedDataUp, 0, []uint8("test"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.appendMIC(frame, 0, fCnt, 0, 0), qt.DeepEquals, pkt)
+}
+
+// aesBlock encrypts a single block, as used by key derivations
+func aesBlock(key [16]uint8, in []uint8) [16]uint8 {
+	var out, b [16]uint8
+	copy(b[:], in)
+	block, _ := aes.NewCipher(key[:])
+	block.Encrypt(out[:], b[:])
+	return out
+}
+
+// joinAccept builds a join accept as a network server would do
+func joinAccept(c *qt.C, encKey, micKey [16]uint8, micPrefix []uint8, plain []uint8) []uint8 {
+	msg := append([]uint8{0x20}, plain...)
+	mic := genPayloadMIC(append(append([]uint8{}, micPrefix...), msg...), micKey)
+	clear := append(plain, mic[:]...)
+	c.Assert(len(clear)%aes.BlockSize, qt.Equals, 0)
+
+	block, _ := aes.NewCipher(encKey[:])
+	out := []uint8{0x20}
+	buf := make([]uint8, aes.BlockSize)
+	for i := 0; i < len(clear); i += aes.BlockSize {
+		block.Decrypt(buf, clear[i:])
+		out = append(out, buf...)
+	}
+	return out
+}
+
+func testOtaa(version uint8) *Otaa {
+	o := &Otaa{Version: version}
+	o.Set(mustHex("70b3d57ed0000001"), mustHex("0004a30b001c0530"), mustHex("2b7e151628aed2a6abf7158809cf4f3c"))
+	o.SetNwkKey(mustHex("000102030405060708090a0b0c0d0e0f"))
+	o.Init()
+	return o
+}
+
+func TestJoinAccept10(t *testing.T) {
+	c := qt.New(t)
+
+	o := testOtaa(Version1_0)
+	_, err := o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+
+	// JoinNonce | NetID | DevAddr | DLSettings | RxDelay
+	plain := mustHex("010000" + "130000" + "01000026" + "01" + "01")
+	s := &Session{}
+	c.Assert(o.DecodeJoinAccept(joinAccept(c, o.AppKey, o.AppKey, nil, plain), s), qt.IsNil)
+	c.Assert(s.Version, qt.Equals, uint8(Version1_0))
+	c.Assert(s.DevAddr, qt.Equals, [4]uint8{0x01, 0x00, 0x00, 0x26})
+	c.Assert(s.DLSettings, qt.Equals, uint8(0x01))
+
+	var in [16]uint8
+	in[0] = 0x01
+	copy(in[1:7], plain[0:6])
+	copy(in[7:9], o.devNonce[:])
+	c.Assert(s.NwkSKey, qt.Equals, aesBlock(o.AppKey, in[:]))
+	in[0] = 0x02
+	c.Assert(s.AppSKey, qt.Equals, aesBlock(o.AppKey, in[:]))
+
+	// Tampered MIC
+	ja := joinAccept(c, o.AppKey, o.AppKey, nil, plain)
+	ja[len(ja)-1] ^= 0xFF
+	c.Assert(o.DecodeJoinAccept(ja, s), qt.Equals, ErrInvalidMic)
+}
+
+// LoRaWAN 1.1 join test vectors, from the join server tests of
+// github.com/brocaar/lorawan: JoinEUI 0807060504030201, DevEUI
+// 0102030405060708, DevNonce 258, JoinNonce 65536, and a zero AppKey
+const (
+	vector11NwkKey      = "01020304050607080102030405060708"
+	vector11JoinReq     = "00010203040506070808070605040302010201cde6acb8"
+	vector11JoinAccept  = "20a4c899795a42f5d16067a822c21b379af8aaa301a90d1c60d4cb792cb8eeb0a3"
+	vector11FNwkSIntKey = "537f8aae896c79e015d102d06286354e"
+	vector11SNwkSIntKey = "589498993092cfdb5fd2e02ac7510bf1"
+	vector11NwkSEncKey  = "9898283c4f66eb6c6fd5165882046c40"
+	vector11AppSKey     = "01621215d1ca08febf0c602cc2ad90fa"
+)
+
+// LoRaWAN 1.1 data frames test vectors, from the PHYPayload tests of
+// github.com/brocaar/lorawan: "hello" on port 1 with ADR, FCnt 1, sent at DR2
+// on channel 3, acknowledging the downlink of ConfFCnt 1, and a LinkCheckAns
+// in encrypted FOpts of a downlink
+const (
+	vector11DataSNwkSIntKey = "02020202020202020202020202020202"
+	vector11DataFNwkSIntKey = "02020202020202020202020202020203"
+	vector11DataNwkSEncKey  = "02020202020202020202020202020204"
+	vector11DataAppSKey     = "01010101010101010101010101010101"
+	vector11Uplink          = "400403020180010001a6946426157612366a"
+	vector11UplinkACK       = "4004030201a0010001a694642615f842c4b9"
+	vector11DownlinkFOpts   = "6004030201030000dfb4f1e24f1f9f"
+)
+
+func TestJoinAccept11(t *testing.T) {
+	c := qt.New(t)
+
+	o := &Otaa{Version: Version1_1}
+	o.Set(mustHex("0807060504030201"), mustHex("0102030405060708"), make([]uint8, 16))
+	o.SetNwkKey(mustHex(vector11NwkKey))
+	o.Init()
+	o.nextNonce = 258
+	req, err := o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	c.Assert(req, qt.DeepEquals, mustHex(vector11JoinReq))
+
+	s := &Session{}
+	c.Assert(o.DecodeJoinAccept(mustHex(vector11JoinAccept), s), qt.IsNil)
+	c.Assert(s.Version, qt.Equals, uint8(Version1_1))
+	c.Assert(s.DevAddr, qt.Equals, [4]uint8{0x04, 0x03, 0x02, 0x01})
+	c.Assert(s.DLSettings, qt.Equals, uint8(0x15))
+	c.Assert(s.RXDelay, qt.Equals, uint8(1))
+	c.Assert(o.NetID, qt.Equals, [3]uint8{0x03, 0x02, 0x01})
+	c.Assert(s.CFList[:6], qt.DeepEquals, mustHex("988d84689584"))
+	c.Assert(s.FNwkSIntKey, qt.Equals, key16(vector11FNwkSIntKey))
+	c.Assert(s.SNwkSIntKey, qt.Equals, key16(vector11SNwkSIntKey))
+	c.Assert(s.NwkSEncKey, qt.Equals, key16(vector11NwkSEncKey))
+	c.Assert(s.AppSKey, qt.Equals, key16(vector11AppSKey))
+
+	// A replayed JoinNonce is rejected, and leaves the session untouched
+	s.DevAddr = [4]uint8{0x11, 0x22, 0x33, 0x44}
+	s.DLSettings = 0x03
+	s.RXDelay = 5
+	s.CFList = [16]uint8{}
+	o.nextNonce = 258
+	_, err = o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	c.Assert(o.DecodeJoinAccept(mustHex(vector11JoinAccept), s), qt.Equals, ErrInvalidJoinNonce)
+	assertSessionUntouched := func() {
+		c.Assert(s.DevAddr, qt.Equals, [4]uint8{0x11, 0x22, 0x33, 0x44})
+		c.Assert(s.DLSettings, qt.Equals, uint8(0x03))
+		c.Assert(s.RXDelay, qt.Equals, uint8(5))
+		c.Assert(s.CFList, qt.Equals, [16]uint8{})
+		c.Assert(s.FNwkSIntKey, qt.Equals, key16(vector11FNwkSIntKey))
+	}
+	assertSessionUntouched()
+
+	// Tampered MIC
+	o = testOtaa(Version1_1)
+	_, err = o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	c.Assert(o.DecodeJoinAccept(mustHex(vector11JoinAccept), s), qt.Equals, ErrInvalidMic)
+	assertSessionUntouched()
+	c.Assert(o.DecodeJoinAccept(mustHex(vector11JoinAccept)[:20], s), qt.Equals, ErrInvalidPacketLength)
+
+	// Without OptNeg, the network is LoRaWAN 1.0: NwkKey is used as AppKey
+	o = testOtaa(Version1_1)
+	_, err = o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	plain := mustHex("050000" + "130000" + "01000026" + "00" + "01" + "184f84e85684b85e84886684586e8400")
+	c.Assert(o.DecodeJoinAccept(joinAccept(c, o.NwkKey, o.NwkKey, nil, plain), s), qt.IsNil)
+	c.Assert(s.Version, qt.Equals, uint8(Version1_0))
+	c.Assert(s.SNwkSIntKey, qt.Equals, s.NwkSKey)
+}
+
+func TestUplinkMIC11(t *testing.T) {
+	c := qt.New(t)
+	SetADR(true)
+	defer SetADR(false)
+
+	s := &Session{
+		Version:     Version1_1,
+		DevAddr:     [4]uint8{0x04, 0x03, 0x02, 0x01},
+		FNwkSIntKey: key16(vector11DataFNwkSIntKey),
+		SNwkSIntKey: key16(vector11DataSNwkSIntKey),
+		NwkSEncKey:  key16(vector11DataNwkSEncKey),
+		AppSKey:     key16(vector11DataAppSKey),
+		FCntUp:      1,
+	}
+	frame, fCnt, err := s.genFrame(MTypeUnconfirmedDataUp, 0, []uint8("hello"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.appendMIC(frame, 0, fCnt, 2, 3), qt.DeepEquals, mustHex(vector11Uplink))
+
+	// ConfFCnt is used by the SNwkSIntKey half of the MIC
+	s.FCntUp = 1
+	s.ackDownlink = true
+	s.confFCntDown = 1
+	frame, fCnt, err = s.genFrame(MTypeUnconfirmedDataUp, 0, []uint8("hello"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.appendMIC(frame, 0, fCnt, 2, 3), qt.DeepEquals, mustHex(vector11UplinkACK))
+
+	// FOpts are encrypted with NwkSEncKey
+	dl, err := s.DecodeDownlink(mustHex(vector11DownlinkFOpts))
+	c.Assert(err, qt.IsNil)
+	c.Assert(dl.FOpts, qt.DeepEquals, []uint8{CIDLinkCheck, 7, 1})
+}
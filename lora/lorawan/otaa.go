@@ -6,21 +6,42 @@ import (
 	"encoding/hex"
 )
 
+// Join request types, as used in LoRaWAN 1.1 join accept MIC
+const (
+	JoinRequestType    = 0xFF
+	RejoinRequestType0 = 0x00
+	RejoinRequestType1 = 0x01
+	RejoinRequestType2 = 0x02
+)
+
 // Otaa is used to store Over The Air Activation data of a LoRaWAN session
 type Otaa struct {
 	DevEUI   [8]uint8
-	AppEUI   [8]uint8
+	AppEUI   [8]uint8 // JoinEUI for LoRaWAN 1.1
 	AppKey   [16]uint8
 	devNonce [2]uint8
-	appNonce [3]uint8
+	appNonce [3]uint8 // JoinNonce for LoRaWAN 1.1
 	NetID    [3]uint8
 	buf      []uint8
+
+	// LoRaWAN 1.1 only
+	Version    uint8
+	NwkKey     [16]uint8
+	rjCount1   uint16
+	nextNonce  uint16   // DevNonce of next join request, a counter from 0
+	rejoin     bool     // Last request is a rejoin request
+	rejoinType uint8    // Type of last rejoin request
+	rjCount    [2]uint8 // RJcount of last rejoin request
 }
 
-// Initialize DevNonce
+// Initialize DevNonce. A DevNonce restored with UnmarshalBinary is kept, so
+// that it is used as a counter across resets. LoRaWAN 1.1 DevNonce is a
+// counter from 0, never random.
 func (o *Otaa) Init() {
 	o.buf = make([]uint8, 0)
-	o.generateDevNonce()
+	if o.Version != Version1_1 && o.devNonce == [2]uint8{} {
+		o.generateDevNonce()
+	}
 }
 
 func (o *Otaa) generateDevNonce() {
@@ -102,9 +123,51 @@ func (o *Otaa) SetNetID(netID []uint8) error {
 	return nil
 }
 
+// SetNwkKey configures the Otaa NwkKey (LoRaWAN 1.1)
+func (o *Otaa) SetNwkKey(nwkKey []uint8) error {
+	if len(nwkKey) != 16 {
+		return ErrInvalidAppKeyLength
+	}
+
+	copy(o.NwkKey[:], nwkKey)
+
+	return nil
+}
+
+func (o *Otaa) GetNwkKey() string {
+	return hex.EncodeToString(o.NwkKey[:])
+}
+
+// rootNwkKey returns the key protecting join procedure: NwkKey since
+// LoRaWAN 1.1, AppKey before
+func (o *Otaa) rootNwkKey() [16]uint8 {
+	if o.Version == Version1_1 {
+		return o.NwkKey
+	}
+	return o.AppKey
+}
+
+// deriveJSKey derives JSIntKey (0x06) or JSEncKey (0x05) from NwkKey
+func (o *Otaa) deriveJSKey(prefix uint8) [16]uint8 {
+	var in, out [16]uint8
+	in[0] = prefix
+	copy(in[1:9], reverseBytes(o.DevEUI[:]))
+	block, _ := aes.NewCipher(o.NwkKey[:])
+	block.Encrypt(out[:], in[:])
+	return out
+}
+
 // GenerateJoinRequest Generates a LoraWAN Join request
 func (o *Otaa) GenerateJoinRequest() ([]uint8, error) {
-	o.incrementDevNonce()
+	if o.Version == Version1_1 {
+		// Network servers reject a DevNonce that does not increase, so
+		// the counter must be saved with MarshalBinary
+		o.devNonce = [2]uint8{uint8(o.nextNonce), uint8(o.nextNonce >> 8)}
+		o.nextNonce++
+	} else {
+		o.incrementDevNonce()
+	}
+	o.rejoin = false
 
 	// TODO: Add checks
 	o.buf = o.buf[:0]
@@ -112,21 +175,75 @@ func (o *Otaa) GenerateJoinRequest() ([]uint8, error) {
 	o.buf = append(o.buf, reverseBytes(o.AppEUI[:])...)
 	o.buf = append(o.buf, reverseBytes(o.DevEUI[:])...)
 	o.buf = append(o.buf, o.devNonce[:]...)
-	mic := genPayloadMIC(o.buf, o.AppKey)
+	mic := genPayloadMIC(o.buf, o.rootNwkKey())
 	o.buf = append(o.buf, mic[:]...)
 
 	return o.buf, nil
 }
 
-// DecodeJoinAccept Decodes a Lora Join Accept packet
+// GenerateRejoinRequest Generates a LoraWAN 1.1 Rejoin request of given type,
+// for a session joined with this Otaa
+func (o *Otaa) GenerateRejoinRequest(rejoinType uint8, s *Session) ([]uint8, error) {
+	if o.Version != Version1_1 || s.Version != Version1_1 {
+		return nil, ErrUnsupportedVersion
+	}
+
+	o.buf = o.buf[:0]
+	o.buf = append(o.buf, 0xC0, rejoinType)
+
+	var rjCount uint16
+	var key [16]uint8
+	switch rejoinType {
+	case RejoinRequestType0, RejoinRequestType2:
+		rjCount = s.RJCount0
+		s.RJCount0++
+		key = s.SNwkSIntKey
+		o.buf = append(o.buf, o.NetID[:]...)
+	case RejoinRequestType1:
+		rjCount = o.rjCount1
+		o.rjCount1++
+		key = o.deriveJSKey(0x06)
+		o.buf = append(o.buf, reverseBytes(o.AppEUI[:])...)
+	default:
+		return nil, ErrInvalidRejoinType
+	}
+	o.rejoin = true
+	o.rejoinType = rejoinType
+	o.rjCount = [2]uint8{uint8(rjCount), uint8(rjCount >> 8)}
+
+	o.buf = append(o.buf, reverseBytes(o.DevEUI[:])...)
+	o.buf = append(o.buf, o.rjCount[:]...)
+	mic := genPayloadMIC(o.buf, key)
+	o.buf = append(o.buf, mic[:]...)
+
+	return o.buf, nil
+}
+
+// lastRequest returns the type and the DevNonce or RJcount of the last join
+// or rejoin request
+func (o *Otaa) lastRequest() (uint8, [2]uint8) {
+	if o.rejoin {
+		return o.rejoinType, o.rjCount
+	}
+	return JoinRequestType, o.devNonce
+}
+
+// DecodeJoinAccept Decodes a Lora Join Accept packet, answering the last join
+// or rejoin request
 func (o *Otaa) DecodeJoinAccept(phyPload []uint8, s *Session) error {
-	if len(phyPload) < 12 {
+	// MHDR | JoinNonce | NetID | DevAddr | DLSettings | RxDelay | CFList | MIC
+	if len(phyPload) != 17 && len(phyPload) != 33 {
 		return ErrInvalidPacketLength
 	}
 	data := phyPload[1:] // Remove trailing 0x20
+	joinReqType, nonce := o.lastRequest()
 
-	// Prepare AES Cipher
-	block, err := aes.NewCipher(o.AppKey[:])
+	// Prepare AES Cipher. Rejoin accepts are encrypted with JSEncKey.
+	key := o.rootNwkKey()
+	if o.Version == Version1_1 && o.rejoin {
+		key = o.deriveJSKey(0x05)
+	}
+	block, err := aes.NewCipher(key[:])
 	if err != nil {
 		return err
 	}
@@ -135,36 +252,82 @@ func (o *Otaa) DecodeJoinAccept(phyPload []uint8, s *Session) error {
 		block.Encrypt(buf[k*aes.BlockSize:], data[k*aes.BlockSize:])
 	}
 
-	copy(o.appNonce[:], buf[0:3])
-	copy(o.NetID[:], buf[3:6])
-	copy(s.DevAddr[:], buf[6:10])
-	s.DLSettings = buf[10]
-	s.RXDelay = buf[11]
-
-	if len(buf) > 16 {
-		copy(s.CFList[:], buf[12:28])
-	}
+	// Fields are only applied once the join accept is authenticated, so
+	// that a forged or replayed one leaves the session untouched
+	var joinNonce, netID [3]uint8
+	copy(joinNonce[:], buf[0:3])
+	copy(netID[:], buf[3:6])
+	dlSettings := buf[10]
 	rxMic := buf[len(buf)-4:]
 
+	// OptNeg bit is set by LoRaWAN 1.1 network servers
+	optNeg := o.Version == Version1_1 && dlSettings&0x80 != 0
+
 	dataMic := []byte{}
+	micKey := key
+	if optNeg {
+		// JoinReqType | JoinEUI | DevNonce or RJcount prefix
+		dataMic = append(dataMic, joinReqType)
+		dataMic = append(dataMic, reverseBytes(o.AppEUI[:])...)
+		dataMic = append(dataMic, nonce[:]...)
+		micKey = o.deriveJSKey(0x06)
+	}
 	dataMic = append(dataMic, phyPload[0])
-	dataMic = append(dataMic, o.appNonce[:]...)
-	dataMic = append(dataMic, o.NetID[:]...)
-	dataMic = append(dataMic, s.DevAddr[:]...)
-	dataMic = append(dataMic, s.DLSettings)
-	dataMic = append(dataMic, s.RXDelay)
-	dataMic = append(dataMic, s.CFList[:]...)
-	computedMic := genPayloadMIC(dataMic[:], o.AppKey)
+	dataMic = append(dataMic, buf[:len(buf)-4]...)
+	computedMic := genPayloadMIC(dataMic[:], micKey)
 	if !bytes.Equal(computedMic[:], rxMic[:]) {
 		return ErrInvalidMic
 	}
+
+	// LoRaWAN 1.1 JoinNonce is a counter, that must always increase
+	if optNeg {
+		if joinNonceValue(joinNonce) <= joinNonceValue(o.appNonce) && o.appNonce != [3]uint8{} {
+			return ErrInvalidJoinNonce
+		}
+	}
+	o.appNonce = joinNonce
+	o.NetID = netID
+
+	copy(s.DevAddr[:], buf[6:10])
+	s.DLSettings = dlSettings
+	s.RXDelay = buf[11]
+	s.CFList = [16]uint8{}
+	if len(buf) > 16 {
+		copy(s.CFList[:], buf[12:28])
+	}
+
+	if optNeg {
+		o.deriveSessionKeys11(s, nonce)
+		s.Version = Version1_1
+		s.versionInd = CIDRekey
+	} else {
+		o.deriveSessionKeys10(s, key, nonce)
+		s.Version = Version1_0
+		s.versionInd = 0
+	}
+	s.DLSettings &= 0x7F
+
+	// Reset counters
+	s.FCntDown = 0
+	s.FCntUp = 0
+	s.AFCntDown = 0
+	s.RJCount0 = 0
+
+	return nil
+}
+
+// deriveSessionKeys10 derives LoRaWAN 1.0 session keys
+func (o *Otaa) deriveSessionKeys10(s *Session, key [16]uint8, nonce [2]uint8) {
+	block, _ := aes.NewCipher(key[:])
+	buf := make([]byte, 16)
+
 	// Generate NwkSKey
 	// NwkSKey = aes128_encrypt(AppKey, 0x01|AppNonce|NetID|DevNonce|pad16)
 	sKey := []byte{}
 	sKey = append(sKey, 0x01)
 	sKey = append(sKey, o.appNonce[:]...)
 	sKey = append(sKey, o.NetID[:]...)
-	sKey = append(sKey, o.devNonce[:]...)
+	sKey = append(sKey, nonce[:]...)
 	for i := 0; i < 7; i++ {
 		sKey = append(sKey, 0x00) // PAD to 16
 	}
@@ -177,9 +340,33 @@ func (o *Otaa) DecodeJoinAccept(phyPload []uint8, s *Session) error {
 	block.Encrypt(buf, sKey)
 	copy(s.AppSKey[:], buf[0:16])
 
-	// Reset counters
-	s.FCntDown = 0
-	s.FCntUp = 0
+	// A LoRaWAN 1.1 device joined on a LoRaWAN 1.0 network uses a single key
+	s.FNwkSIntKey = s.NwkSKey
+	s.SNwkSIntKey = s.NwkSKey
+	s.NwkSEncKey = s.NwkSKey
+}
 
-	return nil
+// deriveSessionKeys11 derives LoRaWAN 1.1 session keys
+func (o *Otaa) deriveSessionKeys11(s *Session, nonce [2]uint8) {
+	// Key = aes128_encrypt(RootKey, Prefix|JoinNonce|JoinEUI|DevNonce|pad16)
+	sKey := make([]byte, 16)
+	copy(sKey[1:4], o.appNonce[:])
+	copy(sKey[4:12], reverseBytes(o.AppEUI[:]))
+	copy(sKey[12:14], nonce[:])
+
+	derive := func(dst *[16]uint8, root [16]uint8, prefix uint8) {
+		block, _ := aes.NewCipher(root[:])
+		sKey[0] = prefix
+		block.Encrypt(dst[:], sKey)
+	}
+	derive(&s.FNwkSIntKey, o.NwkKey, 0x01)
+	derive(&s.AppSKey, o.AppKey, 0x02)
+	derive(&s.SNwkSIntKey, o.NwkKey, 0x03)
+	derive(&s.NwkSEncKey, o.NwkKey, 0x04)
+	s.NwkSKey = [16]uint8{}
+}
+
+// joinNonceValue returns the value of a little endian JoinNonce
+func joinNonceValue(n [3]uint8) uint32 {
+	return uint32(n[0]) | uint32(n[1])<<8 | uint32(n[2])<<16
 }
@@ -0,0 +1,203 @@
+package lorawan
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidBinaryData       = errors.New("invalid binary data")
+	ErrUnsupportedBinaryFormat = errors.New("unsupported binary format version")
+)
+
+// Flags of the session state
+const (
+	stateAckDownlink = 0x01
+)
+
+// Binary formats versions, stored in the first byte
+const (
+	sessionFormatVersion = 1
+	otaaFormatVersion    = 1
+)
+
+const (
+	// Session length, followed by the variable length regional settings
+	// state
+	sessionBinaryLen = 143
+	otaaBinaryLen    = 62
+)
+
+// MarshalBinary encodes the session state (keys, address, frame counters and
+// network parameters) so that it can be stored in non-volatile memory and
+// restored after a reset or a deep sleep. The ADR, acknowledgement and duty
+// cycle state is included, the duty cycle off time being kept as wall clock
+// time. The state of the regional settings
+// set by UseRegionSettings (channels, data rate, TX power and channel mask
+// set by network) is included.
+func (s *Session) MarshalBinary() ([]byte, error) {
+	var rs []byte
+	if regionSettings != nil {
+		var err error
+		if rs, err = regionSettings.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+
+	b := make([]byte, 0, sessionBinaryLen+len(rs))
+	b = append(b, sessionFormatVersion)
+	b = append(b, s.NwkSKey[:]...)
+	b = append(b, s.AppSKey[:]...)
+	b = append(b, s.DevAddr[:]...)
+	b = appendUint32(b, s.FCntDown)
+	b = appendUint32(b, s.FCntUp)
+	b = append(b, s.CFList[:]...)
+	b = append(b, s.RXDelay, s.DLSettings)
+	b = appendUint32(b, s.Rx2Frequency)
+	b = append(b, s.MaxDutyCycle)
+	b = append(b, s.Version)
+	b = append(b, s.FNwkSIntKey[:]...)
+	b = append(b, s.SNwkSIntKey[:]...)
+	b = append(b, s.NwkSEncKey[:]...)
+	b = appendUint32(b, s.AFCntDown)
+	b = append(b, uint8(s.RJCount0), uint8(s.RJCount0>>8))
+	b = append(b, s.versionInd)
+	var flags uint8
+	if s.ackDownlink {
+		flags |= stateAckDownlink
+	}
+	b = append(b, flags)
+	b = append(b, uint8(s.confFCntDown), uint8(s.confFCntDown>>8))
+	b = append(b, uint8(s.confFCntUp), uint8(s.confFCntUp>>8))
+	b = appendUint32(b, s.adrAckCnt)
+	b = appendTime(b, s.txAvailableAt)
+	b = append(b, uint8(len(rs)), uint8(len(rs)>>8))
+	b = append(b, rs...)
+	return b, nil
+}
+
+// UnmarshalBinary restores a session state encoded by MarshalBinary. The
+// regional settings state, if any, is restored in the regional settings set by
+// UseRegionSettings, which must be the ones of the saved session.
+func (s *Session) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrInvalidBinaryData
+	}
+	if data[0] != sessionFormatVersion {
+		return ErrUnsupportedBinaryFormat
+	}
+	if len(data) < sessionBinaryLen {
+		return ErrInvalidBinaryData
+	}
+
+	rs := data[sessionBinaryLen:]
+	if len(rs) != int(binary.LittleEndian.Uint16(data[sessionBinaryLen-2:])) {
+		return ErrInvalidBinaryData
+	}
+	if len(rs) > 0 {
+		if regionSettings == nil {
+			return ErrUndefinedRegionSettings
+		}
+		if err := regionSettings.UnmarshalBinary(rs); err != nil {
+			return err
+		}
+	}
+
+	b := data[1:]
+	b = b[copy(s.NwkSKey[:], b):]
+	b = b[copy(s.AppSKey[:], b):]
+	b = b[copy(s.DevAddr[:], b):]
+	s.FCntDown = binary.LittleEndian.Uint32(b)
+	s.FCntUp = binary.LittleEndian.Uint32(b[4:])
+	b = b[8:]
+	b = b[copy(s.CFList[:], b):]
+	s.RXDelay = b[0]
+	s.DLSettings = b[1]
+	s.Rx2Frequency = binary.LittleEndian.Uint32(b[2:])
+	s.MaxDutyCycle = b[6]
+	s.Version = b[7]
+	b = b[8:]
+	b = b[copy(s.FNwkSIntKey[:], b):]
+	b = b[copy(s.SNwkSIntKey[:], b):]
+	b = b[copy(s.NwkSEncKey[:], b):]
+	s.AFCntDown = binary.LittleEndian.Uint32(b)
+	s.RJCount0 = binary.LittleEndian.Uint16(b[4:])
+	s.versionInd = b[6]
+	s.ackDownlink = b[7]&stateAckDownlink != 0
+	s.confFCntDown = binary.LittleEndian.Uint16(b[8:])
+	s.confFCntUp = binary.LittleEndian.Uint16(b[10:])
+	s.adrAckCnt = binary.LittleEndian.Uint32(b[12:])
+	s.txAvailableAt = decodeTime(b[16:])
+	return nil
+}
+
+// MarshalBinary encodes the OTAA parameters, including the DevNonce counter
+// that must never be reused with a given AppKey, or NwkKey for LoRaWAN 1.1.
+func (o *Otaa) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, otaaBinaryLen)
+	b = append(b, otaaFormatVersion)
+	b = append(b, o.DevEUI[:]...)
+	b = append(b, o.AppEUI[:]...)
+	b = append(b, o.AppKey[:]...)
+	b = append(b, o.devNonce[:]...)
+	b = append(b, o.appNonce[:]...)
+	b = append(b, o.NetID[:]...)
+	b = append(b, o.Version)
+	b = append(b, o.NwkKey[:]...)
+	b = append(b, uint8(o.rjCount1), uint8(o.rjCount1>>8))
+	b = append(b, uint8(o.nextNonce), uint8(o.nextNonce>>8))
+	return b, nil
+}
+
+// UnmarshalBinary restores OTAA parameters encoded by MarshalBinary
+func (o *Otaa) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrInvalidBinaryData
+	}
+	if data[0] != otaaFormatVersion {
+		return ErrUnsupportedBinaryFormat
+	}
+	if len(data) != otaaBinaryLen {
+		return ErrInvalidBinaryData
+	}
+
+	b := data[1:]
+	b = b[copy(o.DevEUI[:], b):]
+	b = b[copy(o.AppEUI[:], b):]
+	b = b[copy(o.AppKey[:], b):]
+	b = b[copy(o.devNonce[:], b):]
+	b = b[copy(o.appNonce[:], b):]
+	b = b[copy(o.NetID[:], b):]
+	o.Version = b[0]
+	b = b[1:]
+	b = b[copy(o.NwkKey[:], b):]
+	o.rjCount1 = binary.LittleEndian.Uint16(b)
+	o.nextNonce = binary.LittleEndian.Uint16(b[2:])
+	return nil
+}
+
+// appendUint32 appends a little endian uint32 to a byte slice
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appendTime appends a time as Unix nanoseconds, 0 for the zero time
+func appendTime(b []byte, t time.Time) []byte {
+	var buf [8]byte
+	if !t.IsZero() {
+		binary.LittleEndian.PutUint64(buf[:], uint64(t.UnixNano()))
+	}
+	return append(b, buf[:]...)
+}
+
+// decodeTime decodes a time encoded by appendTime
+func decodeTime(b []byte) time.Time {
+	ns := int64(binary.LittleEndian.Uint64(b))
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
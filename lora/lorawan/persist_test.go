@@ -0,0 +1,147 @@
+package lorawan
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/lora"
+	"tinygo.org/x/drivers/lora/lorawan/region"
+)
+
+func TestSessionMarshalBinary(t *testing.T) {
+	c := qt.New(t)
+
+	s := &Session{
+		DevAddr:      [4]uint8{0x04, 0x03, 0x02, 0x01},
+		FCntDown:     12,
+		FCntUp:       0x12345,
+		RXDelay:      5,
+		DLSettings:   0x13,
+		Rx2Frequency: 869525000,
+		MaxDutyCycle: 7,
+	}
+	s.SetNwkSKey([]uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	s.SetAppSKey([]uint8{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+	s.CFList[3] = 0xAA
+
+	// ADR, acknowledgement and duty cycle state
+	s.adrAckCnt = 70
+	s.ackDownlink = true
+	s.confFCntDown = 11
+	s.confFCntUp = 0x2345
+	s.txAvailableAt = time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	data, err := s.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(data, qt.HasLen, sessionBinaryLen)
+
+	var r Session
+	c.Assert(r.UnmarshalBinary(data), qt.IsNil)
+	c.Assert(r.FCntUp, qt.Equals, s.FCntUp)
+	c.Assert(r.GetNwkSKey(), qt.Equals, s.GetNwkSKey())
+	c.Assert(r.adrAckCnt, qt.Equals, uint32(70))
+	c.Assert(r.ackDownlink, qt.IsTrue)
+	c.Assert(r.confFCntDown, qt.Equals, uint16(11))
+	c.Assert(r.confFCntUp, qt.Equals, uint16(0x2345))
+	c.Assert(r.txAvailableAt.Equal(s.txAvailableAt), qt.IsTrue)
+	again, err := r.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(again, qt.DeepEquals, data)
+
+	c.Assert(r.UnmarshalBinary(data[:20]), qt.Equals, ErrInvalidBinaryData)
+	data[0] = 0xFF
+	c.Assert(r.UnmarshalBinary(data), qt.Equals, ErrUnsupportedBinaryFormat)
+}
+
+func TestSessionMarshalBinaryRegion(t *testing.T) {
+	c := qt.New(t)
+	UseRegionSettings(region.EU868())
+	defer UseRegionSettings(nil)
+	s := &Session{FCntUp: 7}
+
+	// NewChannelReq: channel 3 at 867.1 MHz, DR0 to DR5, then LinkADRReq:
+	// DR5, TX power index 2, channels 0 and 3
+	s.processMACCommands([]uint8{
+		CIDNewChannel, 3, 0x18, 0x4F, 0x84, 0x50,
+		CIDLinkADR, 0x52, 0x09, 0x00, 0x00,
+	})
+	c.Assert(s.macAnswers, qt.DeepEquals, []uint8{CIDNewChannel, 0x03, CIDLinkADR, 0x07})
+
+	data, err := s.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+
+	// Restored after a reset, with default regional settings
+	UseRegionSettings(region.EU868())
+	var r Session
+	c.Assert(r.UnmarshalBinary(data), qt.IsNil)
+	c.Assert(r.FCntUp, qt.Equals, s.FCntUp)
+	c.Assert(regionSettings.UplinkDataRate(), qt.Equals, uint8(5))
+	for _, freq := range []uint32{867100000, lora.MHz_868_1, 867100000} {
+		ch := regionSettings.UplinkChannel()
+		c.Assert(ch.Frequency(), qt.Equals, freq)
+		c.Assert(ch.SpreadingFactor(), qt.Equals, uint8(lora.SpreadingFactor7))
+		c.Assert(ch.TxPowerDBm(), qt.Equals, int8(region.EU868_DEFAULT_TX_POWER_DBM-4))
+	}
+	again, err := r.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(again, qt.DeepEquals, data)
+
+	// The state of another region is rejected, and needs regional settings
+	UseRegionSettings(region.US915())
+	c.Assert(r.UnmarshalBinary(data), qt.Equals, region.ErrInvalidState)
+	UseRegionSettings(nil)
+	c.Assert(r.UnmarshalBinary(data), qt.Equals, ErrUndefinedRegionSettings)
+}
+
+func TestOtaaMarshalBinary(t *testing.T) {
+	c := qt.New(t)
+
+	o := &Otaa{}
+	o.Set([]uint8{1, 2, 3, 4, 5, 6, 7, 8},
+		[]uint8{8, 7, 6, 5, 4, 3, 2, 1},
+		[]uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	o.Init()
+	_, err := o.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+
+	data, err := o.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(data, qt.HasLen, otaaBinaryLen)
+
+	var r Otaa
+	c.Assert(r.UnmarshalBinary(data), qt.IsNil)
+	c.Assert(r.devNonce, qt.Equals, o.devNonce)
+	c.Assert(r.GetAppKey(), qt.Equals, o.GetAppKey())
+
+	// Restored DevNonce is used as a counter
+	r.Init()
+	_, err = r.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	o.incrementDevNonce()
+	c.Assert(r.devNonce, qt.Equals, o.devNonce)
+}
+
+func TestOtaaMarshalBinary11(t *testing.T) {
+	c := qt.New(t)
+
+	// LoRaWAN 1.1 DevNonce is a counter from 0
+	o := testOtaa(Version1_1)
+	for i := uint8(0); i < 2; i++ {
+		req, err := o.GenerateJoinRequest()
+		c.Assert(err, qt.IsNil)
+		c.Assert(req[17:19], qt.DeepEquals, []uint8{i, 0})
+	}
+
+	data, err := o.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(data, qt.HasLen, otaaBinaryLen)
+
+	// The counter goes on after a reset
+	var r Otaa
+	c.Assert(r.UnmarshalBinary(data), qt.IsNil)
+	r.Init()
+	req, err := r.GenerateJoinRequest()
+	c.Assert(err, qt.IsNil)
+	c.Assert(req[17:19], qt.DeepEquals, []uint8{2, 0})
+}
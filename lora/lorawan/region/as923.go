@@ -0,0 +1,152 @@
+package region
+
+import "tinygo.org/x/drivers/lora"
+
+const (
+	AS923_DEFAULT_PREAMBLE_LEN = 8
+	AS923_DEFAULT_TX_POWER_DBM = 16
+	AS923_RX2_DATA_RATE        = 2
+	AS923_MIN_FREQUENCY        = 915000000
+	AS923_MAX_FREQUENCY        = 928000000
+	AS923_MAX_CHANNELS         = 16
+	AS923_MAX_TX_POWER_INDEX   = 7
+
+	// Frequency offsets of AS923 groups, relative to AS923-1 default channels
+	AS923_1_FREQUENCY_OFFSET = 0
+	AS923_2_FREQUENCY_OFFSET = -1800000
+	AS923_3_FREQUENCY_OFFSET = -6600000
+	AS923_4_FREQUENCY_OFFSET = -5900000
+)
+
+type ChannelAS struct {
+	channel
+}
+
+func (c *ChannelAS) Next() bool {
+	return false
+}
+
+type SettingsAS923 struct {
+	settings
+	rx2Frequency uint32
+}
+
+// AS923_1 returns the settings of AS923-1 channel plan (AS923 group 1)
+func AS923_1() *SettingsAS923 {
+	return as923(AS923_1_FREQUENCY_OFFSET)
+}
+
+// AS923_2 returns the settings of AS923-2 channel plan (AS923 group 2)
+func AS923_2() *SettingsAS923 {
+	return as923(AS923_2_FREQUENCY_OFFSET)
+}
+
+// AS923_3 returns the settings of AS923-3 channel plan (AS923 group 3)
+func AS923_3() *SettingsAS923 {
+	return as923(AS923_3_FREQUENCY_OFFSET)
+}
+
+// AS923_4 returns the settings of AS923-4 channel plan (AS923 group 4)
+func AS923_4() *SettingsAS923 {
+	return as923(AS923_4_FREQUENCY_OFFSET)
+}
+
+// as923 returns AS923 settings, with default channels and RX2 frequency
+// shifted by the group frequency offset
+func as923(offset int32) *SettingsAS923 {
+	ch0 := uint32(int32(lora.MHz_923_2) + offset)
+	ch1 := uint32(int32(lora.MHz_923_4) + offset)
+
+	// Dwell time is limited by default, so uplinks start at DR2
+	return &SettingsAS923{rx2Frequency: ch0, settings: settings{
+		joinRequestChannel: &ChannelAS{channel: channel{ch0,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			AS923_DEFAULT_PREAMBLE_LEN,
+			AS923_DEFAULT_TX_POWER_DBM}},
+		joinAcceptChannel: &ChannelAS{channel: channel{ch0,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			AS923_DEFAULT_PREAMBLE_LEN,
+			AS923_DEFAULT_TX_POWER_DBM}},
+		uplinkChannel: &ChannelAS{channel: channel{ch0,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			AS923_DEFAULT_PREAMBLE_LEN,
+			AS923_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelAS{channel: channel{ch0,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			AS923_DEFAULT_PREAMBLE_LEN,
+			AS923_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelAS{channel: channel{ch0,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			AS923_DEFAULT_PREAMBLE_LEN,
+			AS923_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: AS923_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51},  // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51},  // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 115}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115},  // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242},  // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},  // DR5
+			{lora.SpreadingFactor7, lora.Bandwidth_250_0, 242},  // DR6
+		},
+		dwellMaxPayloads: []uint8{0, 0, 11, 53, 125, 242, 242},
+		channelPlan: channelPlan{
+			channels:          euChannels(AS923_MAX_CHANNELS, ch0, ch1),
+			defaultChannels:   2,
+			dataRate:          2,
+			maxTxPower:        AS923_MAX_TX_POWER_INDEX,
+			defaultTxPower:    AS923_DEFAULT_TX_POWER_DBM,
+			minFrequency:      AS923_MIN_FREQUENCY,
+			maxFrequency:      AS923_MAX_FREQUENCY,
+			txParamSetup:      true,
+			uplinkDwellTime:   true,
+			downlinkDwellTime: true,
+		},
+	}}
+}
+
+// Rx1Channel returns the RX1 receive window channel: same frequency as the
+// uplink, data rate shifted by the RX1 data rate offset
+func (r *SettingsAS923) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	minDR := uint8(0)
+	if r.downlinkDwellTime {
+		minDR = 2
+	}
+	return r.setDownlinkChannel(r.rx1Channel, r.downlinkFrequency(uplink.Frequency()), rx1ShiftedDataRate(dr, rx1DROffset, minDR, 5))
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsAS923) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, r.rx2Frequency, rx2DataRate)
+}
+
+// rx1ShiftedDataRate applies the RX1 data rate offset of AS923 like regions,
+// where offsets 6 and 7 raise the downlink data rate by 1 and 2
+func rx1ShiftedDataRate(dr, rx1DROffset, minDR, maxDR uint8) uint8 {
+	switch {
+	case rx1DROffset > 5:
+		dr += rx1DROffset - 5
+	case dr > rx1DROffset:
+		dr -= rx1DROffset
+	default:
+		dr = 0
+	}
+	if dr < minDR {
+		return minDR
+	}
+	if dr > maxDR {
+		return maxDR
+	}
+	return dr
+}
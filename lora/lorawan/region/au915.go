@@ -5,6 +5,13 @@ import "tinygo.org/x/drivers/lora"
 const (
 	AU915_DEFAULT_PREAMBLE_LEN = 8
 	AU915_DEFAULT_TX_POWER_DBM = 20
+	AU915_RX2_DATA_RATE        = 8
+	AU915_RX2_FREQUENCY        = lora.MHz_923_3
+	AU915_FIRST_CHANNEL_125    = 915200000
+	AU915_FIRST_CHANNEL_500    = 915900000
+	AU915_MIN_FREQUENCY        = 915000000
+	AU915_MAX_FREQUENCY        = 928000000
+	AU915_MAX_TX_POWER_INDEX   = 14
 )
 
 type ChannelAU struct {
@@ -39,9 +46,61 @@ func AU915() *SettingsAU915 {
 			lora.CodingRate4_5,
 			AU915_DEFAULT_PREAMBLE_LEN,
 			AU915_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelAU{channel: channel{lora.MHz_923_3,
+			lora.Bandwidth_500_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			AU915_DEFAULT_PREAMBLE_LEN,
+			AU915_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelAU{channel: channel{AU915_RX2_FREQUENCY,
+			lora.Bandwidth_500_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			AU915_DEFAULT_PREAMBLE_LEN,
+			AU915_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: AU915_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+			{lora.SpreadingFactor8, lora.Bandwidth_500_0, 242}, // DR6
+			{}, // DR7: LR-FHSS, unsupported
+			{lora.SpreadingFactor12, lora.Bandwidth_500_0, 53},  // DR8
+			{lora.SpreadingFactor11, lora.Bandwidth_500_0, 129}, // DR9
+			{lora.SpreadingFactor10, lora.Bandwidth_500_0, 242}, // DR10
+			{lora.SpreadingFactor9, lora.Bandwidth_500_0, 242},  // DR11
+			{lora.SpreadingFactor8, lora.Bandwidth_500_0, 242},  // DR12
+			{lora.SpreadingFactor7, lora.Bandwidth_500_0, 242},  // DR13
+		},
+		channelPlan: channelPlan{
+			channels:       fixedChannels(AU915_FIRST_CHANNEL_125, AU915_FIRST_CHANNEL_500, 5, 6, 1),
+			fixedPlan:      true,
+			wideChannels:   8,
+			dataRate:       3,
+			maxTxPower:     AU915_MAX_TX_POWER_INDEX,
+			defaultTxPower: AU915_DEFAULT_TX_POWER_DBM,
+			minFrequency:   AU915_MIN_FREQUENCY,
+			maxFrequency:   AU915_MAX_FREQUENCY,
+		},
 	}}
 }
 
+// Rx1Channel returns the RX1 receive window channel: one of the 8 downlink
+// channels, with data rate derived from the uplink one
+func (r *SettingsAU915) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	freq := rx1Frequency(uplink, AU915_FIRST_CHANNEL_125, AU915_FIRST_CHANNEL_500)
+	return r.setDownlinkChannel(r.rx1Channel, freq, rx1DataRate(dr+8, rx1DROffset))
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsAU915) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, AU915_RX2_FREQUENCY, rx2DataRate)
+}
+
 func Next(c *ChannelAU) bool {
 	return false
 }
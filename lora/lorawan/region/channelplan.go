@@ -0,0 +1,315 @@
+package region
+
+// plannedChannel is an uplink channel of a regional channel plan
+type plannedChannel struct {
+	frequency   uint32 // Uplink frequency, 0 if channel is undefined
+	dlFrequency uint32 // RX1 frequency when set by DlChannelReq, else 0
+	minDR       uint8
+	maxDR       uint8
+	enabled     bool
+}
+
+// channelPlan holds the channels usable for uplinks, as well as the uplink
+// data rate and TX power, as configured by the network
+type channelPlan struct {
+	channels        []plannedChannel
+	defaultChannels int  // Number of channels that cannot be modified
+	fixedPlan       bool // Channels are fixed (US915 like), not defined by network
+	wideChannels    int  // Number of 500 kHz channels ending a fixed plan
+	lastChannel     int
+	dataRate        uint8
+	txPower         uint8 // TX power index
+	maxTxPower      uint8 // Highest valid TX power index
+	defaultTxPower  int8  // TX power (dBm) of index 0
+	minFrequency    uint32
+	maxFrequency    uint32
+
+	// Duty cycle and dwell time limitations
+	subBands          []subBand
+	txParamSetup      bool // TxParamSetupReq is supported
+	uplinkDwellTime   bool
+	downlinkDwellTime bool
+}
+
+// UplinkChannel returns the channel to use for next uplink. Enabled channels
+// supporting current data rate are used in turn.
+func (r *settings) UplinkChannel() Channel {
+	if len(r.channels) == 0 {
+		return r.uplinkChannel
+	}
+
+	for i := 1; i <= len(r.channels); i++ {
+		n := (r.lastChannel + i) % len(r.channels)
+		if r.usable(&r.channels[n], r.dataRate) {
+			r.lastChannel = n
+			r.uplinkChannel.SetFrequency(r.channels[n].frequency)
+			break
+		}
+	}
+	if dr, ok := r.DataRate(r.dataRate); ok {
+		r.uplinkChannel.SetSpreadingFactor(dr.SpreadingFactor)
+		r.uplinkChannel.SetBandwidth(dr.Bandwidth)
+	}
+	r.uplinkChannel.SetTxPowerDBm(r.txPowerDBm(r.txPower))
+
+	return r.uplinkChannel
+}
+
+// UplinkChannelIndex returns the index of the last uplink channel
+func (r *settings) UplinkChannelIndex() uint8 {
+	return uint8(r.lastChannel)
+}
+
+// UplinkDataRate returns the data rate index used for uplinks
+func (r *settings) UplinkDataRate() uint8 {
+	return r.dataRate
+}
+
+// usable returns true if channel is enabled and supports given data rate
+func (r *settings) usable(c *plannedChannel, dr uint8) bool {
+	return c.enabled && c.frequency != 0 && dr >= c.minDR && dr <= c.maxDR
+}
+
+// txPowerDBm converts a TX power index into dBm. Index 0 is the region
+// default TX power, every next index lowers it by 2 dB.
+func (r *settings) txPowerDBm(index uint8) int8 {
+	return r.defaultTxPower - 2*int8(index)
+}
+
+// downlinkFrequency returns the RX1 frequency of the last uplink channel,
+// when it has been redefined by network
+func (r *settings) downlinkFrequency(uplinkFreq uint32) uint32 {
+	if len(r.channels) == 0 {
+		return uplinkFreq
+	}
+	c := &r.channels[r.lastChannel]
+	if c.frequency == uplinkFreq && c.dlFrequency != 0 {
+		return c.dlFrequency
+	}
+	return uplinkFreq
+}
+
+// ValidFrequency returns true if frequency is within the regional band
+func (r *settings) ValidFrequency(freq uint32) bool {
+	return freq >= r.minFrequency && freq <= r.maxFrequency
+}
+
+// ApplyLinkADR applies the content of a LinkADRReq block: data rate and TX
+// power (0x0F keeps current value), and a set of ChMaskCntl/ChMask pairs.
+// Nothing is changed unless all parameters are accepted.
+func (r *settings) ApplyLinkADR(dataRate, txPower uint8, chMaskCntl []uint8, chMask []uint16) (powerOK, dataRateOK, chMaskOK bool) {
+	enabled := make([]bool, len(r.channels))
+	for i := range r.channels {
+		enabled[i] = r.channels[i].enabled
+	}
+
+	chMaskOK = len(chMaskCntl) == len(chMask)
+	for i := 0; chMaskOK && i < len(chMaskCntl); i++ {
+		chMaskOK = r.applyChMask(enabled, chMaskCntl[i], chMask[i])
+	}
+
+	// At least one channel must remain enabled once the whole block applied
+	if chMaskOK {
+		chMaskOK = false
+		for i := range enabled {
+			chMaskOK = chMaskOK || enabled[i]
+		}
+	}
+
+	if dataRate == 0x0F {
+		dataRate = r.dataRate
+	}
+	if d, ok := r.DataRate(dataRate); ok && d.MaxPayload > 0 {
+		for i := range r.channels {
+			c := r.channels[i]
+			c.enabled = enabled[i]
+			if r.usable(&c, dataRate) {
+				dataRateOK = true
+				break
+			}
+		}
+	}
+
+	if txPower == 0x0F {
+		txPower = r.txPower
+	}
+	powerOK = txPower <= r.maxTxPower
+
+	if powerOK && dataRateOK && chMaskOK {
+		for i := range r.channels {
+			r.channels[i].enabled = enabled[i]
+		}
+		r.dataRate = dataRate
+		r.txPower = txPower
+	}
+	return
+}
+
+// applyChMask updates the enabled channels set according to one
+// ChMaskCntl/ChMask pair
+func (r *settings) applyChMask(enabled []bool, cntl uint8, mask uint16) bool {
+	first, count := 0, 16
+	narrow := len(r.channels) - r.wideChannels
+	switch {
+	case !r.fixedPlan && cntl == 0:
+	case !r.fixedPlan && cntl == 6:
+		// All defined channels ON
+		for i := range r.channels {
+			enabled[i] = r.channels[i].frequency != 0
+		}
+		return true
+	case r.fixedPlan && int(cntl) < narrow/16:
+		first = 16 * int(cntl)
+	case r.fixedPlan && r.wideChannels > 0 && cntl == 4:
+		first, count = narrow, r.wideChannels
+	case r.fixedPlan && r.wideChannels > 0 && (cntl == 6 || cntl == 7):
+		// All 125 kHz channels ON (6) or OFF (7), mask applies to 500 kHz ones
+		for i := 0; i < narrow; i++ {
+			enabled[i] = cntl == 6
+		}
+		first, count = narrow, r.wideChannels
+	case r.fixedPlan && r.wideChannels == 0 && cntl == 6:
+		// All channels ON (CN470 like plans)
+		for i := range enabled {
+			enabled[i] = true
+		}
+		return true
+	default:
+		return false
+	}
+
+	for i := 0; i < count; i++ {
+		n := first + i
+		on := mask&(1<<i) != 0
+		if n >= len(r.channels) {
+			if on {
+				return false
+			}
+			continue
+		}
+		if on && r.channels[n].frequency == 0 {
+			return false
+		}
+		enabled[n] = on
+	}
+	return true
+}
+
+// ADRBackoff performs one step of the ADR back-off, when network does not
+// answer ADR acknowledgement requests: default TX power is restored first,
+// then data rate is lowered, and finally default channels are enabled.
+func (r *settings) ADRBackoff() {
+	switch {
+	case r.txPower != 0:
+		r.txPower = 0
+	case r.lowerDataRate() != r.dataRate:
+		r.dataRate = r.lowerDataRate()
+	case r.fixedPlan:
+		for i := range r.channels {
+			r.channels[i].enabled = true
+		}
+	default:
+		for i := 0; i < r.defaultChannels; i++ {
+			r.channels[i].enabled = true
+		}
+	}
+}
+
+// ADRDefaults returns true if uplinks use default TX power and the lowest
+// data rate, so that ADR back-off cannot go further
+func (r *settings) ADRDefaults() bool {
+	return r.txPower == 0 && r.lowerDataRate() == r.dataRate
+}
+
+// LowerDataRate lowers the uplink data rate to the highest one below current
+// one that is supported by an enabled channel and carries payloadLen bytes, as
+// done between retransmissions of confirmed uplinks. It returns false, and
+// keeps current data rate, if there is none.
+func (r *settings) LowerDataRate(payloadLen int) bool {
+	dr := r.lowerDataRate()
+	if dr == r.dataRate {
+		return false
+	}
+	if d, ok := r.DataRate(dr); !ok || int(d.MaxPayload) < payloadLen {
+		return false
+	}
+	r.dataRate = dr
+	return true
+}
+
+// lowerDataRate returns the highest uplink data rate below current one, and
+// supported by an enabled channel. Current data rate is returned if none.
+func (r *settings) lowerDataRate() uint8 {
+	for dr := int(r.dataRate) - 1; dr >= 0; dr-- {
+		if d, ok := r.DataRate(uint8(dr)); !ok || d.MaxPayload == 0 {
+			continue
+		}
+		for i := range r.channels {
+			if r.usable(&r.channels[i], uint8(dr)) {
+				return uint8(dr)
+			}
+		}
+	}
+	return r.dataRate
+}
+
+// SetChannel creates, modifies or deletes (freq = 0) an uplink channel, as
+// requested by a NewChannelReq
+func (r *settings) SetChannel(index uint8, freq uint32, minDR, maxDR uint8) (freqOK, dataRateOK bool) {
+	if r.fixedPlan || int(index) < r.defaultChannels || int(index) >= len(r.channels) {
+		return false, false
+	}
+
+	freqOK = freq == 0 || r.ValidFrequency(freq)
+	_, minOK := r.DataRate(minDR)
+	_, maxOK := r.DataRate(maxDR)
+	dataRateOK = minOK && maxOK && minDR <= maxDR
+
+	if freqOK && dataRateOK {
+		r.channels[index] = plannedChannel{
+			frequency: freq,
+			minDR:     minDR,
+			maxDR:     maxDR,
+			enabled:   freq != 0,
+		}
+	}
+	return
+}
+
+// SetDownlinkFrequency sets the RX1 frequency of an uplink channel, as
+// requested by a DlChannelReq
+func (r *settings) SetDownlinkFrequency(index uint8, freq uint32) (freqOK, uplinkExists bool) {
+	if r.fixedPlan || int(index) >= len(r.channels) {
+		return false, false
+	}
+
+	freqOK = r.ValidFrequency(freq)
+	uplinkExists = r.channels[index].frequency != 0
+	if freqOK && uplinkExists {
+		r.channels[index].dlFrequency = freq
+	}
+	return
+}
+
+// fixedChannels returns the 64 x 125 kHz + 8 x 500 kHz channels of US915
+// like channel plans, with the given 8 + 1 channels sub-band enabled
+func fixedChannels(base125, base500 uint32, maxDR125, dr500 uint8, subBand int) []plannedChannel {
+	channels := make([]plannedChannel, 72)
+	for i := 0; i < 64; i++ {
+		channels[i] = plannedChannel{
+			frequency: base125 + uint32(i)*US915_FREQUENCY_INCREMENT_DR_0,
+			minDR:     0,
+			maxDR:     maxDR125,
+			enabled:   i/8 == subBand,
+		}
+	}
+	for i := 0; i < 8; i++ {
+		channels[64+i] = plannedChannel{
+			frequency: base500 + uint32(i)*US915_FREQUENCY_INCREMENT_DR_4,
+			minDR:     dr500,
+			maxDR:     dr500,
+			enabled:   i == subBand,
+		}
+	}
+	return channels
+}
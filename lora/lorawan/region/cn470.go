@@ -0,0 +1,121 @@
+package region
+
+import "tinygo.org/x/drivers/lora"
+
+const (
+	CN470_DEFAULT_PREAMBLE_LEN   = 8
+	CN470_DEFAULT_TX_POWER_DBM   = 19
+	CN470_FREQUENCY_INCREMENT    = 200000
+	CN470_RX2_FREQUENCY          = lora.MHz_505_3
+	CN470_RX2_DATA_RATE          = 0
+	CN470_MIN_FREQUENCY          = 470000000
+	CN470_MAX_FREQUENCY          = 510000000
+	CN470_MAX_CHANNELS           = 96
+	CN470_DOWNLINK_CHANNELS      = 48
+	CN470_MAX_TX_POWER_INDEX     = 7
+	CN470_FIRST_UPLINK_CHANNEL   = lora.MHz_470_3
+	CN470_FIRST_DOWNLINK_CHANNEL = lora.MHz_500_3
+)
+
+type ChannelCN struct {
+	channel
+}
+
+func (c *ChannelCN) Next() bool {
+	return false
+}
+
+type SettingsCN470 struct {
+	settings
+}
+
+// CN470Legacy returns the settings of the legacy CN470-510 channel plan, of
+// LoRaWAN Regional Parameters v1.1rB and before: 96 uplink and 48 downlink
+// channels, RX2 on 505.3 MHz. The 20 MHz and 26 MHz plans that replaced it in
+// RP002-1.0.3 are not supported.
+func CN470Legacy() *SettingsCN470 {
+	return &SettingsCN470{settings: settings{
+		joinRequestChannel: &ChannelCN{channel: channel{CN470_FIRST_UPLINK_CHANNEL,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			CN470_DEFAULT_PREAMBLE_LEN,
+			CN470_DEFAULT_TX_POWER_DBM}},
+		joinAcceptChannel: &ChannelCN{channel: channel{CN470_FIRST_DOWNLINK_CHANNEL,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			CN470_DEFAULT_PREAMBLE_LEN,
+			CN470_DEFAULT_TX_POWER_DBM}},
+		uplinkChannel: &ChannelCN{channel: channel{CN470_FIRST_UPLINK_CHANNEL,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			CN470_DEFAULT_PREAMBLE_LEN,
+			CN470_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelCN{channel: channel{CN470_FIRST_DOWNLINK_CHANNEL,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			CN470_DEFAULT_PREAMBLE_LEN,
+			CN470_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelCN{channel: channel{CN470_RX2_FREQUENCY,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			CN470_DEFAULT_PREAMBLE_LEN,
+			CN470_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: CN470_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+		},
+		channelPlan: channelPlan{
+			channels:       cn470Channels(0),
+			fixedPlan:      true,
+			dataRate:       3,
+			maxTxPower:     CN470_MAX_TX_POWER_INDEX,
+			defaultTxPower: CN470_DEFAULT_TX_POWER_DBM,
+			minFrequency:   CN470_MIN_FREQUENCY,
+			maxFrequency:   CN470_MAX_FREQUENCY,
+		},
+	}}
+}
+
+// cn470Channels returns the 96 x 125 kHz uplink channels of CN470 legacy
+// plan, with the given 8 channels sub-band enabled
+func cn470Channels(subBand int) []plannedChannel {
+	channels := make([]plannedChannel, CN470_MAX_CHANNELS)
+	for i := range channels {
+		channels[i] = plannedChannel{
+			frequency: CN470_FIRST_UPLINK_CHANNEL + uint32(i)*CN470_FREQUENCY_INCREMENT,
+			minDR:     0,
+			maxDR:     5,
+			enabled:   i/8 == subBand,
+		}
+	}
+	return channels
+}
+
+// Rx1Channel returns the RX1 receive window channel: one of the 48 downlink
+// channels, with data rate lowered by the RX1 data rate offset
+func (r *SettingsCN470) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	if dr > rx1DROffset {
+		dr -= rx1DROffset
+	} else {
+		dr = 0
+	}
+	n := (uplink.Frequency() - CN470_FIRST_UPLINK_CHANNEL) / CN470_FREQUENCY_INCREMENT
+	freq := CN470_FIRST_DOWNLINK_CHANNEL + (n%CN470_DOWNLINK_CHANNELS)*CN470_FREQUENCY_INCREMENT
+	return r.setDownlinkChannel(r.rx1Channel, freq, dr)
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsCN470) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, CN470_RX2_FREQUENCY, rx2DataRate)
+}
@@ -0,0 +1,66 @@
+package region
+
+import "time"
+
+// UPLINK_DWELL_TIME is the maximum uplink duration when dwell time is limited
+const UPLINK_DWELL_TIME = 400 * time.Millisecond
+
+// maxEIRP gives the MaxEIRP (dBm) encoded in TxParamSetupReq
+var maxEIRP = [16]int8{8, 10, 12, 13, 14, 16, 18, 20, 21, 24, 26, 27, 29, 30, 33, 36}
+
+// subBand is a frequency range sharing a duty cycle limit
+type subBand struct {
+	minFrequency uint32
+	maxFrequency uint32
+	dutyCycle    uint16    // Duty cycle limit, as 1/dutyCycle
+	availableAt  time.Time // End of the off time following last transmission
+}
+
+// subBand returns the sub-band containing given frequency, nil if the
+// frequency is not subject to duty cycle limitation
+func (r *settings) subBand(freq uint32) *subBand {
+	for i := range r.subBands {
+		b := &r.subBands[i]
+		if freq >= b.minFrequency && freq <= b.maxFrequency {
+			return b
+		}
+	}
+	return nil
+}
+
+// DutyCycleWait returns the time to wait, from now, before transmitting on
+// given frequency without exceeding its sub-band duty cycle
+func (r *settings) DutyCycleWait(freq uint32, now time.Time) time.Duration {
+	if b := r.subBand(freq); b != nil && now.Before(b.availableAt) {
+		return b.availableAt.Sub(now)
+	}
+	return 0
+}
+
+// RegisterTx accounts a transmission started at given time in the duty cycle
+// of the frequency sub-band
+func (r *settings) RegisterTx(freq uint32, start time.Time, airTime time.Duration) {
+	if b := r.subBand(freq); b != nil {
+		b.availableAt = start.Add(airTime * time.Duration(b.dutyCycle))
+	}
+}
+
+// MaxDwellTime returns the maximum duration of an uplink, 0 if not limited
+func (r *settings) MaxDwellTime() time.Duration {
+	if r.uplinkDwellTime {
+		return UPLINK_DWELL_TIME
+	}
+	return 0
+}
+
+// SetTxParams applies a TxParamSetupReq. It returns false if the region does
+// not support it.
+func (r *settings) SetTxParams(uplinkDwellTime, downlinkDwellTime bool, maxEIRPIndex uint8) bool {
+	if !r.txParamSetup {
+		return false
+	}
+	r.uplinkDwellTime = uplinkDwellTime
+	r.downlinkDwellTime = downlinkDwellTime
+	r.defaultTxPower = maxEIRP[maxEIRPIndex&0x0F]
+	return true
+}
@@ -0,0 +1,92 @@
+package region
+
+import "tinygo.org/x/drivers/lora"
+
+const (
+	EU433_DEFAULT_PREAMBLE_LEN = 8
+	EU433_DEFAULT_TX_POWER_DBM = 12
+	EU433_RX2_FREQUENCY        = lora.MHz_434_665
+	EU433_RX2_DATA_RATE        = 0
+	EU433_MIN_FREQUENCY        = lora.MHz_433_175
+	EU433_MAX_FREQUENCY        = lora.MHz_434_665
+	EU433_MAX_CHANNELS         = 16
+	EU433_MAX_TX_POWER_INDEX   = 5
+)
+
+type SettingsEU433 struct {
+	settings
+}
+
+func EU433() *SettingsEU433 {
+	return &SettingsEU433{settings: settings{
+		joinRequestChannel: &ChannelEU{channel: channel{lora.MHz_433_175,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			EU433_DEFAULT_PREAMBLE_LEN,
+			EU433_DEFAULT_TX_POWER_DBM}},
+		joinAcceptChannel: &ChannelEU{channel: channel{lora.MHz_433_175,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			EU433_DEFAULT_PREAMBLE_LEN,
+			EU433_DEFAULT_TX_POWER_DBM}},
+		uplinkChannel: &ChannelEU{channel: channel{lora.MHz_433_175,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			EU433_DEFAULT_PREAMBLE_LEN,
+			EU433_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelEU{channel: channel{lora.MHz_433_175,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			EU433_DEFAULT_PREAMBLE_LEN,
+			EU433_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelEU{channel: channel{EU433_RX2_FREQUENCY,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			EU433_DEFAULT_PREAMBLE_LEN,
+			EU433_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: EU433_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+			{lora.SpreadingFactor7, lora.Bandwidth_250_0, 242}, // DR6
+		},
+		channelPlan: channelPlan{
+			channels:        euChannels(EU433_MAX_CHANNELS, lora.MHz_433_175, lora.MHz_433_375, lora.MHz_433_575),
+			defaultChannels: 3,
+			dataRate:        3,
+			maxTxPower:      EU433_MAX_TX_POWER_INDEX,
+			defaultTxPower:  EU433_DEFAULT_TX_POWER_DBM,
+			minFrequency:    EU433_MIN_FREQUENCY,
+			maxFrequency:    EU433_MAX_FREQUENCY,
+			subBands: []subBand{
+				{minFrequency: EU433_MIN_FREQUENCY, maxFrequency: EU433_MAX_FREQUENCY, dutyCycle: 100}, // 1 %
+			},
+		},
+	}}
+}
+
+// Rx1Channel returns the RX1 receive window channel: same frequency as the
+// uplink, data rate lowered by the RX1 data rate offset
+func (r *SettingsEU433) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	if dr > rx1DROffset {
+		dr -= rx1DROffset
+	} else {
+		dr = 0
+	}
+	return r.setDownlinkChannel(r.rx1Channel, r.downlinkFrequency(uplink.Frequency()), dr)
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsEU433) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, EU433_RX2_FREQUENCY, rx2DataRate)
+}
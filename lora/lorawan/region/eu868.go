@@ -5,6 +5,12 @@ import "tinygo.org/x/drivers/lora"
 const (
 	EU868_DEFAULT_PREAMBLE_LEN = 8
 	EU868_DEFAULT_TX_POWER_DBM = 20
+	EU868_RX2_FREQUENCY        = lora.MHz_869_525
+	EU868_RX2_DATA_RATE        = 0
+	EU868_MIN_FREQUENCY        = 863000000
+	EU868_MAX_FREQUENCY        = 870000000
+	EU868_MAX_CHANNELS         = 16
+	EU868_MAX_TX_POWER_INDEX   = 7
 )
 
 type ChannelEU struct {
@@ -39,5 +45,71 @@ func EU868() *SettingsEU868 {
 			lora.CodingRate4_7,
 			EU868_DEFAULT_PREAMBLE_LEN,
 			EU868_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelEU{channel: channel{lora.MHz_868_1,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			EU868_DEFAULT_PREAMBLE_LEN,
+			EU868_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelEU{channel: channel{EU868_RX2_FREQUENCY,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			EU868_DEFAULT_PREAMBLE_LEN,
+			EU868_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: EU868_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+			{lora.SpreadingFactor7, lora.Bandwidth_250_0, 242}, // DR6
+		},
+		channelPlan: channelPlan{
+			channels:        euChannels(EU868_MAX_CHANNELS, lora.MHz_868_1, lora.MHz_868_3, lora.MHz_868_5),
+			defaultChannels: 3,
+			dataRate:        3,
+			maxTxPower:      EU868_MAX_TX_POWER_INDEX,
+			defaultTxPower:  EU868_DEFAULT_TX_POWER_DBM,
+			minFrequency:    EU868_MIN_FREQUENCY,
+			maxFrequency:    EU868_MAX_FREQUENCY,
+			subBands: []subBand{
+				{minFrequency: 863000000, maxFrequency: 865000000, dutyCycle: 1000}, // 0.1 %
+				{minFrequency: 865000000, maxFrequency: 868000000, dutyCycle: 100},  // 1 %
+				{minFrequency: 868000000, maxFrequency: 868600000, dutyCycle: 100},  // 1 %
+				{minFrequency: 868700000, maxFrequency: 869200000, dutyCycle: 1000}, // 0.1 %
+				{minFrequency: 869400000, maxFrequency: 869650000, dutyCycle: 10},   // 10 %
+				{minFrequency: 869700000, maxFrequency: 870000000, dutyCycle: 100},  // 1 %
+			},
+		},
 	}}
 }
+
+// euChannels returns the channels of an EU868 like channel plan, with given
+// default channels enabled for DR0 to DR5
+func euChannels(maxChannels int, defaults ...uint32) []plannedChannel {
+	channels := make([]plannedChannel, maxChannels)
+	for i, freq := range defaults {
+		channels[i] = plannedChannel{frequency: freq, minDR: 0, maxDR: 5, enabled: true}
+	}
+	return channels
+}
+
+// Rx1Channel returns the RX1 receive window channel: same frequency as the
+// uplink, data rate lowered by the RX1 data rate offset
+func (r *SettingsEU868) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	if dr > rx1DROffset {
+		dr -= rx1DROffset
+	} else {
+		dr = 0
+	}
+	return r.setDownlinkChannel(r.rx1Channel, r.downlinkFrequency(uplink.Frequency()), dr)
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsEU868) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, EU868_RX2_FREQUENCY, rx2DataRate)
+}
@@ -0,0 +1,91 @@
+package region
+
+import "tinygo.org/x/drivers/lora"
+
+const (
+	IN865_DEFAULT_PREAMBLE_LEN = 8
+	IN865_DEFAULT_TX_POWER_DBM = 30
+	IN865_RX2_FREQUENCY        = lora.MHz_866_55
+	IN865_RX2_DATA_RATE        = 2
+	IN865_MIN_FREQUENCY        = 865000000
+	IN865_MAX_FREQUENCY        = 867000000
+	IN865_MAX_CHANNELS         = 16
+	IN865_MAX_TX_POWER_INDEX   = 10
+)
+
+type ChannelIN struct {
+	channel
+}
+
+func (c *ChannelIN) Next() bool {
+	return false
+}
+
+type SettingsIN865 struct {
+	settings
+}
+
+func IN865() *SettingsIN865 {
+	return &SettingsIN865{settings: settings{
+		joinRequestChannel: &ChannelIN{channel: channel{lora.MHz_865_0625,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			IN865_DEFAULT_PREAMBLE_LEN,
+			IN865_DEFAULT_TX_POWER_DBM}},
+		joinAcceptChannel: &ChannelIN{channel: channel{lora.MHz_865_0625,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			IN865_DEFAULT_PREAMBLE_LEN,
+			IN865_DEFAULT_TX_POWER_DBM}},
+		uplinkChannel: &ChannelIN{channel: channel{lora.MHz_865_0625,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			IN865_DEFAULT_PREAMBLE_LEN,
+			IN865_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelIN{channel: channel{lora.MHz_865_0625,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			IN865_DEFAULT_PREAMBLE_LEN,
+			IN865_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelIN{channel: channel{IN865_RX2_FREQUENCY,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor10,
+			lora.CodingRate4_5,
+			IN865_DEFAULT_PREAMBLE_LEN,
+			IN865_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: IN865_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+		},
+		channelPlan: channelPlan{
+			channels:        euChannels(IN865_MAX_CHANNELS, lora.MHz_865_0625, lora.MHz_865_4025, lora.MHz_865_985),
+			defaultChannels: 3,
+			dataRate:        3,
+			maxTxPower:      IN865_MAX_TX_POWER_INDEX,
+			defaultTxPower:  IN865_DEFAULT_TX_POWER_DBM,
+			minFrequency:    IN865_MIN_FREQUENCY,
+			maxFrequency:    IN865_MAX_FREQUENCY,
+		},
+	}}
+}
+
+// Rx1Channel returns the RX1 receive window channel: same frequency as the
+// uplink, data rate shifted by the RX1 data rate offset
+func (r *SettingsIN865) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	return r.setDownlinkChannel(r.rx1Channel, r.downlinkFrequency(uplink.Frequency()), rx1ShiftedDataRate(dr, rx1DROffset, 0, 5))
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsIN865) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, IN865_RX2_FREQUENCY, rx2DataRate)
+}
@@ -0,0 +1,96 @@
+package region
+
+import "tinygo.org/x/drivers/lora"
+
+const (
+	KR920_DEFAULT_PREAMBLE_LEN = 8
+	KR920_DEFAULT_TX_POWER_DBM = 14
+	KR920_RX2_FREQUENCY        = lora.MHz_921_9
+	KR920_RX2_DATA_RATE        = 0
+	KR920_MIN_FREQUENCY        = 920900000
+	KR920_MAX_FREQUENCY        = 923300000
+	KR920_MAX_CHANNELS         = 16
+	KR920_MAX_TX_POWER_INDEX   = 7
+)
+
+type ChannelKR struct {
+	channel
+}
+
+func (c *ChannelKR) Next() bool {
+	return false
+}
+
+type SettingsKR920 struct {
+	settings
+}
+
+func KR920() *SettingsKR920 {
+	return &SettingsKR920{settings: settings{
+		joinRequestChannel: &ChannelKR{channel: channel{lora.MHz_922_1,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			KR920_DEFAULT_PREAMBLE_LEN,
+			KR920_DEFAULT_TX_POWER_DBM}},
+		joinAcceptChannel: &ChannelKR{channel: channel{lora.MHz_922_1,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			KR920_DEFAULT_PREAMBLE_LEN,
+			KR920_DEFAULT_TX_POWER_DBM}},
+		uplinkChannel: &ChannelKR{channel: channel{lora.MHz_922_1,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			KR920_DEFAULT_PREAMBLE_LEN,
+			KR920_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelKR{channel: channel{lora.MHz_922_1,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor9,
+			lora.CodingRate4_5,
+			KR920_DEFAULT_PREAMBLE_LEN,
+			KR920_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelKR{channel: channel{KR920_RX2_FREQUENCY,
+			lora.Bandwidth_125_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			KR920_DEFAULT_PREAMBLE_LEN,
+			KR920_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: KR920_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51}, // DR0
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51}, // DR1
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51}, // DR2
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242}, // DR4
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR5
+		},
+		channelPlan: channelPlan{
+			channels:        euChannels(KR920_MAX_CHANNELS, lora.MHz_922_1, lora.MHz_922_3, lora.MHz_922_5),
+			defaultChannels: 3,
+			dataRate:        3,
+			maxTxPower:      KR920_MAX_TX_POWER_INDEX,
+			defaultTxPower:  KR920_DEFAULT_TX_POWER_DBM,
+			minFrequency:    KR920_MIN_FREQUENCY,
+			maxFrequency:    KR920_MAX_FREQUENCY,
+		},
+	}}
+}
+
+// Rx1Channel returns the RX1 receive window channel: same frequency as the
+// uplink, data rate lowered by the RX1 data rate offset
+func (r *SettingsKR920) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	if dr > rx1DROffset {
+		dr -= rx1DROffset
+	} else {
+		dr = 0
+	}
+	return r.setDownlinkChannel(r.rx1Channel, r.downlinkFrequency(uplink.Frequency()), dr)
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsKR920) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, KR920_RX2_FREQUENCY, rx2DataRate)
+}
@@ -0,0 +1,120 @@
+package region
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var ErrInvalidState = errors.New("invalid regional settings state")
+
+// Flags of the TxParamSetupReq state
+const (
+	stateUplinkDwellTime   = 0x01
+	stateDownlinkDwellTime = 0x02
+)
+
+// MarshalBinary encodes the channel plan state set by network MAC commands:
+// uplink data rate, TX power, dwell time limits, enabled channels and, unless
+// the plan is fixed, the channels themselves. The end of the duty cycle off
+// time of each sub-band follows, as wall clock time.
+func (r *settings) MarshalBinary() ([]byte, error) {
+	var flags uint8
+	if r.uplinkDwellTime {
+		flags |= stateUplinkDwellTime
+	}
+	if r.downlinkDwellTime {
+		flags |= stateDownlinkDwellTime
+	}
+
+	b := []byte{r.dataRate, r.txPower, flags, uint8(r.defaultTxPower), uint8(len(r.channels))}
+	mask := make([]byte, (len(r.channels)+7)/8)
+	for i := range r.channels {
+		if r.channels[i].enabled {
+			mask[i/8] |= 1 << (i % 8)
+		}
+	}
+	b = append(b, mask...)
+
+	var buf [8]byte
+	if !r.fixedPlan {
+		for i := range r.channels {
+			c := &r.channels[i]
+			binary.LittleEndian.PutUint32(buf[:], c.frequency)
+			b = append(b, buf[:4]...)
+			binary.LittleEndian.PutUint32(buf[:], c.dlFrequency)
+			b = append(b, buf[:4]...)
+			b = append(b, c.minDR|c.maxDR<<4)
+		}
+	}
+
+	b = append(b, uint8(len(r.subBands)))
+	for i := range r.subBands {
+		var ns int64
+		if t := r.subBands[i].availableAt; !t.IsZero() {
+			ns = t.UnixNano()
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(ns))
+		b = append(b, buf[:]...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary restores a channel plan state encoded by MarshalBinary for
+// the same region. Nothing is changed if the state is invalid.
+func (r *settings) UnmarshalBinary(data []byte) error {
+	if len(data) < 5 || int(data[4]) != len(r.channels) {
+		return ErrInvalidState
+	}
+	n := len(r.channels)
+	maskLen := (n + 7) / 8
+	stateLen := 5 + maskLen
+	if !r.fixedPlan {
+		stateLen += 9 * n
+	}
+	bandsAt := stateLen
+	stateLen += 1 + 8*len(r.subBands)
+	if len(data) != stateLen || int(data[bandsAt]) != len(r.subBands) {
+		return ErrInvalidState
+	}
+	if _, ok := r.DataRate(data[0]); !ok || data[1] > r.maxTxPower {
+		return ErrInvalidState
+	}
+
+	channels := make([]plannedChannel, n)
+	copy(channels, r.channels)
+	mask := data[5 : 5+maskLen]
+	b := data[5+maskLen:]
+	for i := range channels {
+		c := &channels[i]
+		c.enabled = mask[i/8]&(1<<(i%8)) != 0
+		if r.fixedPlan {
+			continue
+		}
+		c.frequency = binary.LittleEndian.Uint32(b)
+		c.dlFrequency = binary.LittleEndian.Uint32(b[4:])
+		c.minDR = b[8] & 0x0F
+		c.maxDR = b[8] >> 4
+		b = b[9:]
+		if c.frequency != 0 && !r.ValidFrequency(c.frequency) {
+			return ErrInvalidState
+		}
+	}
+
+	r.dataRate = data[0]
+	r.txPower = data[1]
+	r.uplinkDwellTime = data[2]&stateUplinkDwellTime != 0
+	r.downlinkDwellTime = data[2]&stateDownlinkDwellTime != 0
+	r.defaultTxPower = int8(data[3])
+	copy(r.channels, channels)
+
+	b = data[bandsAt+1:]
+	for i := range r.subBands {
+		r.subBands[i].availableAt = time.Time{}
+		if ns := int64(binary.LittleEndian.Uint64(b)); ns != 0 {
+			r.subBands[i].availableAt = time.Unix(0, ns)
+		}
+		b = b[8:]
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package region
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/lora"
+)
+
+// rx1Test is an RX1 channel expected after an uplink, for a given offset
+type rx1Test struct {
+	upFreq uint32
+	upDR   uint8
+	offset uint8
+	freq   uint32
+	dr     uint8
+}
+
+// Expected values come from LoRaWAN Regional Parameters RP002-1.0.3, except
+// for the legacy CN470 plan, which comes from LoRaWAN Regional Parameters
+// v1.1rB
+var regionTests = []struct {
+	name       string
+	new        func() (Settings, *settings)
+	channels   []uint32   // Default enabled uplink channels
+	dataRates  []DataRate // Zero value for RFU data rates
+	rx2Freq    uint32
+	rx2DR      uint8
+	maxEIRP    int8
+	maxTxPower uint8
+	rx1        []rx1Test
+}{
+	{
+		name:     "AS923-1",
+		new:      func() (Settings, *settings) { r := AS923_1(); return r, &r.settings },
+		channels: []uint32{923200000, 923400000},
+		// Uplink dwell time limited
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 0},
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 0},
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 11},
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 53},
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 125},
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_250_0, 242},
+		},
+		rx2Freq:    923200000,
+		rx2DR:      2,
+		maxEIRP:    16,
+		maxTxPower: 7,
+		rx1: []rx1Test{
+			{923200000, 5, 0, 923200000, 5},
+			{923400000, 5, 3, 923400000, 2},
+			{923200000, 2, 5, 923200000, 2},
+			{923200000, 3, 6, 923200000, 4},
+			{923200000, 4, 7, 923200000, 5},
+		},
+	},
+	{
+		name:       "AS923-2",
+		new:        func() (Settings, *settings) { r := AS923_2(); return r, &r.settings },
+		channels:   []uint32{921400000, 921600000},
+		rx2Freq:    921400000,
+		rx2DR:      2,
+		maxEIRP:    16,
+		maxTxPower: 7,
+		rx1:        []rx1Test{{921600000, 4, 1, 921600000, 3}},
+	},
+	{
+		name:       "AS923-3",
+		new:        func() (Settings, *settings) { r := AS923_3(); return r, &r.settings },
+		channels:   []uint32{916600000, 916800000},
+		rx2Freq:    916600000,
+		rx2DR:      2,
+		maxEIRP:    16,
+		maxTxPower: 7,
+		rx1:        []rx1Test{{916800000, 5, 0, 916800000, 5}},
+	},
+	{
+		name:       "AS923-4",
+		new:        func() (Settings, *settings) { r := AS923_4(); return r, &r.settings },
+		channels:   []uint32{917300000, 917500000},
+		rx2Freq:    917300000,
+		rx2DR:      2,
+		maxEIRP:    16,
+		maxTxPower: 7,
+		rx1:        []rx1Test{{917300000, 5, 2, 917300000, 3}},
+	},
+	{
+		name:     "KR920",
+		new:      func() (Settings, *settings) { r := KR920(); return r, &r.settings },
+		channels: []uint32{922100000, 922300000, 922500000},
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115},
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},
+		},
+		rx2Freq:    921900000,
+		rx2DR:      0,
+		maxEIRP:    14,
+		maxTxPower: 7,
+		rx1: []rx1Test{
+			{922300000, 5, 2, 922300000, 3},
+			{922500000, 1, 3, 922500000, 0},
+		},
+	},
+	{
+		name:     "IN865",
+		new:      func() (Settings, *settings) { r := IN865(); return r, &r.settings },
+		channels: []uint32{865062500, 865402500, 865985000},
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115},
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},
+		},
+		rx2Freq:    866550000,
+		rx2DR:      2,
+		maxEIRP:    30,
+		maxTxPower: 10,
+		rx1: []rx1Test{
+			{865402500, 2, 7, 865402500, 4},
+			{865985000, 5, 1, 865985000, 4},
+		},
+	},
+	{
+		name: "CN470 legacy",
+		new:  func() (Settings, *settings) { r := CN470Legacy(); return r, &r.settings },
+		channels: []uint32{470300000, 470500000, 470700000, 470900000,
+			471100000, 471300000, 471500000, 471700000},
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115},
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},
+		},
+		rx2Freq:    505300000,
+		rx2DR:      0,
+		maxEIRP:    19,
+		maxTxPower: 7,
+		rx1: []rx1Test{
+			{470300000, 5, 0, 500300000, 5},
+			{480300000, 3, 1, 500700000, 2},
+		},
+	},
+	{
+		name:     "EU433",
+		new:      func() (Settings, *settings) { r := EU433(); return r, &r.settings },
+		channels: []uint32{433175000, 433375000, 433575000},
+		dataRates: []DataRate{
+			{lora.SpreadingFactor12, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor11, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 51},
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 115},
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242},
+			{lora.SpreadingFactor7, lora.Bandwidth_250_0, 242},
+		},
+		rx2Freq:    434665000,
+		rx2DR:      0,
+		maxEIRP:    12,
+		maxTxPower: 5,
+		rx1:        []rx1Test{{433375000, 4, 4, 433375000, 0}},
+	},
+}
+
+func TestRegionDefaultChannels(t *testing.T) {
+	for _, tt := range regionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, s := tt.new()
+
+			var enabled []uint32
+			for _, ch := range s.channels {
+				if ch.enabled {
+					enabled = append(enabled, ch.frequency)
+				}
+			}
+			c.Assert(enabled, qt.DeepEquals, tt.channels)
+			for _, f := range tt.channels {
+				c.Assert(s.ValidFrequency(f), qt.IsTrue)
+			}
+		})
+	}
+}
+
+func TestRegionDataRates(t *testing.T) {
+	for _, tt := range regionTests {
+		if tt.dataRates == nil {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			r, _ := tt.new()
+
+			for i, want := range tt.dataRates {
+				dr, ok := r.DataRate(uint8(i))
+				c.Assert(ok, qt.Equals, want.SpreadingFactor != 0, qt.Commentf("DR%d", i))
+				c.Assert(dr, qt.Equals, want, qt.Commentf("DR%d", i))
+			}
+			_, ok := r.DataRate(uint8(len(tt.dataRates)))
+			c.Assert(ok, qt.IsFalse)
+		})
+	}
+}
+
+func TestRegionRx2(t *testing.T) {
+	for _, tt := range regionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			r, _ := tt.new()
+
+			c.Assert(r.Rx2DefaultDataRate(), qt.Equals, tt.rx2DR)
+			want, ok := r.DataRate(tt.rx2DR)
+			c.Assert(ok, qt.IsTrue)
+			ch := r.Rx2Channel(tt.rx2DR)
+			c.Assert(ch.Frequency(), qt.Equals, tt.rx2Freq)
+			c.Assert(ch.SpreadingFactor(), qt.Equals, want.SpreadingFactor)
+			c.Assert(ch.Bandwidth(), qt.Equals, want.Bandwidth)
+		})
+	}
+}
+
+func TestRegionRx1(t *testing.T) {
+	for _, tt := range regionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			r, _ := tt.new()
+
+			for _, rx1 := range tt.rx1 {
+				up, _ := r.DataRate(rx1.upDR)
+				uplink := &ChannelEU{channel: channel{rx1.upFreq, up.Bandwidth, up.SpreadingFactor, lora.CodingRate4_5, 8, 0}}
+				want, _ := r.DataRate(rx1.dr)
+
+				ch := r.Rx1Channel(uplink, rx1.offset)
+				c.Assert(ch.Frequency(), qt.Equals, rx1.freq, qt.Commentf("%+v", rx1))
+				c.Assert(ch.SpreadingFactor(), qt.Equals, want.SpreadingFactor, qt.Commentf("%+v", rx1))
+				c.Assert(ch.Bandwidth(), qt.Equals, want.Bandwidth, qt.Commentf("%+v", rx1))
+			}
+		})
+	}
+}
+
+func TestRegionTxPower(t *testing.T) {
+	for _, tt := range regionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			r, s := tt.new()
+
+			// TX power index N is Max EIRP - 2N dB
+			for i := uint8(0); i <= tt.maxTxPower; i++ {
+				c.Assert(s.txPowerDBm(i), qt.Equals, tt.maxEIRP-2*int8(i))
+			}
+
+			powerOK, _, _ := r.ApplyLinkADR(0x0F, tt.maxTxPower+1, nil, nil)
+			c.Assert(powerOK, qt.IsFalse)
+			powerOK, drOK, chMaskOK := r.ApplyLinkADR(0x0F, tt.maxTxPower, nil, nil)
+			c.Assert([]bool{powerOK, drOK, chMaskOK}, qt.DeepEquals, []bool{true, true, true})
+			c.Assert(r.UplinkChannel().TxPowerDBm(), qt.Equals, tt.maxEIRP-2*int8(tt.maxTxPower))
+		})
+	}
+}
+
+func TestCN470LegacyChannelMask(t *testing.T) {
+	c := qt.New(t)
+	r := CN470Legacy()
+
+	// ChMaskCntl 0..5 address blocks of 16 channels
+	_, _, ok := r.ApplyLinkADR(0x0F, 0x0F, []uint8{0, 5}, []uint16{0, 0x8001})
+	c.Assert(ok, qt.IsTrue)
+	for i, ch := range r.channels {
+		c.Assert(ch.enabled, qt.Equals, i == 80 || i == 95, qt.Commentf("channel %d", i))
+	}
+
+	// ChMaskCntl 6 enables all channels, 7 is RFU
+	_, _, ok = r.ApplyLinkADR(0x0F, 0x0F, []uint8{6}, []uint16{0})
+	c.Assert(ok, qt.IsTrue)
+	for _, ch := range r.channels {
+		c.Assert(ch.enabled, qt.IsTrue)
+	}
+	_, _, ok = r.ApplyLinkADR(0x0F, 0x0F, []uint8{7}, []uint16{0xFFFF})
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestDutyCycle(t *testing.T) {
+	c := qt.New(t)
+	r := EU868()
+	start := time.Now()
+
+	// 1 % sub-band: 100 ms on air blocks the sub-band for 10 s from start
+	r.RegisterTx(lora.MHz_868_1, start, 100*time.Millisecond)
+	c.Assert(r.DutyCycleWait(lora.MHz_868_3, start.Add(time.Second)), qt.Equals, 9*time.Second)
+	c.Assert(r.DutyCycleWait(lora.MHz_868_5, start.Add(10*time.Second)), qt.Equals, time.Duration(0))
+
+	// Other sub-bands are accounted separately
+	c.Assert(r.DutyCycleWait(lora.MHz_869_525, start), qt.Equals, time.Duration(0))
+	r.RegisterTx(lora.MHz_869_525, start, 100*time.Millisecond)
+	c.Assert(r.DutyCycleWait(lora.MHz_869_525, start), qt.Equals, time.Second)
+
+	// The off time is kept in the saved state
+	data, err := r.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	restored := EU868()
+	c.Assert(restored.UnmarshalBinary(data), qt.IsNil)
+	c.Assert(restored.DutyCycleWait(lora.MHz_868_3, start.Add(time.Second)), qt.Equals, 9*time.Second)
+	c.Assert(restored.DutyCycleWait(lora.MHz_869_525, start), qt.Equals, time.Second)
+	c.Assert(restored.DutyCycleWait(lora.MHz_868_3, start.Add(10*time.Second)), qt.Equals, time.Duration(0))
+
+	// No duty cycle limitation in US915
+	us := US915()
+	us.RegisterTx(lora.MHz_902_3, start, time.Second)
+	c.Assert(us.DutyCycleWait(lora.MHz_902_3, start), qt.Equals, time.Duration(0))
+}
+
+func TestTxParamSetup(t *testing.T) {
+	c := qt.New(t)
+
+	r := AS923_1()
+	c.Assert(r.MaxDwellTime(), qt.Equals, 400*time.Millisecond)
+	// DR0 and DR1 cannot be used for uplinks when dwell time is limited
+	_, drOK, _ := r.ApplyLinkADR(1, 0x0F, nil, nil)
+	c.Assert(drOK, qt.IsFalse)
+
+	// No dwell time limit, MaxEIRP 20 dBm
+	c.Assert(r.SetTxParams(false, false, 7), qt.IsTrue)
+	c.Assert(r.MaxDwellTime(), qt.Equals, time.Duration(0))
+	dr, _ := r.DataRate(2)
+	c.Assert(dr.MaxPayload, qt.Equals, uint8(115))
+	_, drOK, _ = r.ApplyLinkADR(1, 0x0F, nil, nil)
+	c.Assert(drOK, qt.IsTrue)
+	c.Assert(r.UplinkChannel().TxPowerDBm(), qt.Equals, int8(20))
+
+	c.Assert(KR920().SetTxParams(false, false, 7), qt.IsFalse)
+}
+
+func TestADRBackoff(t *testing.T) {
+	c := qt.New(t)
+	r := EU868()
+
+	// DR5, TX power index 3, only channel 1 enabled
+	_, _, ok := r.ApplyLinkADR(5, 3, []uint8{0}, []uint16{0x0002})
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(r.ADRDefaults(), qt.IsFalse)
+
+	r.ADRBackoff()
+	c.Assert(r.txPower, qt.Equals, uint8(0))
+	c.Assert(r.UplinkDataRate(), qt.Equals, uint8(5))
+	for dr := 4; dr >= 0; dr-- {
+		r.ADRBackoff()
+		c.Assert(r.UplinkDataRate(), qt.Equals, uint8(dr))
+	}
+	c.Assert(r.ADRDefaults(), qt.IsTrue)
+
+	// Default channels are enabled again
+	r.ADRBackoff()
+	for i, ch := range r.channels {
+		c.Assert(ch.enabled, qt.Equals, i < 3, qt.Commentf("channel %d", i))
+	}
+
+	// AS923 lowest uplink data rate is DR2 when dwell time is limited
+	as := AS923_1()
+	as.ADRBackoff()
+	c.Assert(as.UplinkDataRate(), qt.Equals, uint8(2))
+	c.Assert(as.ADRDefaults(), qt.IsTrue)
+}
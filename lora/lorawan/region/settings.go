@@ -1,15 +1,49 @@
 package region
 
+import "time"
+
 type Settings interface {
 	JoinRequestChannel() Channel
 	JoinAcceptChannel() Channel
 	UplinkChannel() Channel
+	UplinkChannelIndex() uint8
+	UplinkDataRate() uint8
+	Rx1Channel(uplink Channel, rx1DROffset uint8) Channel
+	Rx2Channel(rx2DataRate uint8) Channel
+	Rx2DefaultDataRate() uint8
+	DataRate(dr uint8) (DataRate, bool)
+	ValidFrequency(freq uint32) bool
+	ApplyLinkADR(dataRate, txPower uint8, chMaskCntl []uint8, chMask []uint16) (powerOK, dataRateOK, chMaskOK bool)
+	SetChannel(index uint8, freq uint32, minDR, maxDR uint8) (freqOK, dataRateOK bool)
+	SetDownlinkFrequency(index uint8, freq uint32) (freqOK, uplinkExists bool)
+	ADRBackoff()
+	ADRDefaults() bool
+	LowerDataRate(payloadLen int) bool
+	DutyCycleWait(freq uint32, now time.Time) time.Duration
+	RegisterTx(freq uint32, start time.Time, airTime time.Duration)
+	MaxDwellTime() time.Duration
+	SetTxParams(uplinkDwellTime, downlinkDwellTime bool, maxEIRPIndex uint8) bool
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+// DataRate is the LoRa modulation matching a LoRaWAN data rate index
+type DataRate struct {
+	SpreadingFactor uint8
+	Bandwidth       uint8
+	MaxPayload      uint8 // Maximum application payload size (N), without FOpts
 }
 
 type settings struct {
 	joinRequestChannel Channel
 	joinAcceptChannel  Channel
 	uplinkChannel      Channel
+	rx1Channel         Channel
+	rx2Channel         Channel
+	rx2DataRate        uint8
+	dataRates          []DataRate
+	dwellMaxPayloads   []uint8 // Uplink max payload sizes when dwell time is limited
+	channelPlan
 }
 
 func (r *settings) JoinRequestChannel() Channel {
@@ -20,6 +54,40 @@ func (r *settings) JoinAcceptChannel() Channel {
 	return r.joinAcceptChannel
 }
 
-func (r *settings) UplinkChannel() Channel {
-	return r.uplinkChannel
+// Rx2DefaultDataRate returns the RX2 data rate used until network sets it
+func (r *settings) Rx2DefaultDataRate() uint8 {
+	return r.rx2DataRate
+}
+
+// DataRate returns the modulation of a given data rate index. A zero
+// MaxPayload means the data rate cannot be used for uplinks.
+func (r *settings) DataRate(dr uint8) (DataRate, bool) {
+	if int(dr) >= len(r.dataRates) || r.dataRates[dr].SpreadingFactor == 0 {
+		return DataRate{}, false
+	}
+	d := r.dataRates[dr]
+	if r.uplinkDwellTime && int(dr) < len(r.dwellMaxPayloads) {
+		d.MaxPayload = r.dwellMaxPayloads[dr]
+	}
+	return d, true
+}
+
+// dataRateIndex returns the first data rate index matching a modulation
+func (r *settings) dataRateIndex(sf, bw uint8) (uint8, bool) {
+	for i, dr := range r.dataRates {
+		if dr.SpreadingFactor == sf && dr.Bandwidth == bw {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// setDownlinkChannel configures a receive window channel
+func (r *settings) setDownlinkChannel(ch Channel, freq uint32, dr uint8) Channel {
+	ch.SetFrequency(freq)
+	if d, ok := r.DataRate(dr); ok {
+		ch.SetSpreadingFactor(d.SpreadingFactor)
+		ch.SetBandwidth(d.Bandwidth)
+	}
+	return ch
 }
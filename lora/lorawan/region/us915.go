@@ -3,10 +3,16 @@ package region
 import "tinygo.org/x/drivers/lora"
 
 const (
-	US915_DEFAULT_PREAMBLE_LEN     = 8
-	US915_DEFAULT_TX_POWER_DBM     = 20
-	US915_FREQUENCY_INCREMENT_DR_0 = 200000  // only for 125 kHz Bandwidth
-	US915_FREQUENCY_INCREMENT_DR_4 = 1600000 // only for 500 kHz Bandwidth
+	US915_DEFAULT_PREAMBLE_LEN         = 8
+	US915_DEFAULT_TX_POWER_DBM         = 20
+	US915_FREQUENCY_INCREMENT_DR_0     = 200000  // only for 125 kHz Bandwidth
+	US915_FREQUENCY_INCREMENT_DR_4     = 1600000 // only for 500 kHz Bandwidth
+	US915_DOWNLINK_FREQUENCY_INCREMENT = 600000
+	US915_RX2_DATA_RATE                = 8
+	US915_RX2_FREQUENCY                = lora.MHz_923_3
+	US915_MIN_FREQUENCY                = 902000000
+	US915_MAX_FREQUENCY                = 928000000
+	US915_MAX_TX_POWER_INDEX           = 14
 )
 
 type ChannelUS struct {
@@ -74,9 +80,84 @@ func US915() *SettingsUS915 {
 			US915_DEFAULT_TX_POWER_DBM}},
 		uplinkChannel: &ChannelUS{channel: channel{lora.Mhz_903_0,
 			lora.Bandwidth_500_0,
-			lora.SpreadingFactor9,
+			lora.SpreadingFactor8,
+			lora.CodingRate4_5,
+			US915_DEFAULT_PREAMBLE_LEN,
+			US915_DEFAULT_TX_POWER_DBM}},
+		rx1Channel: &ChannelUS{channel: channel{lora.MHz_923_3,
+			lora.Bandwidth_500_0,
+			lora.SpreadingFactor10,
 			lora.CodingRate4_5,
 			US915_DEFAULT_PREAMBLE_LEN,
 			US915_DEFAULT_TX_POWER_DBM}},
+		rx2Channel: &ChannelUS{channel: channel{US915_RX2_FREQUENCY,
+			lora.Bandwidth_500_0,
+			lora.SpreadingFactor12,
+			lora.CodingRate4_5,
+			US915_DEFAULT_PREAMBLE_LEN,
+			US915_DEFAULT_TX_POWER_DBM}},
+		rx2DataRate: US915_RX2_DATA_RATE,
+		dataRates: []DataRate{
+			{lora.SpreadingFactor10, lora.Bandwidth_125_0, 11}, // DR0
+			{lora.SpreadingFactor9, lora.Bandwidth_125_0, 53},  // DR1
+			{lora.SpreadingFactor8, lora.Bandwidth_125_0, 125}, // DR2
+			{lora.SpreadingFactor7, lora.Bandwidth_125_0, 242}, // DR3
+			{lora.SpreadingFactor8, lora.Bandwidth_500_0, 242}, // DR4
+			{}, {}, {}, // DR5..7: RFU
+			{lora.SpreadingFactor12, lora.Bandwidth_500_0, 53},  // DR8
+			{lora.SpreadingFactor11, lora.Bandwidth_500_0, 129}, // DR9
+			{lora.SpreadingFactor10, lora.Bandwidth_500_0, 242}, // DR10
+			{lora.SpreadingFactor9, lora.Bandwidth_500_0, 242},  // DR11
+			{lora.SpreadingFactor8, lora.Bandwidth_500_0, 242},  // DR12
+			{lora.SpreadingFactor7, lora.Bandwidth_500_0, 242},  // DR13
+		},
+		channelPlan: channelPlan{
+			channels:       fixedChannels(lora.MHz_902_3, lora.Mhz_903_0, 3, 4, 0),
+			fixedPlan:      true,
+			wideChannels:   8,
+			dataRate:       4,
+			maxTxPower:     US915_MAX_TX_POWER_INDEX,
+			defaultTxPower: US915_DEFAULT_TX_POWER_DBM,
+			minFrequency:   US915_MIN_FREQUENCY,
+			maxFrequency:   US915_MAX_FREQUENCY,
+		},
 	}}
 }
+
+// Rx1Channel returns the RX1 receive window channel: one of the 8 downlink
+// channels, with data rate derived from the uplink one
+func (r *SettingsUS915) Rx1Channel(uplink Channel, rx1DROffset uint8) Channel {
+	dr, _ := r.dataRateIndex(uplink.SpreadingFactor(), uplink.Bandwidth())
+	freq := rx1Frequency(uplink, lora.MHz_902_3, lora.Mhz_903_0)
+	return r.setDownlinkChannel(r.rx1Channel, freq, rx1DataRate(dr+10, rx1DROffset))
+}
+
+// Rx2Channel returns the RX2 receive window channel
+func (r *SettingsUS915) Rx2Channel(rx2DataRate uint8) Channel {
+	return r.setDownlinkChannel(r.rx2Channel, US915_RX2_FREQUENCY, rx2DataRate)
+}
+
+// rx1Frequency returns the downlink frequency associated to an uplink
+// channel for US915 like channel plans (64 x 125 kHz + 8 x 500 kHz uplink
+// channels, 8 x 500 kHz downlink channels)
+func rx1Frequency(uplink Channel, base125, base500 uint32) uint32 {
+	var n uint32
+	if uplink.Bandwidth() == lora.Bandwidth_500_0 {
+		n = (uplink.Frequency() - base500) / US915_FREQUENCY_INCREMENT_DR_4
+	} else {
+		n = (uplink.Frequency() - base125) / US915_FREQUENCY_INCREMENT_DR_0
+	}
+	return lora.MHz_923_3 + (n%8)*US915_DOWNLINK_FREQUENCY_INCREMENT
+}
+
+// rx1DataRate applies the RX1 offset to a downlink data rate, within the
+// DR8..DR13 range of US915 like channel plans
+func rx1DataRate(dr uint8, rx1DROffset uint8) uint8 {
+	if dr > 13+rx1DROffset {
+		return 13
+	}
+	if dr < 8+rx1DROffset {
+		return 8
+	}
+	return dr - rx1DROffset
+}
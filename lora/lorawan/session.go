@@ -5,6 +5,13 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"math"
+	"time"
+)
+
+// LoRaWAN specification versions
+const (
+	Version1_0 = iota // LoRaWAN 1.0.x
+	Version1_1        // LoRaWAN 1.1
 )
 
 // Session is used to store session data of a LoRaWAN session
@@ -12,13 +19,42 @@ type Session struct {
 	NwkSKey    [16]uint8
 	AppSKey    [16]uint8
 	DevAddr    [4]uint8
-	FCntDown   uint32
+	FCntDown   uint32 // NFCntDown for LoRaWAN 1.1
 	FCntUp     uint32
 	CFList     [16]uint8
 	RXDelay    uint8
 	DLSettings uint8
+
+	// LoRaWAN 1.1 only, NwkSKey is unused
+	Version     uint8
+	FNwkSIntKey [16]uint8
+	SNwkSIntKey [16]uint8
+	NwkSEncKey  [16]uint8
+	AFCntDown   uint32
+	RJCount0    uint16
+
+	// Set by network MAC commands
+	Rx2Frequency uint32 // 0 for region default
+	MaxDutyCycle uint8  // Aggregated duty cycle limit is 1/2^MaxDutyCycle
+
+	macAnswers       []uint8
+	stickyMACAnswers []uint8
+	versionInd       uint8         // ResetInd or RekeyInd CID, until confirmed
+	ackDownlink      bool          // Last downlink was confirmed
+	confFCntDown     uint16        // Last confirmed downlink frame counter
+	confFCntUp       uint16        // Last confirmed uplink frame counter
+	txAvailableAt    time.Time     // End of aggregated duty cycle off time
+	adrAckCnt        uint32        // Uplinks sent since last downlink
+	backoffStart     time.Time     // First unacknowledged confirmed uplink
+	backoffPeriod    time.Time     // Start of current back-off period
+	backoffAirTime   time.Duration // Time on air in current back-off period
 }
 
+const (
+	MTypeUnconfirmedDataUp = 0b010
+	MTypeConfirmedDataUp   = 0b100
+)
+
 // SetDevAddr configures the Session DevAddr
 func (s *Session) SetDevAddr(devAddr []uint8) error {
 	if len(devAddr) != 4 {
@@ -67,21 +103,72 @@ func (s *Session) GetAppSKey() string {
 	return hex.EncodeToString(s.AppSKey[:])
 }
 
-// GenMessage generates an uplink message.
-func (s *Session) GenMessage(dir uint8, payload []uint8) ([]uint8, error) {
-	var buf []uint8
-	buf = append(buf, 0b01000000) // FHDR Unconfirmed up
-	buf = append(buf, s.DevAddr[:]...)
+// SetFNwkSIntKey configures the Session FNwkSIntKey (LoRaWAN 1.1)
+func (s *Session) SetFNwkSIntKey(key []uint8) error {
+	if len(key) != 16 {
+		return ErrInvalidNwkSKeyLength
+	}
 
-	// FCtl : No ADR, No RFU, No ACK, No FPending, No FOpt
-	buf = append(buf, 0x00)
+	copy(s.FNwkSIntKey[:], key)
 
-	// FCnt Up
-	buf = append(buf, uint8(s.FCntUp&0xFF), uint8((s.FCntUp>>8)&0xFF))
+	return nil
+}
 
-	// FPort=1
-	buf = append(buf, 0x01)
+// GetFNwkSIntKey returns the Session FNwkSIntKey
+func (s *Session) GetFNwkSIntKey() string {
+	return hex.EncodeToString(s.FNwkSIntKey[:])
+}
+
+// SetSNwkSIntKey configures the Session SNwkSIntKey (LoRaWAN 1.1)
+func (s *Session) SetSNwkSIntKey(key []uint8) error {
+	if len(key) != 16 {
+		return ErrInvalidNwkSKeyLength
+	}
 
+	copy(s.SNwkSIntKey[:], key)
+
+	return nil
+}
+
+// GetSNwkSIntKey returns the Session SNwkSIntKey
+func (s *Session) GetSNwkSIntKey() string {
+	return hex.EncodeToString(s.SNwkSIntKey[:])
+}
+
+// SetNwkSEncKey configures the Session NwkSEncKey (LoRaWAN 1.1)
+func (s *Session) SetNwkSEncKey(key []uint8) error {
+	if len(key) != 16 {
+		return ErrInvalidNwkSKeyLength
+	}
+
+	copy(s.NwkSEncKey[:], key)
+
+	return nil
+}
+
+// GetNwkSEncKey returns the Session NwkSEncKey
+func (s *Session) GetNwkSEncKey() string {
+	return hex.EncodeToString(s.NwkSEncKey[:])
+}
+
+// GenMessage generates an uplink message.
+// For LoRaWAN 1.1 sessions, the MIC depends on the data rate and channel used
+// for transmission: the current region data rate and last channel are used.
+func (s *Session) GenMessage(dir uint8, payload []uint8) ([]uint8, error) {
+	frame, fCnt, err := s.genFrame(MTypeUnconfirmedDataUp, dir, payload)
+	if err != nil {
+		return nil, err
+	}
+	var txDr, txCh uint8
+	if regionSettings != nil {
+		txDr, txCh = regionSettings.UplinkDataRate(), regionSettings.UplinkChannelIndex()
+	}
+	return s.appendMIC(frame, dir, fCnt, txDr, txCh), nil
+}
+
+// genFrame generates an uplink message of given message type, without MIC.
+// It returns the frame counter used.
+func (s *Session) genFrame(mType uint8, dir uint8, payload []uint8) ([]uint8, uint32, error) {
 	fCnt := uint32(0)
 	if dir == 0 {
 		fCnt = s.FCntUp
@@ -89,19 +176,72 @@ func (s *Session) GenMessage(dir uint8, payload []uint8) ([]uint8, error) {
 	} else {
 		fCnt = s.FCntDown
 	}
-	data, err := s.genFRMPayload(dir, fCnt, payload, false)
+
+	var buf []uint8
+	buf = append(buf, mType<<5) // MHDR
+	buf = append(buf, s.DevAddr[:]...)
+
+	// FCtl : ADR, ADRACKReq, ACK, No ClassB, FOptsLen
+	fOpts := s.pendingMACAnswers()
+	s.dequeueMACAnswers(fOpts)
+	fCtrl := uint8(len(fOpts))
+	if adrEnabled {
+		fCtrl |= 0x80
+	}
+	if s.adrAckReq() {
+		fCtrl |= 0x40
+	}
+	if s.ackDownlink {
+		fCtrl |= 0x20
+		s.ackDownlink = false
+	}
+	if dir == 0 {
+		s.adrAckCnt++
+	}
+	buf = append(buf, fCtrl)
+
+	// FCnt
+	buf = append(buf, uint8(fCnt&0xFF), uint8((fCnt>>8)&0xFF))
+
+	// FOpts : MAC commands answers, encrypted since LoRaWAN 1.1
+	if s.Version == Version1_1 && len(fOpts) > 0 {
+		fOpts = s.encryptFOpts(dir, false, fCnt, fOpts)
+	}
+	buf = append(buf, fOpts...)
+
+	// FPort=1
+	buf = append(buf, 0x01)
+
+	data, err := s.genFRMPayload(s.AppSKey, dir, fCnt, payload, false)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	buf = append(buf, data[:]...)
 
-	mic := calcMessageMIC(buf, s.NwkSKey, dir, s.DevAddr[:], fCnt, uint8(len(buf)))
-	buf = append(buf, mic[:]...)
+	return buf, fCnt, nil
+}
+
+// appendMIC returns the given frame followed by its MIC. Data rate and channel
+// index of the transmission are only used by LoRaWAN 1.1.
+func (s *Session) appendMIC(frame []uint8, dir uint8, fCnt uint32, txDr, txCh uint8) []uint8 {
+	var mic [4]uint8
+	if s.Version == Version1_1 && dir == 0 {
+		var confFCnt uint16
+		if frame[5]&0x20 != 0 {
+			confFCnt = s.confFCntDown
+		}
+		mic = calcUplinkMIC11(frame, s.FNwkSIntKey, s.SNwkSIntKey, confFCnt, txDr, txCh, s.DevAddr[:], fCnt, uint8(len(frame)))
+	} else {
+		mic = calcMessageMIC(frame, s.NwkSKey, dir, s.DevAddr[:], fCnt, uint8(len(frame)))
+	}
 
-	return buf, nil
+	msg := make([]uint8, 0, len(frame)+4)
+	msg = append(msg, frame...)
+	return append(msg, mic[:]...)
 }
 
-func (s *Session) genFRMPayload(dir uint8, fCnt uint32, payload []byte, isFOpts bool) ([]byte, error) {
+// genFRMPayload encrypts (or decrypts) a FRMPayload with the given session key
+func (s *Session) genFRMPayload(key [16]uint8, dir uint8, fCnt uint32, payload []byte, isFOpts bool) ([]byte, error) {
 	k := len(payload) / aes.BlockSize
 	if len(payload)%aes.BlockSize != 0 {
 		k++
@@ -110,7 +250,7 @@ func (s *Session) genFRMPayload(dir uint8, fCnt uint32, payload []byte, isFOpts
 		return nil, ErrFrmPayloadTooLarge
 	}
 	encrypted := make([]byte, 0, k*16)
-	cipher, err := aes.NewCipher(s.AppSKey[:])
+	cipher, err := aes.NewCipher(key[:])
 	if err != nil {
 		panic(err)
 	}
@@ -135,3 +275,33 @@ func (s *Session) genFRMPayload(dir uint8, fCnt uint32, payload []byte, isFOpts
 	}
 	return encrypted[:len(payload)], nil
 }
+
+// encryptFOpts encrypts (or decrypts) LoRaWAN 1.1 FOpts with NwkSEncKey. As
+// fixed by the LoRaWAN 1.1 errata, A[4] is 0x02 for downlinks counted by
+// AFCntDown, and 0x01 for other frames.
+func (s *Session) encryptFOpts(dir uint8, aFCntDown bool, fCnt uint32, fOpts []uint8) []uint8 {
+	cipher, err := aes.NewCipher(s.NwkSEncKey[:])
+	if err != nil {
+		panic(err)
+	}
+
+	var a [aes.BlockSize]byte
+	a[0] = 0x01
+	a[4] = 0x01
+	if aFCntDown {
+		a[4] = 0x02
+	}
+	a[5] = dir
+	copy(a[6:10], s.DevAddr[:])
+	binary.LittleEndian.PutUint32(a[10:14], fCnt)
+	a[15] = 0x01
+	var ss [aes.BlockSize]byte
+	cipher.Encrypt(ss[:], a[:])
+
+	// FOpts are 15 bytes at most: a single block
+	encrypted := make([]uint8, len(fOpts))
+	for i := range fOpts {
+		encrypted[i] = fOpts[i] ^ ss[i]
+	}
+	return encrypted
+}
@@ -10,8 +10,10 @@ import (
 
 // Device wraps an I2C connection to a HTS221 device.
 type Device struct {
-	bus     drivers.I2C
-	Address uint8
+	bus         drivers.I2C
+	Address     uint8
+	temperature int32
+	pressure    int32
 }
 
 // New creates a new LPS22HB connection. The I2C bus must already be
@@ -57,6 +59,39 @@ func (d *Device) ReadTemperature() (temperature int32, err error) {
 	return int32(tValue * 1000), nil
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Pressure methods.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature != 0 {
+		temperature, err := d.ReadTemperature()
+		if err != nil {
+			return err
+		}
+		d.temperature = temperature
+	}
+	if which&drivers.Pressure != 0 {
+		pressure, err := d.ReadPressure()
+		if err != nil {
+			return err
+		}
+		// ReadPressure returns thousandths of a hectopascal.
+		d.pressure = pressure * 100
+	}
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Pressure returns the pressure read in the last Update call, in milli
+// pascals (mPa).
+func (d *Device) Pressure() int32 {
+	return d.pressure
+}
+
 // private functions
 
 // wait and trigger one shot in block update
@@ -13,12 +13,13 @@ import (
 )
 
 type Device struct {
-	bus     drivers.I2C
-	Address uint16
+	bus         drivers.I2C
+	Address     uint16
+	temperature int32
 }
 
 func New(bus drivers.I2C) Device {
-	return Device{bus, MCP9808_I2CADDR_DEFAULT}
+	return Device{bus: bus, Address: MCP9808_I2CADDR_DEFAULT}
 }
 
 func (d *Device) Connected() bool {
@@ -43,6 +44,29 @@ func (d *Device) ReadTemperature() (float64, error) {
 	return temp, nil
 }
 
+// Update reads the ambient temperature and stores it for the Temperature
+// method.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature == 0 {
+		return nil
+	}
+	data := make([]byte, 2)
+	if err := d.Read(MCP9808_REG_AMBIENT_TEMP, &data); err != nil {
+		return err
+	}
+	// The ambient temperature is a 13-bit two's complement value in
+	// 1/16 °C, below the three alert flags.
+	raw := int32(int16(binary.BigEndian.Uint16(data)<<3)) >> 3
+	d.temperature = raw * 125 / 2
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
 func (d *Device) ReadResolution() (resolution, error) {
 	data := make([]byte, 2)
 	err := d.Read(MCP9808_REG_RESOLUTION, &data)
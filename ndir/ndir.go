@@ -91,6 +91,15 @@ func (d *DevI2C) PPMCO2() int32 {
 	return d.lastMeasurement
 }
 
+// CO2 returns the CO2 parts per million read in the last Update call, or 0
+// if no concentration was read yet.
+func (d *DevI2C) CO2() uint32 {
+	if d.lastMeasurement < 0 {
+		return 0
+	}
+	return uint32(d.lastMeasurement)
+}
+
 var errInitWait = errors.New("ndir: must wait 12 seconds after init before reading concentration")
 
 // Update reads the CO2 concentration from the NDIR and stores it ready for the
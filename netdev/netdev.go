@@ -90,3 +90,24 @@ type Netdever interface {
 	Close(sockfd int) error
 	SetSockOpt(sockfd int, level int, opt int, value interface{}) error
 }
+
+// PacketConner is an optional Netdever interface for devices able to receive
+// datagrams from any address, and send datagrams to any address, on a UDP
+// socket, as needed by UDP servers.  Send and Recv only exchange datagrams
+// with the address passed to Connect.
+//
+// Check if a Netdever implements it with a type assertion:
+//
+//	if pc, ok := dev.(netdev.PacketConner); ok {
+//		n, from, err := pc.RecvFrom(sockfd, buf, 0, time.Time{})
+//		...
+//	}
+
+type PacketConner interface {
+
+	// RecvFrom receives a datagram, and returns its source address
+	RecvFrom(sockfd int, buf []byte, flags int, deadline time.Time) (int, netip.AddrPort, error)
+
+	// SendTo sends a datagram to an address
+	SendTo(sockfd int, buf []byte, flags int, to netip.AddrPort, deadline time.Time) (int, error)
+}
@@ -0,0 +1,584 @@
+// Package netdevtest implements a conformance test suite for netdev.Netdever
+// implementations.
+//
+// The suite connects the sockets of the device under test to each other, so
+// the device must be able to reach its own address, as on a loopback network.
+// The tests that need it are skipped for devices that can't, as set by
+// Config.NoLoopback.  Network drivers can run the suite on the host against a
+// fake transport that emulates the protocol of their hardware, or on the
+// hardware itself:
+//
+//	func TestNetdever(t *testing.T) {
+//		dev := New(fakeTransport())
+//		netdevtest.TestNetdever(t, dev, &netdevtest.Config{
+//			Addr:       netip.MustParseAddr("127.0.0.1"),
+//			MaxSockets: 10,
+//		})
+//	}
+package netdevtest // import "tinygo.org/x/drivers/netdev/netdevtest"
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"tinygo.org/x/drivers/netdev"
+)
+
+// Config describes the device under test.
+type Config struct {
+	// Addr is the IP address the sockets of the device listen on, and
+	// connect to.  The default is the address returned by the device.
+	Addr netip.Addr
+
+	// Port is the first port used by the servers.  The default is 8080.
+	Port uint16
+
+	// MaxSockets is the number of sockets of the device.  The default zero
+	// value means the device has more sockets than the suite opens, and
+	// the exhaustion of the sockets is not tested.
+	MaxSockets int
+
+	// Timeout bounds the operations expected to complete.  The default is
+	// 5 seconds.
+	Timeout time.Duration
+
+	// SkipTLS skips the checks of IPPROTO_TLS sockets, for devices
+	// without TLS support.
+	SkipTLS bool
+
+	// SingleServer is set for devices running a single TCP server at a
+	// time, such as the ESP8266/ESP32 AT firmware.  The tests of
+	// concurrent servers are skipped.
+	SingleServer bool
+
+	// NoLoopback is set for devices that can't connect to their own
+	// address.  The tests connecting the sockets of the device to each
+	// other are skipped.
+	NoLoopback bool
+}
+
+type suite struct {
+	dev  netdev.Netdever
+	cfg  Config
+	port uint16
+}
+
+// TestNetdever runs the conformance tests against dev, as subtests of t.  The
+// device must be connected to a network, for example with NetConnect for a
+// netlink.Netlinker.
+func TestNetdever(t *testing.T, dev netdev.Netdever, cfg *Config) {
+	s := &suite{dev: dev}
+	if cfg != nil {
+		s.cfg = *cfg
+	}
+	if !s.cfg.Addr.IsValid() {
+		addr, err := dev.Addr()
+		if err != nil {
+			t.Fatalf("Addr: %v", err)
+		}
+		s.cfg.Addr = addr
+	}
+	if s.cfg.Port == 0 {
+		s.cfg.Port = 8080
+	}
+	if s.cfg.Timeout == 0 {
+		s.cfg.Timeout = 5 * time.Second
+	}
+	s.port = s.cfg.Port
+
+	t.Run("Socket", s.testSocket)
+	t.Run("InvalidSocketFd", s.testInvalidSocketFd)
+	t.Run("TCP", s.testTCP)
+	t.Run("RecvTimeout", s.testRecvTimeout)
+	t.Run("SendLarge", s.testSendLarge)
+	t.Run("RecvClosedPeer", s.testRecvClosedPeer)
+	t.Run("UDP", s.testUDP)
+	t.Run("NoMoreSockets", s.testNoMoreSockets)
+	t.Run("Concurrent", s.testConcurrent)
+	t.Run("ConcurrentServers", s.testConcurrentServers)
+}
+
+// needLoopback skips the test if the device can't connect to itself.
+func (s *suite) needLoopback(t *testing.T) {
+	t.Helper()
+	if s.cfg.NoLoopback {
+		t.Skip("NoLoopback set")
+	}
+}
+
+// nextAddr returns the address of a new server.  Each server gets its own
+// port, so it doesn't wait for the connections of the previous ones to be
+// released.
+func (s *suite) nextAddr() netip.AddrPort {
+	addr := netip.AddrPortFrom(s.cfg.Addr, s.port)
+	s.port++
+	return addr
+}
+
+func (s *suite) deadline() time.Time {
+	return time.Now().Add(s.cfg.Timeout)
+}
+
+func (s *suite) socket(t *testing.T, stype, protocol int) int {
+	t.Helper()
+	sockfd, err := s.dev.Socket(netdev.AF_INET, stype, protocol)
+	if err != nil {
+		t.Fatalf("Socket: %v", err)
+	}
+	t.Cleanup(func() { s.dev.Close(sockfd) })
+	return sockfd
+}
+
+func (s *suite) close(t *testing.T, sockfd int) {
+	t.Helper()
+	if err := s.dev.Close(sockfd); err != nil {
+		t.Errorf("Close(%d): %v", sockfd, err)
+	}
+}
+
+// listen returns a TCP server listening on a new address.
+func (s *suite) listen(t *testing.T) (int, netip.AddrPort) {
+	t.Helper()
+	server := s.socket(t, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	addr := s.nextAddr()
+	if err := s.dev.Bind(server, addr); err != nil {
+		t.Fatalf("Bind(%s): %v", addr, err)
+	}
+	if err := s.dev.Listen(server, 1); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	return server, addr
+}
+
+// connect returns a new TCP connection to the server, as the sockets of the
+// client and of the server side.
+func (s *suite) connect(t *testing.T, server int, addr netip.AddrPort) (int, int) {
+	t.Helper()
+	client := s.socket(t, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+
+	type accepted struct {
+		sockfd int
+		raddr  netip.AddrPort
+		err    error
+	}
+	done := make(chan accepted, 1)
+	go func() {
+		sockfd, raddr, err := s.dev.Accept(server)
+		done <- accepted{sockfd, raddr, err}
+	}()
+
+	if err := s.dev.Connect(client, "", addr); err != nil {
+		t.Fatalf("Connect(%s): %v", addr, err)
+	}
+
+	select {
+	case a := <-done:
+		if a.err != nil {
+			t.Fatalf("Accept: %v", a.err)
+		}
+		if a.raddr.Addr() != s.cfg.Addr {
+			t.Errorf("Accept: remote address %s, want %s", a.raddr, s.cfg.Addr)
+		}
+		t.Cleanup(func() { s.dev.Close(a.sockfd) })
+		return client, a.sockfd
+	case <-time.After(s.cfg.Timeout):
+		t.Fatalf("Accept: no connection after %s", s.cfg.Timeout)
+	}
+	return -1, -1
+}
+
+// send sends all of buf, checking that Send returns either all of it or an
+// error.
+func (s *suite) send(t *testing.T, sockfd int, buf []byte) {
+	t.Helper()
+	n, err := s.dev.Send(sockfd, buf, 0, s.deadline())
+	if err != nil {
+		t.Fatalf("Send(%d bytes): %v", len(buf), err)
+	}
+	if n != len(buf) {
+		t.Fatalf("Send(%d bytes): sent %d bytes without error", len(buf), n)
+	}
+}
+
+// recv receives exactly n bytes, possibly with several calls to Recv.
+func (s *suite) recv(t *testing.T, sockfd int, n int) []byte {
+	t.Helper()
+	data := make([]byte, 0, n)
+	buf := make([]byte, 1024)
+	deadline := s.deadline()
+	for len(data) < n {
+		m, err := s.dev.Recv(sockfd, buf, 0, deadline)
+		if m > 0 {
+			data = append(data, buf[:m]...)
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v after %d of %d bytes", err, len(data), n)
+		}
+		if m <= 0 {
+			t.Fatalf("Recv: returned %d without error", m)
+		}
+	}
+	if len(data) > n {
+		t.Fatalf("Recv: received %d bytes, want %d", len(data), n)
+	}
+	return data
+}
+
+func (s *suite) testSocket(t *testing.T) {
+	for _, test := range []struct {
+		domain, stype, protocol int
+		err                     error
+	}{
+		{netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP, nil},
+		{netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TLS, nil},
+		{netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP, nil},
+		{netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_TCP, netdev.ErrProtocolNotSupported},
+		{netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_UDP, netdev.ErrProtocolNotSupported},
+		{10, netdev.SOCK_STREAM, netdev.IPPROTO_TCP, netdev.ErrFamilyNotSupported}, // AF_INET6
+	} {
+		if test.protocol == netdev.IPPROTO_TLS && s.cfg.SkipTLS {
+			continue
+		}
+		sockfd, err := s.dev.Socket(test.domain, test.stype, test.protocol)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Socket(%d, %d, %d): got error %v, want %v",
+				test.domain, test.stype, test.protocol, err, test.err)
+		}
+		if err != nil {
+			if sockfd != -1 {
+				t.Errorf("Socket(%d, %d, %d): got sockfd %d with error, want -1",
+					test.domain, test.stype, test.protocol, sockfd)
+			}
+			continue
+		}
+		if sockfd < 0 {
+			t.Errorf("Socket(%d, %d, %d): got sockfd %d", test.domain, test.stype, test.protocol, sockfd)
+		}
+		s.close(t, sockfd)
+		if err := s.dev.Close(sockfd); !errors.Is(err, netdev.ErrInvalidSocketFd) {
+			t.Errorf("Close(%d) twice: got error %v, want %v", sockfd, err, netdev.ErrInvalidSocketFd)
+		}
+	}
+}
+
+func (s *suite) testInvalidSocketFd(t *testing.T) {
+	sockfd := s.socket(t, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	s.close(t, sockfd)
+
+	addr := netip.AddrPortFrom(s.cfg.Addr, s.cfg.Port)
+	buf := make([]byte, 4)
+	check := func(op string, err error) {
+		t.Helper()
+		if !errors.Is(err, netdev.ErrInvalidSocketFd) {
+			t.Errorf("%s on closed socket: got error %v, want %v", op, err, netdev.ErrInvalidSocketFd)
+		}
+	}
+	check("Bind", s.dev.Bind(sockfd, addr))
+	check("Connect", s.dev.Connect(sockfd, "", addr))
+	check("Listen", s.dev.Listen(sockfd, 1))
+	_, _, err := s.dev.Accept(sockfd)
+	check("Accept", err)
+	_, err = s.dev.Send(sockfd, buf, 0, s.deadline())
+	check("Send", err)
+	_, err = s.dev.Recv(sockfd, buf, 0, s.deadline())
+	check("Recv", err)
+	check("Close", s.dev.Close(sockfd))
+}
+
+func (s *suite) testTCP(t *testing.T) {
+	s.needLoopback(t)
+	server, addr := s.listen(t)
+	client, conn := s.connect(t, server, addr)
+
+	s.send(t, client, []byte("ping"))
+	if got := s.recv(t, conn, 4); string(got) != "ping" {
+		t.Errorf("server received %q, want %q", got, "ping")
+	}
+	s.send(t, conn, []byte("pong"))
+	if got := s.recv(t, client, 4); string(got) != "pong" {
+		t.Errorf("client received %q, want %q", got, "pong")
+	}
+
+	s.close(t, client)
+	s.close(t, conn)
+	s.close(t, server)
+}
+
+func (s *suite) testRecvTimeout(t *testing.T) {
+	s.needLoopback(t)
+	server, addr := s.listen(t)
+	client, _ := s.connect(t, server, addr)
+
+	const timeout = 200 * time.Millisecond
+	start := time.Now()
+	n, err := s.dev.Recv(client, make([]byte, 16), 0, start.Add(timeout))
+	elapsed := time.Since(start)
+	if !errors.Is(err, netdev.ErrTimeout) {
+		t.Fatalf("Recv: got %d, %v, want %v", n, err, netdev.ErrTimeout)
+	}
+	if elapsed < timeout {
+		t.Errorf("Recv: timed out after %s, before the deadline", elapsed)
+	}
+	if elapsed > timeout+s.cfg.Timeout {
+		t.Errorf("Recv: timed out after %s, long after the deadline", elapsed)
+	}
+
+	// A deadline in the past times out at once
+	n, err = s.dev.Recv(client, make([]byte, 16), 0, time.Now().Add(-time.Second))
+	if !errors.Is(err, netdev.ErrTimeout) {
+		t.Errorf("Recv with past deadline: got %d, %v, want %v", n, err, netdev.ErrTimeout)
+	}
+}
+
+// testSendLarge checks that a buffer larger than the chunks of the devices
+// is sent in full, and received by parts.
+func (s *suite) testSendLarge(t *testing.T) {
+	s.needLoopback(t)
+	server, addr := s.listen(t)
+	client, conn := s.connect(t, server, addr)
+
+	buf := make([]byte, 16*1024)
+	for i := range buf {
+		buf[i] = byte(i * 7)
+	}
+
+	received := make(chan []byte, 1)
+	go func() {
+		data := make([]byte, 0, len(buf))
+		part := make([]byte, 1024)
+		deadline := s.deadline()
+		for len(data) < len(buf) {
+			n, err := s.dev.Recv(conn, part, 0, deadline)
+			if n > 0 {
+				data = append(data, part[:n]...)
+			}
+			if err != nil {
+				break
+			}
+		}
+		received <- data
+	}()
+
+	s.send(t, client, buf)
+	if data := <-received; !bytes.Equal(data, buf) {
+		t.Errorf("received %d bytes, want the %d bytes sent", len(data), len(buf))
+	}
+}
+
+func (s *suite) testRecvClosedPeer(t *testing.T) {
+	s.needLoopback(t)
+	server, addr := s.listen(t)
+	client, conn := s.connect(t, server, addr)
+
+	// The data sent before closing is received first
+	s.send(t, client, []byte("bye"))
+	s.close(t, client)
+
+	var data []byte
+	buf := make([]byte, 16)
+	deadline := s.deadline()
+	for {
+		n, err := s.dev.Recv(conn, buf, 0, deadline)
+		if n > 0 {
+			data = append(data, buf[:n]...)
+		}
+		if err == nil && n <= 0 {
+			t.Fatalf("Recv: returned %d without error", n)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("Recv: got error %v, want %v", err, io.EOF)
+			}
+			break
+		}
+	}
+	if string(data) != "bye" {
+		t.Errorf("received %q, want %q", data, "bye")
+	}
+
+	// And it stays closed
+	if _, err := s.dev.Recv(conn, buf, 0, s.deadline()); !errors.Is(err, io.EOF) {
+		t.Errorf("Recv again: got error %v, want %v", err, io.EOF)
+	}
+}
+
+// testUDP checks datagrams between two connected UDP sockets.  Some devices,
+// such as the ESP8266/ESP32 AT firmware, only receive on connected sockets.
+func (s *suite) testUDP(t *testing.T) {
+	s.needLoopback(t)
+	server := s.socket(t, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	saddr := s.nextAddr()
+	if err := s.dev.Bind(server, saddr); err != nil {
+		t.Fatalf("Bind(%s): %v", saddr, err)
+	}
+
+	client := s.socket(t, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	caddr := s.nextAddr()
+	if err := s.dev.Bind(client, caddr); err != nil {
+		t.Fatalf("Bind(%s): %v", caddr, err)
+	}
+
+	if err := s.dev.Connect(server, "", caddr); err != nil {
+		t.Fatalf("Connect(%s): %v", caddr, err)
+	}
+	if err := s.dev.Connect(client, "", saddr); err != nil {
+		t.Fatalf("Connect(%s): %v", saddr, err)
+	}
+
+	s.send(t, client, []byte("datagram"))
+	buf := make([]byte, 64)
+	n, err := s.dev.Recv(server, buf, 0, s.deadline())
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if string(buf[:n]) != "datagram" {
+		t.Errorf("received %q, want %q", buf[:n], "datagram")
+	}
+
+	// Nothing more to receive
+	if _, err := s.dev.Recv(server, buf, 0, time.Now().Add(100*time.Millisecond)); !errors.Is(err, netdev.ErrTimeout) {
+		t.Errorf("Recv: got error %v, want %v", err, netdev.ErrTimeout)
+	}
+}
+
+func (s *suite) testNoMoreSockets(t *testing.T) {
+	if s.cfg.MaxSockets == 0 {
+		t.Skip("MaxSockets not set")
+	}
+
+	var sockets []int
+	defer func() {
+		for _, sockfd := range sockets {
+			s.dev.Close(sockfd)
+		}
+	}()
+	for i := 0; i < s.cfg.MaxSockets; i++ {
+		sockfd, err := s.dev.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+		if err != nil {
+			t.Fatalf("Socket %d of %d: %v", i+1, s.cfg.MaxSockets, err)
+		}
+		sockets = append(sockets, sockfd)
+	}
+
+	sockfd, err := s.dev.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	if !errors.Is(err, netdev.ErrNoMoreSockets) {
+		if err == nil {
+			sockets = append(sockets, sockfd)
+		}
+		t.Fatalf("Socket %d of %d: got error %v, want %v",
+			s.cfg.MaxSockets+1, s.cfg.MaxSockets, err, netdev.ErrNoMoreSockets)
+	}
+
+	// Closing a socket makes it available again
+	s.close(t, sockets[0])
+	sockfd, err = s.dev.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	if err != nil {
+		t.Fatalf("Socket after Close: %v", err)
+	}
+	sockets[0] = sockfd
+}
+
+// testConcurrent runs clients in parallel, each sending from one goroutine
+// while receiving the echo of the server from another.
+func (s *suite) testConcurrent(t *testing.T) {
+	s.needLoopback(t)
+	const (
+		clients  = 2
+		messages = 20
+		size     = 100
+	)
+
+	server, addr := s.listen(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*clients)
+	for i := 0; i < clients; i++ {
+		client, conn := s.connect(t, server, addr)
+
+		// Echo on the server side
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, size)
+			for total := 0; total < messages*size; {
+				n, err := s.dev.Recv(conn, buf, 0, s.deadline())
+				if err != nil {
+					errs <- errors.New("echo Recv: " + err.Error())
+					return
+				}
+				if _, err := s.dev.Send(conn, buf[:n], 0, s.deadline()); err != nil {
+					errs <- errors.New("echo Send: " + err.Error())
+					return
+				}
+				total += n
+			}
+		}()
+
+		msg := bytes.Repeat([]byte{byte('a' + i)}, size)
+
+		// Send on the client side...
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				if _, err := s.dev.Send(client, msg, 0, s.deadline()); err != nil {
+					errs <- errors.New("client Send: " + err.Error())
+					return
+				}
+			}
+		}()
+
+		// ...while receiving the echo
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, size)
+			for total := 0; total < messages*size; {
+				n, err := s.dev.Recv(client, buf, 0, s.deadline())
+				if err != nil {
+					errs <- errors.New("client Recv: " + err.Error())
+					return
+				}
+				if !bytes.Equal(buf[:n], msg[:n]) {
+					errs <- errors.New("client received the data of another connection")
+					return
+				}
+				total += n
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// testConcurrentServers checks that the connections of two TCP servers
+// listening at the same time go to the right server.
+func (s *suite) testConcurrentServers(t *testing.T) {
+	s.needLoopback(t)
+	if s.cfg.SingleServer {
+		t.Skip("SingleServer set")
+	}
+
+	server1, addr1 := s.listen(t)
+	server2, addr2 := s.listen(t)
+	client1, conn1 := s.connect(t, server1, addr1)
+	client2, conn2 := s.connect(t, server2, addr2)
+
+	s.send(t, client2, []byte("two"))
+	s.send(t, client1, []byte("one"))
+	if got := s.recv(t, conn1, 3); string(got) != "one" {
+		t.Errorf("first server received %q, want %q", got, "one")
+	}
+	if got := s.recv(t, conn2, 3); string(got) != "two" {
+		t.Errorf("second server received %q, want %q", got, "two")
+	}
+}
@@ -18,6 +18,7 @@ var (
 	ErrAuthTypeNoGood    = errors.New("Wifi authorization type not supported")
 	ErrConnectModeNoGood = errors.New("Connect mode not supported")
 	ErrNotSupported      = errors.New("Not supported")
+	ErrScanFailed        = errors.New("Wifi scan failed")
 )
 
 type Event int
@@ -47,6 +48,10 @@ const (
 	AuthTypeOpen             // No authorization required (open)
 	AuthTypeWPA              // WPA authorization
 	AuthTypeWPA2Mixed        // WPA2/WPA mixed authorization
+	AuthTypeWEP              // WEP authorization
+	AuthTypeWPA3             // WPA3 authorization
+	AuthTypeWPA3Mixed        // WPA3/WPA2 mixed authorization
+	AuthTypeUnknown          // Other authorization, e.g. enterprise
 )
 
 const DefaultConnectTimeout = 10 * time.Second
@@ -100,3 +105,38 @@ type Netlinker interface {
 	// GetHardwareAddr returns device MAC address
 	GetHardwareAddr() (net.HardwareAddr, error)
 }
+
+// AccessPoint is a Wifi access point found by a scan
+type AccessPoint struct {
+
+	// SSID of Wifi AP
+	Ssid string
+
+	// MAC address of Wifi AP
+	Bssid net.HardwareAddr
+
+	// Signal strength in dBm
+	Rssi int
+
+	// Wifi channel
+	Channel int
+
+	// Wifi authorization type
+	AuthType
+}
+
+// Scanner is an optional Netlinker interface for Wifi devices able to list
+// the nearby access points.  Check if a Netlinker implements it with a type
+// assertion:
+//
+//	if scanner, ok := link.(netlink.Scanner); ok {
+//		aps, err := scanner.NetScan()
+//		...
+//	}
+
+type Scanner interface {
+
+	// NetScan scans for Wifi access points.  The device doesn't need to
+	// be connected to a network.
+	NetScan() ([]AccessPoint, error)
+}
@@ -0,0 +1,17 @@
+//go:build !tinygo
+
+package probe
+
+import (
+	"tinygo.org/x/drivers/hostnet"
+	"tinygo.org/x/drivers/netdev"
+	"tinygo.org/x/drivers/netlink"
+)
+
+// Probe returns the host network device when not running on TinyGo.  Go's
+// "net" package already uses the host networking stack, so the device is not
+// set with netdev.UseNetdev.
+func Probe() (netlink.Netlinker, netdev.Netdever) {
+	dev := hostnet.New(nil)
+	return dev, dev
+}
@@ -0,0 +1,117 @@
+package provision
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"time"
+
+	"tinygo.org/x/drivers/netdev"
+)
+
+const (
+	dnsHeaderLen = 12
+	dnsTypeA     = 1
+	dnsClassIN   = 1
+	dnsTTL       = 60 // seconds
+)
+
+// serveDNS answers the DNS queries received on the UDP socket sockfd with the
+// address of the access point, until stop is closed, so the clients of the
+// access point open the form for any host name.
+func (p *provisioner) serveDNS(pc netdev.PacketConner, sockfd int, stop chan struct{}) {
+	var buf [512]byte
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n, from, err := pc.RecvFrom(sockfd, buf[:], 0, time.Now().Add(pollInterval))
+		switch {
+		case err == netdev.ErrTimeout, err == nil && n <= 0:
+			continue
+		case err != nil:
+			return
+		}
+		if reply := dnsAnswer(buf[:n], p.addr); reply != nil {
+			pc.SendTo(sockfd, reply, 0, from, time.Now().Add(pollInterval))
+		}
+	}
+}
+
+// dnsAnswer returns the reply to a DNS query, answering the A queries with
+// addr, or nil if the packet is not a query.
+func dnsAnswer(query []byte, addr netip.Addr) []byte {
+	if len(query) < dnsHeaderLen {
+		return nil
+	}
+	flags := binary.BigEndian.Uint16(query[2:])
+	if flags&0x8000 != 0 || flags&0x7800 != 0 {
+		// Not a standard query
+		return nil
+	}
+	if binary.BigEndian.Uint16(query[4:]) != 1 {
+		// Only a single question is supported, as by most servers
+		return nil
+	}
+
+	// Skip the labels of the question name
+	i := dnsHeaderLen
+	for i < len(query) && query[i] != 0 {
+		if query[i]&0xC0 != 0 {
+			return nil
+		}
+		i += 1 + int(query[i])
+	}
+	i++
+	if i+4 > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[i:])
+	qclass := binary.BigEndian.Uint16(query[i+2:])
+	question := query[dnsHeaderLen : i+4]
+
+	reply := make([]byte, 0, dnsHeaderLen+len(question)+16)
+	reply = append(reply, query[0], query[1]) // ID
+	// Response, authoritative, recursion desired as queried, and available
+	reply = append(reply, 0x84|query[2]&0x01, 0x80)
+	reply = append(reply, 0, 1) // questions
+	if qtype == dnsTypeA && qclass == dnsClassIN && addr.Is4() {
+		reply = append(reply, 0, 1) // answers
+	} else {
+		reply = append(reply, 0, 0)
+	}
+	reply = append(reply, 0, 0, 0, 0) // authority and additional records
+	reply = append(reply, question...)
+
+	if qtype == dnsTypeA && qclass == dnsClassIN && addr.Is4() {
+		ip := addr.As4()
+		reply = append(reply,
+			0xC0, dnsHeaderLen, // name of the question
+			0, dnsTypeA,
+			0, dnsClassIN,
+			0, 0, 0, dnsTTL,
+			0, 4) // data length
+		reply = append(reply, ip[:]...)
+	}
+
+	return reply
+}
+
+// dnsSocket returns a UDP socket for the DNS server on port.
+func (p *provisioner) dnsSocket(port uint16) (int, error) {
+	sockfd, err := p.dev.Socket(netdev.AF_INET, netdev.SOCK_DGRAM, netdev.IPPROTO_UDP)
+	if err != nil {
+		return -1, err
+	}
+	if err := p.dev.Bind(sockfd, netip.AddrPortFrom(netip.IPv4Unspecified(), port)); err != nil {
+		p.dev.Close(sockfd)
+		return -1, err
+	}
+	if err := p.dev.Listen(sockfd, 1); err != nil {
+		p.dev.Close(sockfd)
+		return -1, err
+	}
+	return sockfd, nil
+}
@@ -0,0 +1,246 @@
+package provision
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tinygo.org/x/drivers/netdev"
+	"tinygo.org/x/drivers/netlink"
+)
+
+const (
+	maxRequestLength = 2048
+	requestTimeout   = 5 * time.Second
+)
+
+var (
+	errRequestTooLong = errors.New("HTTP request too long")
+	errBadRequest     = errors.New("Bad HTTP request")
+)
+
+type request struct {
+	method string
+	path   string
+	body   []byte
+}
+
+// serveHTTP serves the form on port, until the credentials are posted
+func (p *provisioner) serveHTTP(port uint16) (Credentials, error) {
+	sockfd, err := p.dev.Socket(netdev.AF_INET, netdev.SOCK_STREAM, netdev.IPPROTO_TCP)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer p.dev.Close(sockfd)
+
+	if err := p.dev.Bind(sockfd, netip.AddrPortFrom(netip.IPv4Unspecified(), port)); err != nil {
+		return Credentials{}, err
+	}
+	if err := p.dev.Listen(sockfd, 1); err != nil {
+		return Credentials{}, err
+	}
+
+	for {
+		clientfd, _, err := p.dev.Accept(sockfd)
+		if err != nil {
+			return Credentials{}, err
+		}
+		creds, ok := p.handle(clientfd)
+		p.dev.Close(clientfd)
+		if ok {
+			return creds, nil
+		}
+	}
+}
+
+// handle answers a client request, and returns the credentials if the client
+// posted valid ones
+func (p *provisioner) handle(sockfd int) (Credentials, bool) {
+	req, err := p.readRequest(sockfd)
+	switch err {
+	case nil:
+	case errBadRequest, errRequestTooLong:
+		p.respond(sockfd, "400 Bad Request", "", "")
+		return Credentials{}, false
+	default:
+		return Credentials{}, false
+	}
+
+	switch {
+	case req.path != "/":
+		// Captive portal: send any other page, as the connectivity
+		// checks of the clients, to the form
+		p.respond(sockfd, "302 Found", "Location: http://"+p.addr.String()+"/\r\n", "")
+	case req.method == "GET":
+		p.respond(sockfd, "200 OK", "Content-Type: text/html; charset=utf-8\r\n", p.form())
+	case req.method == "POST":
+		creds, err := parseForm(req.body)
+		if err != nil {
+			p.message = err.Error()
+			p.respond(sockfd, "200 OK", "Content-Type: text/html; charset=utf-8\r\n", p.form())
+			return Credentials{}, false
+		}
+		p.respond(sockfd, "200 OK", "Content-Type: text/html; charset=utf-8\r\n", connecting(creds))
+		return creds, true
+	default:
+		p.respond(sockfd, "405 Method Not Allowed", "Allow: GET, POST\r\n", "")
+	}
+
+	return Credentials{}, false
+}
+
+// readRequest reads a request, with its body, from the client
+func (p *provisioner) readRequest(sockfd int) (request, error) {
+	var buf [maxRequestLength]byte
+	var n int
+
+	deadline := time.Now().Add(requestTimeout)
+	for {
+		if n == len(buf) {
+			return request{}, errRequestTooLong
+		}
+		m, err := p.dev.Recv(sockfd, buf[n:], 0, deadline)
+		if m > 0 {
+			n += m
+		}
+		req, ok, perr := parseRequest(buf[:n])
+		if perr != nil {
+			return request{}, perr
+		}
+		if ok {
+			return req, nil
+		}
+		if err != nil {
+			return request{}, err
+		}
+	}
+}
+
+// parseRequest parses a request, and returns false if it is not complete yet
+func parseRequest(data []byte) (request, bool, error) {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end == -1 {
+		return request{}, false, nil
+	}
+
+	var req request
+	lines := strings.Split(string(data[:end]), "\r\n")
+	if fields := strings.Fields(lines[0]); len(fields) >= 2 {
+		req.method = fields[0]
+		req.path, _, _ = strings.Cut(fields[1], "?")
+	}
+
+	length := 0
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Content-Length") {
+			var err error
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return request{}, false, errBadRequest
+			}
+		}
+	}
+
+	// The whole request must fit in the buffer of readRequest
+	if length > maxRequestLength-(end+4) {
+		return request{}, false, errRequestTooLong
+	}
+
+	body := data[end+4:]
+	if len(body) < length {
+		return request{}, false, nil
+	}
+	req.body = body[:length]
+
+	return req, true, nil
+}
+
+// parseForm returns the credentials posted with the form
+func parseForm(body []byte) (Credentials, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	creds := Credentials{
+		Ssid:       values.Get("ssid"),
+		Passphrase: values.Get("passphrase"),
+	}
+
+	switch {
+	case creds.Ssid == "":
+		return Credentials{}, netlink.ErrMissingSSID
+	case creds.Passphrase != "" && len(creds.Passphrase) < 8:
+		return Credentials{}, netlink.ErrShortPassphrase
+	case len(creds.Ssid) > maxSsid || len(creds.Passphrase) > maxPassphrase:
+		return Credentials{}, ErrCredentialsTooLong
+	}
+
+	return creds, nil
+}
+
+// respond sends a response to the client, and the client must then be closed
+func (p *provisioner) respond(sockfd int, status, header, body string) {
+	resp := "HTTP/1.1 " + status + "\r\n" +
+		header +
+		"Cache-Control: no-store\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n" +
+		"Connection: close\r\n" +
+		"\r\n" +
+		body
+	p.dev.Send(sockfd, []byte(resp), 0, time.Now().Add(requestTimeout))
+}
+
+const pageHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Wifi Setup</title>
+</head>
+<body>
+<h1>Wifi Setup</h1>
+`
+
+const pageTail = `</body>
+</html>
+`
+
+// form returns the page of the form, suggesting the networks in range
+func (p *provisioner) form() string {
+	var b strings.Builder
+
+	b.WriteString(pageHead)
+	if p.message != "" {
+		b.WriteString("<p><strong>" + html.EscapeString(p.message) + "</strong></p>\n")
+	}
+	b.WriteString(`<form method="post" action="/">
+<p><label>Network<br><input name="ssid" list="networks" maxlength="32" required></label></p>
+<datalist id="networks">
+`)
+	for _, ssid := range p.networks {
+		b.WriteString(`<option value="` + html.EscapeString(ssid) + `">` + "\n")
+	}
+	b.WriteString(`</datalist>
+<p><label>Passphrase<br><input name="passphrase" type="password" maxlength="64"></label></p>
+<p><input type="submit" value="Connect"></p>
+</form>
+`)
+	b.WriteString(pageTail)
+
+	return b.String()
+}
+
+// connecting returns the page shown once the credentials are posted
+func connecting(creds Credentials) string {
+	return pageHead +
+		"<p>Connecting to " + html.EscapeString(creds.Ssid) + "...</p>\n" +
+		"<p>If the Wifi Setup network shows up again, join it to retry.</p>\n" +
+		pageTail
+}
@@ -0,0 +1,205 @@
+// Package provision collects the Wifi credentials of a device from its user,
+// on first boot, with a captive portal.
+//
+// The device starts a Wifi access point, and serves a form to enter the SSID
+// and passphrase of the Wifi network.  DNS queries are answered with the
+// address of the device, so phones and laptops joining the access point open
+// the form on their own.  Once the credentials are posted, the access point is
+// stopped, the device connects to the Wifi network, and the credentials are
+// saved to a Store to connect directly on the next boot:
+//
+//	link, dev := probe.Probe()
+//	store := provision.NewDeviceStore(flash, 0)
+//	err := provision.Connect(link, dev, &provision.Config{Store: store})
+//
+// The captive DNS server needs a netdev.PacketConner device.  Otherwise, the
+// form is served at the address of the access point only.
+package provision // import "tinygo.org/x/drivers/netlink/provision"
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"tinygo.org/x/drivers/netdev"
+	"tinygo.org/x/drivers/netlink"
+)
+
+const (
+	defaultSsid     = "TinyGo Setup"
+	defaultRetries  = 3
+	defaultHTTPPort = 80
+	defaultDNSPort  = 53
+
+	// Polling interval of the servers, to check if they must stop
+	pollInterval = 100 * time.Millisecond
+)
+
+type Config struct {
+	// AP are the parameters of the access point started to collect the
+	// credentials.  The ConnectMode is always ConnectModeAP.  The default
+	// SSID is "TinyGo Setup", and the access point is open unless a
+	// Passphrase is set.
+	AP netlink.ConnectParams
+
+	// STA are the parameters to connect to the Wifi network, with the
+	// collected SSID and passphrase.  Retries defaults to 3, so the form
+	// is served again if the credentials are wrong.
+	STA netlink.ConnectParams
+
+	// Store persists the credentials.  If nil, the credentials are
+	// collected on each call to Connect.
+	Store Store
+
+	// HTTPPort is the port of the form.  The default zero value means 80.
+	HTTPPort uint16
+
+	// DNSPort is the port of the captive DNS server.  The default zero
+	// value means 53.
+	DNSPort uint16
+}
+
+type provisioner struct {
+	link netlink.Netlinker
+	dev  netdev.Netdever
+	cfg  *Config
+
+	// Address of the access point
+	addr netip.Addr
+
+	// SSIDs of the networks in range, suggested in the form
+	networks []string
+
+	// Message shown in the form, e.g. why the last credentials failed
+	message string
+}
+
+// Connect connects link to a Wifi network in station mode, with the
+// credentials of the store.  If there are none, or they fail, Connect starts
+// an access point and serves the form to collect them from the user, until
+// the device connects to a network.  The credentials are then saved to the
+// store.
+func Connect(link netlink.Netlinker, dev netdev.Netdever, cfg *Config) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	p := &provisioner{link: link, dev: dev, cfg: cfg}
+
+	if cfg.Store != nil {
+		creds, err := cfg.Store.Load()
+		switch err {
+		case nil:
+			if err := p.connect(creds); err == nil {
+				return nil
+			}
+			p.message = fmt.Sprintf("Failed to connect to %s", creds.Ssid)
+		case ErrNoCredentials:
+		default:
+			return err
+		}
+	}
+
+	for {
+		creds, err := p.provision()
+		if err != nil {
+			return err
+		}
+
+		if err := p.connect(creds); err != nil {
+			p.message = fmt.Sprintf("Failed to connect to %s: %s", creds.Ssid, err)
+			continue
+		}
+
+		if cfg.Store != nil {
+			return cfg.Store.Save(creds)
+		}
+		return nil
+	}
+}
+
+// connect connects to the Wifi network in station mode
+func (p *provisioner) connect(creds Credentials) error {
+	params := p.cfg.STA
+	params.ConnectMode = netlink.ConnectModeSTA
+	params.Ssid = creds.Ssid
+	params.Passphrase = creds.Passphrase
+	if params.Retries == 0 {
+		params.Retries = defaultRetries
+	}
+	return p.link.NetConnect(&params)
+}
+
+// provision starts the access point and serves the form, until the user posts
+// the credentials
+func (p *provisioner) provision() (Credentials, error) {
+
+	// Scan before starting the access point, as the radio is then busy
+	if scanner, ok := p.link.(netlink.Scanner); ok {
+		if aps, err := scanner.NetScan(); err == nil {
+			p.networks = ssids(aps)
+		}
+	}
+
+	params := p.cfg.AP
+	params.ConnectMode = netlink.ConnectModeAP
+	if params.Ssid == "" {
+		params.Ssid = defaultSsid
+	}
+	if params.Passphrase == "" {
+		params.AuthType = netlink.AuthTypeOpen
+	}
+	if err := p.link.NetConnect(&params); err != nil {
+		return Credentials{}, err
+	}
+	defer p.link.NetDisconnect()
+
+	addr, err := p.dev.Addr()
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.addr = addr
+
+	if pc, ok := p.dev.(netdev.PacketConner); ok {
+		port := p.cfg.DNSPort
+		if port == 0 {
+			port = defaultDNSPort
+		}
+		sockfd, err := p.dnsSocket(port)
+		if err != nil {
+			return Credentials{}, err
+		}
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			p.serveDNS(pc, sockfd, stop)
+			close(done)
+		}()
+		defer func() {
+			close(stop)
+			<-done
+			p.dev.Close(sockfd)
+		}()
+	}
+
+	port := p.cfg.HTTPPort
+	if port == 0 {
+		port = defaultHTTPPort
+	}
+	return p.serveHTTP(port)
+}
+
+// ssids returns the SSIDs of the access points, without duplicates and hidden
+// networks
+func ssids(aps []netlink.AccessPoint) []string {
+	var ssids []string
+	seen := make(map[string]bool)
+	for _, ap := range aps {
+		if ap.Ssid == "" || seen[ap.Ssid] {
+			continue
+		}
+		seen[ap.Ssid] = true
+		ssids = append(ssids, ap.Ssid)
+	}
+	return ssids
+}
@@ -0,0 +1,299 @@
+//go:build !tinygo
+
+package provision
+
+import (
+	"bufio"
+	"encoding/binary"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/hostnet"
+	"tinygo.org/x/drivers/netlink"
+)
+
+const (
+	testHTTPPort = 42180
+	testDNSPort  = 42153
+)
+
+// memory is a block device in memory, erased to 0xFF as flash memories
+type memory struct {
+	data   []byte
+	erased int
+}
+
+func newMemory(size int) *memory {
+	m := &memory{data: make([]byte, size)}
+	for i := range m.data {
+		m.data[i] = 0xFF
+	}
+	return m
+}
+
+func (m *memory) ReadAt(buf []byte, off int64) (int, error) {
+	return copy(buf, m.data[off:]), nil
+}
+
+func (m *memory) WriteAt(buf []byte, off int64) (int, error) {
+	return copy(m.data[off:], buf), nil
+}
+
+func (m *memory) EraseBlockSize() int64 { return 256 }
+
+func (m *memory) EraseBlocks(start, len int64) error {
+	for i := start * 256; i < (start+len)*256; i++ {
+		m.data[i] = 0xFF
+	}
+	m.erased++
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	c := qt.New(t)
+	m := newMemory(1024)
+	s := NewDeviceStore(m, 256)
+
+	_, err := s.Load()
+	c.Assert(err, qt.Equals, ErrNoCredentials)
+
+	creds := Credentials{Ssid: "home", Passphrase: "secret123"}
+	c.Assert(s.Save(creds), qt.IsNil)
+	c.Assert(m.erased, qt.Equals, 1)
+	got, err := s.Load()
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, creds)
+
+	// An open network
+	creds = Credentials{Ssid: "cafe"}
+	c.Assert(s.Save(creds), qt.IsNil)
+	got, err = s.Load()
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, creds)
+
+	// Corrupted
+	m.data[256+recordHeader] ^= 0x01
+	_, err = s.Load()
+	c.Assert(err, qt.Equals, ErrNoCredentials)
+
+	c.Assert(s.Save(Credentials{Ssid: strings.Repeat("x", 33)}), qt.Equals, ErrCredentialsTooLong)
+}
+
+func TestParseRequest(t *testing.T) {
+	c := qt.New(t)
+
+	req, ok, err := parseRequest([]byte("POST /?x=1 HTTP/1.1\r\nContent-Length: 4\r\n\r\nssid"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(req.method, qt.Equals, "POST")
+	c.Assert(req.path, qt.Equals, "/")
+	c.Assert(string(req.body), qt.Equals, "ssid")
+
+	// Incomplete
+	_, ok, err = parseRequest([]byte("POST / HTTP/1.1\r\nContent-Length: 4\r\n\r\nss"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+
+	for _, length := range []string{"-1", "x", "2048", "9223372036854775807"} {
+		_, _, err = parseRequest([]byte("POST / HTTP/1.1\r\nContent-Length: " + length + "\r\n\r\n"))
+		c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("Content-Length: %s", length))
+	}
+}
+
+// link records the parameters passed to NetConnect, failing to connect to the
+// networks with the wrong passphrase
+type link struct {
+	*hostnet.Device
+	passphrase string
+
+	mu     sync.Mutex
+	params []netlink.ConnectParams
+}
+
+func (l *link) NetConnect(params *netlink.ConnectParams) error {
+	l.mu.Lock()
+	l.params = append(l.params, *params)
+	l.mu.Unlock()
+	if params.ConnectMode == netlink.ConnectModeSTA && params.Passphrase != l.passphrase {
+		return netlink.ErrConnectFailed
+	}
+	return l.Device.NetConnect(params)
+}
+
+func (l *link) modes() []netlink.ConnectMode {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var modes []netlink.ConnectMode
+	for _, params := range l.params {
+		modes = append(modes, params.ConnectMode)
+	}
+	return modes
+}
+
+func newLink(passphrase string) *link {
+	return &link{
+		Device: hostnet.New(&hostnet.Config{
+			AccessPoints: []netlink.AccessPoint{
+				{Ssid: "home", AuthType: netlink.AuthTypeWPA2},
+				{Ssid: "<cafe>", AuthType: netlink.AuthTypeOpen},
+				{Ssid: "home", AuthType: netlink.AuthTypeWPA2},
+			},
+		}),
+		passphrase: passphrase,
+	}
+}
+
+func TestConnectStored(t *testing.T) {
+	c := qt.New(t)
+	l := newLink("secret123")
+	defer l.NetDisconnect()
+
+	store := NewDeviceStore(newMemory(256), 0)
+	c.Assert(store.Save(Credentials{Ssid: "home", Passphrase: "secret123"}), qt.IsNil)
+
+	c.Assert(Connect(l, l, &Config{Store: store}), qt.IsNil)
+	c.Assert(l.params, qt.HasLen, 1)
+	c.Assert(l.params[0].Ssid, qt.Equals, "home")
+	c.Assert(l.params[0].Retries, qt.Equals, defaultRetries)
+}
+
+// get sends a GET request to the form, without following redirects
+func get(c *qt.C, path string) *http.Response {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		// Wait for the server
+		resp, err = client.Get("http://127.0.0.1:42180" + path)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.Assert(err, qt.IsNil)
+	return resp
+}
+
+func post(c *qt.C, ssid, passphrase string) string {
+	resp, err := http.PostForm("http://127.0.0.1:42180/",
+		url.Values{"ssid": {ssid}, "passphrase": {passphrase}})
+	c.Assert(err, qt.IsNil)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+	return string(body)
+}
+
+// lookup queries the captive DNS server for the A record of name
+func lookup(c *qt.C, name string) net.IP {
+	conn, err := net.Dial("udp4", "127.0.0.1:42153")
+	c.Assert(err, qt.IsNil)
+	defer conn.Close()
+
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, 0, dnsTypeA, 0, dnsClassIN)
+	_, err = conn.Write(query)
+	c.Assert(err, qt.IsNil)
+
+	reply := make([]byte, 512)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(reply)
+	c.Assert(err, qt.IsNil)
+	reply = reply[:n]
+
+	c.Assert(reply[:2], qt.DeepEquals, query[:2])
+	c.Assert(binary.BigEndian.Uint16(reply[6:]), qt.Equals, uint16(1))
+	return net.IP(reply[n-4:])
+}
+
+func TestProvision(t *testing.T) {
+	c := qt.New(t)
+	l := newLink("secret123")
+	defer l.NetDisconnect()
+
+	m := newMemory(256)
+	store := NewDeviceStore(m, 0)
+	cfg := &Config{
+		Store:    store,
+		HTTPPort: testHTTPPort,
+		DNSPort:  testDNSPort,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- Connect(l, l, cfg)
+	}()
+
+	// The connectivity checks are redirected to the form
+	addr, err := l.Addr()
+	c.Assert(err, qt.IsNil)
+	resp := get(c, "/generate_204")
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusFound)
+	c.Assert(resp.Header.Get("Location"), qt.Equals, "http://"+addr.String()+"/")
+
+	// Any name resolves to the access point
+	c.Assert(lookup(c, "connectivitycheck.gstatic.com").String(), qt.Equals, addr.String())
+
+	// The form suggests the networks in range
+	resp = get(c, "/")
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	c.Assert(strings.Count(string(body), `<option value="home">`), qt.Equals, 1)
+	c.Assert(string(body), qt.Contains, `<option value="&lt;cafe&gt;">`)
+
+	// Bad requests don't stop the server
+	for _, length := range []string{"-1", "100000"} {
+		conn, err := net.Dial("tcp4", "127.0.0.1:42180")
+		c.Assert(err, qt.IsNil)
+		_, err = conn.Write([]byte("POST / HTTP/1.1\r\nContent-Length: " + length + "\r\n\r\n"))
+		c.Assert(err, qt.IsNil)
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		conn.Close()
+		c.Assert(err, qt.IsNil)
+		c.Assert(status, qt.Equals, "HTTP/1.1 400 Bad Request\r\n")
+	}
+
+	// Invalid credentials show the form again
+	c.Assert(post(c, "home", "short"), qt.Contains, html.EscapeString(netlink.ErrShortPassphrase.Error()))
+
+	// Wrong credentials serve the form again
+	c.Assert(post(c, "home", "wrong1234"), qt.Contains, "Connecting to home")
+	resp = get(c, "/")
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(body), qt.Contains, "Failed to connect to home")
+
+	c.Assert(post(c, "home", "secret123"), qt.Contains, "Connecting to home")
+	c.Assert(<-done, qt.IsNil)
+
+	c.Assert(l.modes(), qt.DeepEquals, []netlink.ConnectMode{
+		netlink.ConnectModeAP, netlink.ConnectModeSTA, // wrong passphrase
+		netlink.ConnectModeAP, netlink.ConnectModeSTA,
+	})
+	c.Assert(l.params[0].Ssid, qt.Equals, defaultSsid)
+	c.Assert(l.params[0].AuthType, qt.Equals, netlink.AuthType(netlink.AuthTypeOpen))
+
+	creds, err := store.Load()
+	c.Assert(err, qt.IsNil)
+	c.Assert(creds, qt.Equals, Credentials{Ssid: "home", Passphrase: "secret123"})
+}
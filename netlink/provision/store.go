@@ -0,0 +1,124 @@
+package provision
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	ErrNoCredentials      = errors.New("No Wifi credentials stored")
+	ErrCredentialsTooLong = errors.New("Wifi credentials too long")
+)
+
+// Credentials are the credentials of a Wifi network
+type Credentials struct {
+	Ssid       string
+	Passphrase string
+}
+
+// Store persists the Wifi credentials
+type Store interface {
+
+	// Load returns the stored credentials, or ErrNoCredentials if there
+	// are none
+	Load() (Credentials, error)
+
+	// Save stores the credentials
+	Save(creds Credentials) error
+}
+
+// BlockDevice is a storage device, such as the flash or at24cx drivers
+type BlockDevice interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// eraser is implemented by the block devices that must be erased before
+// writing, such as flash memories
+type eraser interface {
+	EraseBlockSize() int64
+	EraseBlocks(start, len int64) error
+}
+
+// Layout of the credentials record:
+//
+//	magic		4 bytes
+//	len(ssid)	1 byte
+//	len(passphrase)	1 byte
+//	ssid
+//	passphrase
+//	crc32		4 bytes, little endian, of all of the above
+const (
+	recordMagic     = "WiFi"
+	recordHeader    = len(recordMagic) + 2
+	maxSsid         = 32
+	maxPassphrase   = 64
+	maxRecordLength = recordHeader + maxSsid + maxPassphrase + 4
+)
+
+type deviceStore struct {
+	dev    BlockDevice
+	offset int64
+}
+
+// NewDeviceStore returns a Store saving the credentials on dev at offset,
+// using up to 106 bytes.  If dev must be erased before writing, as flash
+// memories, offset must be aligned to the erase blocks of dev, and the
+// erase block at offset is erased on Save.
+func NewDeviceStore(dev BlockDevice, offset int64) Store {
+	return &deviceStore{dev: dev, offset: offset}
+}
+
+func (s *deviceStore) Load() (Credentials, error) {
+	var buf [maxRecordLength]byte
+
+	if _, err := s.dev.ReadAt(buf[:recordHeader], s.offset); err != nil {
+		return Credentials{}, err
+	}
+	if string(buf[:len(recordMagic)]) != recordMagic {
+		return Credentials{}, ErrNoCredentials
+	}
+	ssidLen, passLen := int(buf[4]), int(buf[5])
+	if ssidLen > maxSsid || passLen > maxPassphrase {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	n := recordHeader + ssidLen + passLen
+	if _, err := s.dev.ReadAt(buf[recordHeader:n+4], s.offset+int64(recordHeader)); err != nil {
+		return Credentials{}, err
+	}
+	if crc32.ChecksumIEEE(buf[:n]) != binary.LittleEndian.Uint32(buf[n:]) {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	return Credentials{
+		Ssid:       string(buf[recordHeader : recordHeader+ssidLen]),
+		Passphrase: string(buf[recordHeader+ssidLen : n]),
+	}, nil
+}
+
+func (s *deviceStore) Save(creds Credentials) error {
+	if len(creds.Ssid) > maxSsid || len(creds.Passphrase) > maxPassphrase {
+		return ErrCredentialsTooLong
+	}
+
+	buf := make([]byte, 0, maxRecordLength)
+	buf = append(buf, recordMagic...)
+	buf = append(buf, byte(len(creds.Ssid)), byte(len(creds.Passphrase)))
+	buf = append(buf, creds.Ssid...)
+	buf = append(buf, creds.Passphrase...)
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(buf))
+	buf = append(buf, crc[:]...)
+
+	if e, ok := s.dev.(eraser); ok {
+		if err := e.EraseBlocks(s.offset/e.EraseBlockSize(), 1); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.dev.WriteAt(buf, s.offset)
+	return err
+}
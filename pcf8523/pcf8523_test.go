@@ -79,6 +79,27 @@ func TestDevice_ReadTime(t *testing.T) {
 	assertEquals(t, actualPointInTime, expectedPointInTime)
 }
 
+func TestDevice_ClockTicks(t *testing.T) {
+	bus := tester.NewI2CBus(t)
+	fake := tester.NewPCF8523(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.Now = func() time.Time { return now }
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	err := dev.Reset()
+	assertNoError(t, err)
+
+	pointInTime := time.Date(2023, 12, 31, 23, 59, 30, 0, time.UTC)
+	err = dev.SetTime(pointInTime)
+	assertNoError(t, err)
+
+	now = now.Add(45 * time.Second)
+	actualPointInTime, err := dev.ReadTime()
+	assertNoError(t, err)
+	assertEquals(t, actualPointInTime, pointInTime.Add(45*time.Second))
+}
+
 func assertNoError(t testing.TB, e error) {
 	if e != nil {
 		t.Fatalf("unexpected error: %v", e)
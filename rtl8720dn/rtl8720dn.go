@@ -6,6 +6,8 @@
 package rtl8720dn // import "tinygo.org/x/drivers/rtl8720dn"
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -383,6 +385,111 @@ func (r *rtl8720dn) NetNotify(cb func(netlink.Event)) {
 	r.notifyCb = cb
 }
 
+// Size of the wifi_ap_record_t scan records of the firmware:
+//
+//	uint8_t bssid[6];		// offset 0
+//	uint8_t ssid[33];		// offset 6
+//	uint8_t primary;		// offset 39, channel
+//	wifi_second_chan_t second;	// offset 40
+//	int8_t rssi;			// offset 44
+//	wifi_auth_mode_t authmode;	// offset 48
+//	...				// ciphers, antenna, phy modes, country
+const apRecordSize = 80
+
+// Max number of AP records in an RPC reply: the payload holds the reply
+// header (8 bytes), the length of the records (4 bytes), and the result (4
+// bytes).
+const maxApRecords = uint16((len(payload) - 16) / apRecordSize)
+
+func toAuthType(authmode uint32) netlink.AuthType {
+	switch authmode {
+	case 0: // WIFI_AUTH_OPEN
+		return netlink.AuthTypeOpen
+	case 1: // WIFI_AUTH_WEP
+		return netlink.AuthTypeWEP
+	case 2: // WIFI_AUTH_WPA_PSK
+		return netlink.AuthTypeWPA
+	case 3: // WIFI_AUTH_WPA2_PSK
+		return netlink.AuthTypeWPA2
+	case 4: // WIFI_AUTH_WPA_WPA2_PSK
+		return netlink.AuthTypeWPA2Mixed
+	case 6: // WIFI_AUTH_WPA3_PSK
+		return netlink.AuthTypeWPA3
+	case 7: // WIFI_AUTH_WPA2_WPA3_PSK
+		return netlink.AuthTypeWPA3Mixed
+	}
+	return netlink.AuthTypeUnknown
+}
+
+func (r *rtl8720dn) NetScan() ([]netlink.AccessPoint, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[NetScan]\r\n")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Start the device, if not connected yet
+	if !r.netConnected {
+		r.showDriver()
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if result := r.rpc_wifi_scan_start(); result != 0 {
+		return nil, netlink.ErrScanFailed
+	}
+
+	// Wait for the scan to complete
+	for i := 0; r.rpc_wifi_is_scaning(); i++ {
+		if i == 100 {
+			return nil, netlink.ErrScanFailed
+		}
+		// Unlock while we sleep, so others can make progress
+		r.mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		r.mu.Lock()
+	}
+
+	n := r.rpc_wifi_scan_get_ap_num()
+	if n == 0 {
+		return nil, nil
+	}
+
+	// The records are returned in a single RPC reply, which must fit in
+	// payload with its header, length and result.  As with
+	// esp_wifi_scan_get_ap_records, the scan list is freed after the call,
+	// so get as many records as fit.
+	if n > maxApRecords {
+		n = maxApRecords
+	}
+
+	records := make([]byte, int(n)*apRecordSize)
+	if result := r.rpc_wifi_scan_get_ap_records(n, records); result != 0 {
+		return nil, netlink.ErrScanFailed
+	}
+
+	aps := make([]netlink.AccessPoint, n)
+	for i := range aps {
+		record := records[i*apRecordSize : (i+1)*apRecordSize]
+		ssid := record[6:39]
+		if end := bytes.IndexByte(ssid, 0); end != -1 {
+			ssid = ssid[:end]
+		}
+		aps[i] = netlink.AccessPoint{
+			Ssid:     string(ssid),
+			Bssid:    net.HardwareAddr(append([]byte(nil), record[0:6]...)),
+			Rssi:     int(int8(record[44])),
+			Channel:  int(record[39]),
+			AuthType: toAuthType(binary.LittleEndian.Uint32(record[48:52])),
+		}
+	}
+
+	return aps, nil
+}
+
 func (r *rtl8720dn) GetHostByName(name string) (netip.Addr, error) {
 
 	if debugging(debugNetdev) {
@@ -789,6 +896,101 @@ func (r *rtl8720dn) Recv(sockfd int, buf []byte, flags int,
 	}
 }
 
+// RecvFrom receives a datagram from any address on a bound UDP socket, and
+// returns its source address
+func (r *rtl8720dn) RecvFrom(sockfd int, buf []byte, flags int,
+	deadline time.Time) (int, netip.AddrPort, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[RecvFrom] sockfd: %d, len(buf): %d, flags: %d\r\n",
+			sockfd, len(buf), flags)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sock = sock(sockfd)
+	socket, ok := r.sockets[sock]
+	if !ok {
+		return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+	}
+	var length = len(buf)
+	var name = ipToName(netip.AddrPort{})
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netip.AddrPort{}, netdev.ErrProtocolNotSupported
+	}
+
+	// Limit length read size to chunk large read requests
+	if length > 1436 {
+		length = 1436
+	}
+
+	for {
+		// Check if we've timed out
+		if !deadline.IsZero() {
+			if time.Now().After(deadline) {
+				return -1, netip.AddrPort{}, netdev.ErrTimeout
+			}
+		}
+
+		namelen := uint32(len(name))
+		n := r.rpc_lwip_recvfrom(int32(sock), buf[:length],
+			uint32(length), 0x00000008, name, &namelen, 0)
+		if n < 0 {
+			r.mu.Unlock()
+			time.Sleep(100 * time.Millisecond)
+			r.mu.Lock()
+			continue
+		}
+
+		if debugging(debugNetdev) {
+			fmt.Printf("[<--RecvFrom] sockfd: %d, n: %d\r\n",
+				sock, n)
+		}
+
+		return int(n), nameToIp(name), nil
+	}
+}
+
+// SendTo sends a datagram to any address on a bound UDP socket
+func (r *rtl8720dn) SendTo(sockfd int, buf []byte, flags int, to netip.AddrPort,
+	deadline time.Time) (int, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[SendTo] sockfd: %d, len(buf): %d, flags: %d, to: %s\r\n",
+			sockfd, len(buf), flags, to)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sock = sock(sockfd)
+	socket, ok := r.sockets[sock]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+	var name = ipToName(to)
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netdev.ErrProtocolNotSupported
+	}
+
+	// Check if we've timed out
+	if !deadline.IsZero() {
+		if time.Now().After(deadline) {
+			return -1, netdev.ErrTimeout
+		}
+	}
+
+	result := r.rpc_lwip_sendto(int32(sock), buf, 0x00000008, name, uint32(len(name)))
+	if result == -1 {
+		return -1, fmt.Errorf("SendTo %s error", to)
+	}
+
+	return int(result), nil
+}
+
 func (r *rtl8720dn) Close(sockfd int) error {
 
 	if debugging(debugNetdev) {
@@ -142,6 +142,41 @@ func (d *Device) ReadHumidity() (humidity int32, err error) {
 	return (25 * int32(d.humidity)) / 16384, err
 }
 
+// Update reads the latest measurement if the sensor has a new one, and
+// stores it for the CO2, Temperature and Humidity methods. All three values
+// come from a single measurement.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Concentration|drivers.Temperature|drivers.Humidity) == 0 {
+		return nil
+	}
+	ok, err := d.DataReady()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return d.ReadData()
+}
+
+// CO2 returns the CO2 concentration read in the last Update call, in PPM
+// (parts per million).
+func (d *Device) CO2() uint32 {
+	return uint32(d.co2)
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return -45000 + int32(int64(d.temperature)*175000>>16)
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return int32(int64(d.humidity) * 10000 >> 16)
+}
+
 func (d *Device) sendCommand(command uint16) error {
 	binary.BigEndian.PutUint16(d.tx[0:], command)
 	return d.bus.Tx(uint16(d.Address), d.tx[0:2], nil)
@@ -1,9 +1,11 @@
 package scd4x
 
 import (
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
 	"tinygo.org/x/drivers/tester"
 )
 
@@ -13,3 +15,26 @@ func TestDefaultI2CAddress(t *testing.T) {
 	dev := New(bus)
 	c.Assert(dev.Address, qt.Equals, uint8(Address))
 }
+
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CReplay(c, strings.NewReader(`
+# data not ready
+0x62 w=e4b8
+0x62 w= r=800000
+# data ready, read measurement
+0x62 w=e4b8
+0x62 w= r=800600
+0x62 w=ec05
+0x62 w= r=01f47b666693800000
+`))
+	dev := New(bus)
+
+	c.Assert(dev.Update(drivers.Concentration), qt.IsNil)
+	c.Assert(dev.CO2(), qt.Equals, uint32(0))
+	c.Assert(dev.Update(drivers.Concentration), qt.IsNil)
+	c.Assert(dev.CO2(), qt.Equals, uint32(500))
+	c.Assert(dev.Temperature(), qt.Equals, int32(24998))
+	c.Assert(dev.Humidity(), qt.Equals, int32(5000))
+	bus.AssertDone()
+}
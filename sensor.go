@@ -36,3 +36,69 @@ type Sensor interface {
 	// storing all or part of the measurements it was called to do.
 	Update(which Measurement) error
 }
+
+// Temperaturer is a Sensor that measures temperature.
+type Temperaturer interface {
+	Sensor
+	// Temperature returns the temperature read in the last Update call, in
+	// celsius milli degrees (°C/1000).
+	Temperature() int32
+}
+
+// Humidityer is a Sensor that measures relative humidity.
+type Humidityer interface {
+	Sensor
+	// Humidity returns the relative humidity read in the last Update call,
+	// in hundredths of a percent.
+	Humidity() int32
+}
+
+// Pressurer is a Sensor that measures pressure.
+type Pressurer interface {
+	Sensor
+	// Pressure returns the pressure read in the last Update call, in milli
+	// pascals (mPa).
+	Pressure() int32
+}
+
+// Accelerometer is a Sensor that measures acceleration.
+type Accelerometer interface {
+	Sensor
+	// Acceleration returns the acceleration read in the last Update call,
+	// in µg (micro-gravity). When one of the axes is pointing straight to
+	// Earth and the sensor is not moving the returned value will be around
+	// 1000000 or -1000000.
+	Acceleration() (x, y, z int32)
+}
+
+// Gyroscope is a Sensor that measures angular velocity.
+type Gyroscope interface {
+	Sensor
+	// AngularVelocity returns the angular velocity read in the last Update
+	// call, in µrad/s (microradians per second).
+	AngularVelocity() (x, y, z int32)
+}
+
+// Magnetometer is a Sensor that measures magnetic field.
+type Magnetometer interface {
+	Sensor
+	// MagneticField returns the magnetic field read in the last Update
+	// call, in nT (nanotesla). 1 G (gauss) = 100_000 nT.
+	MagneticField() (x, y, z int32)
+}
+
+// Luminometer is a Sensor that measures illuminance.
+type Luminometer interface {
+	Sensor
+	// Illuminance returns the illuminance read in the last Update call, in
+	// mlx (millilux).
+	Illuminance() int32
+}
+
+// CO2er is a Sensor that measures carbon dioxide concentration.
+type CO2er interface {
+	Sensor
+	// CO2 returns the CO2 concentration read in the last Update call, in
+	// ppm (parts per million).
+	CO2() uint32
+}
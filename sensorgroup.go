@@ -0,0 +1,108 @@
+package drivers
+
+import "errors"
+
+// SensorGroup is a Sensor that updates many sensors at once. Each sensor is
+// added with the measurements it makes, and is only updated when one of
+// them is requested.
+//
+//	var group drivers.SensorGroup
+//	group.Add(&bme, drivers.Temperature|drivers.Humidity|drivers.Pressure)
+//	group.Add(&scd, drivers.Concentration)
+//	err := group.Update(drivers.Temperature | drivers.Concentration)
+type SensorGroup struct {
+	sensors []groupSensor
+}
+
+type groupSensor struct {
+	sensor Sensor
+	which  Measurement
+}
+
+// Add adds a sensor making the given measurements to the group.
+func (g *SensorGroup) Add(sensor Sensor, which Measurement) {
+	g.sensors = append(g.sensors, groupSensor{sensor: sensor, which: which})
+}
+
+// Update calls Update on each sensor of the group making one of the
+// requested measurements, with only the measurements it makes. A failing
+// sensor does not prevent the others from being updated: Update returns a
+// SensorErrors with the error of each failing sensor, or nil if all of them
+// succeeded.
+func (g *SensorGroup) Update(which Measurement) error {
+	var errs SensorErrors
+	for _, s := range g.sensors {
+		if s.which&which == 0 {
+			continue
+		}
+		if err := s.sensor.Update(s.which & which); err != nil {
+			errs = append(errs, SensorError{Sensor: s.sensor, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// SensorError is the error returned by a sensor of a SensorGroup.
+type SensorError struct {
+	Sensor Sensor
+	Err    error
+}
+
+// Error implements the error interface.
+func (e SensorError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the sensor.
+func (e SensorError) Unwrap() error {
+	return e.Err
+}
+
+// SensorErrors holds the errors of the failing sensors during a
+// SensorGroup update.
+type SensorErrors []SensorError
+
+// Error implements the error interface.
+func (e SensorErrors) Error() string {
+	s := ""
+	for i, err := range e {
+		if i > 0 {
+			s += "; "
+		}
+		s += err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the errors of the failing sensors.
+func (e SensorErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is reports whether any of the errors matches target. Multiple errors
+// returned by Unwrap are only followed by errors.Is since Go 1.20.
+func (e SensorErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target, like errors.As.
+func (e SensorErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
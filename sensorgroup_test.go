@@ -0,0 +1,91 @@
+package drivers_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/bma42x"
+	"tinygo.org/x/drivers/bme280"
+	"tinygo.org/x/drivers/ndir"
+	"tinygo.org/x/drivers/scd4x"
+	"tinygo.org/x/drivers/sgp30"
+	"tinygo.org/x/drivers/sht3x"
+)
+
+var (
+	_ drivers.Temperaturer  = (*bme280.Device)(nil)
+	_ drivers.Humidityer    = (*bme280.Device)(nil)
+	_ drivers.Pressurer     = (*bme280.Device)(nil)
+	_ drivers.Humidityer    = (*sht3x.Device)(nil)
+	_ drivers.CO2er         = (*scd4x.Device)(nil)
+	_ drivers.CO2er         = (*sgp30.Device)(nil)
+	_ drivers.CO2er         = (*ndir.DevI2C)(nil)
+	_ drivers.Accelerometer = (*bma42x.Device)(nil)
+	_ drivers.Sensor        = (*drivers.SensorGroup)(nil)
+)
+
+type fakeSensor struct {
+	updates []drivers.Measurement
+	err     error
+}
+
+func (s *fakeSensor) Update(which drivers.Measurement) error {
+	s.updates = append(s.updates, which)
+	return s.err
+}
+
+func TestSensorGroup(t *testing.T) {
+	c := qt.New(t)
+	thermometer := &fakeSensor{}
+	weather := &fakeSensor{}
+	var group drivers.SensorGroup
+	group.Add(thermometer, drivers.Temperature)
+	group.Add(weather, drivers.Temperature|drivers.Humidity|drivers.Pressure)
+
+	c.Assert(group.Update(drivers.Humidity|drivers.Acceleration), qt.IsNil)
+	c.Assert(group.Update(drivers.AllMeasurements), qt.IsNil)
+	c.Assert(group.Update(drivers.Acceleration), qt.IsNil)
+	c.Assert(thermometer.updates, qt.DeepEquals, []drivers.Measurement{drivers.Temperature})
+	c.Assert(weather.updates, qt.DeepEquals, []drivers.Measurement{
+		drivers.Humidity,
+		drivers.Temperature | drivers.Humidity | drivers.Pressure,
+	})
+}
+
+func TestSensorGroupErrors(t *testing.T) {
+	c := qt.New(t)
+	errBus := errors.New("bus error")
+	errCRC := errors.New("invalid CRC")
+	first := &fakeSensor{err: errBus}
+	second := &fakeSensor{}
+	third := &fakeSensor{err: errCRC}
+	var group drivers.SensorGroup
+	group.Add(first, drivers.Temperature)
+	group.Add(second, drivers.Temperature)
+	group.Add(third, drivers.Temperature)
+
+	err := group.Update(drivers.Temperature)
+	c.Assert(err, qt.ErrorMatches, "bus error; invalid CRC")
+	c.Assert(len(second.updates), qt.Equals, 1, qt.Commentf("failing sensors do not stop the update"))
+	c.Assert(errors.Is(err, errCRC), qt.IsTrue)
+	var errs drivers.SensorErrors
+	c.Assert(errors.As(err, &errs), qt.IsTrue)
+	c.Assert(errs[0].Sensor, qt.Equals, drivers.Sensor(first))
+	c.Assert(errs[1].Sensor, qt.Equals, drivers.Sensor(third))
+	var sensorErr drivers.SensorError
+	c.Assert(errors.As(err, &sensorErr), qt.IsTrue)
+	c.Assert(sensorErr.Sensor, qt.Equals, drivers.Sensor(first))
+	c.Assert(errors.Is(err, io.EOF), qt.IsFalse)
+
+	// The errors are kept by the next updates
+	first.err = nil
+	c.Assert(group.Update(drivers.Temperature), qt.ErrorMatches, "invalid CRC")
+	c.Assert(errs, qt.HasLen, 2)
+	c.Assert(errs[0].Err, qt.Equals, errBus)
+
+	third.err = nil
+	c.Assert(group.Update(drivers.Temperature), qt.IsNil)
+}
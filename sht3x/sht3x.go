@@ -15,6 +15,9 @@ import (
 type Device struct {
 	bus     drivers.I2C
 	Address uint16
+
+	temperature int32
+	humidity    int32
 }
 
 // New creates a new SHT31 connection. The I2C bus must already be
@@ -53,14 +56,47 @@ func (d *Device) ReadTemperatureHumidity() (tempMilliCelsius int32, relativeHumi
 	return tempMilliCelsius, relativeHumidity, err
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Humidity methods. Both values come from a single measurement.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Humidity) == 0 {
+		return nil
+	}
+	temp, humidity, err := d.ReadTemperatureHumidity()
+	if err != nil {
+		return err
+	}
+	d.temperature = temp
+	d.humidity = int32(humidity)
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return d.humidity
+}
+
 // rawReadings returns the sensor's raw values of the temperature and humidity
 func (d *Device) rawReadings() (uint16, uint16, error) {
-	d.bus.Tx(d.Address, []byte{MEASUREMENT_COMMAND_MSB, MEASUREMENT_COMMAND_LSB}, nil)
+	err := d.bus.Tx(d.Address, []byte{MEASUREMENT_COMMAND_MSB, MEASUREMENT_COMMAND_LSB}, nil)
+	if err != nil {
+		return 0, 0, err
+	}
 
 	time.Sleep(17 * time.Millisecond)
 
 	var data [5]byte
-	d.bus.Tx(d.Address, []byte{}, data[:])
+	err = d.bus.Tx(d.Address, []byte{}, data[:])
+	if err != nil {
+		return 0, 0, err
+	}
 	// ignore crc for now
 
 	return readUint(data[0], data[1]), readUint(data[3], data[4]), nil
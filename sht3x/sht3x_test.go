@@ -0,0 +1,43 @@
+package sht3x
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+func TestReadTemperatureHumidity(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewSHT3x(c, AddressA)
+	fake.Temperature = 21300
+	fake.Humidity = 45600
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	temp, humidity, err := dev.ReadTemperatureHumidity()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp > 21290 && temp < 21310, qt.IsTrue, qt.Commentf("%d", temp))
+	c.Assert(humidity, qt.Equals, int16(4560))
+}
+
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewSHT3x(c, AddressA)
+	fake.Temperature = -8000
+	fake.Humidity = 80000
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	c.Assert(dev.Update(drivers.Humidity), qt.IsNil)
+	c.Assert(dev.Temperature() > -8010 && dev.Temperature() < -7990, qt.IsTrue, qt.Commentf("%d", dev.Temperature()))
+	c.Assert(dev.Humidity(), qt.Equals, int32(8000))
+
+	fake.Err = errors.New("bus error")
+	c.Assert(dev.Update(drivers.Temperature), qt.ErrorMatches, "bus error")
+	c.Assert(dev.Humidity(), qt.Equals, int32(8000))
+}
@@ -19,6 +19,9 @@ const (
 type Device struct {
 	bus     drivers.I2C
 	Address uint8
+
+	temperature int32
+	humidity    int32
 }
 
 // New creates a new SHT4x connection. The I2C bus must already be
@@ -48,6 +51,33 @@ func (d *Device) ReadTemperatureHumidity() (temperatureMilliCelsius int32, relat
 	return temperatureMilliCelsius, relativeHumidityMilliPercent, err
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Humidity methods. Both values come from a single measurement.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Humidity) == 0 {
+		return nil
+	}
+	temp, humidity, err := d.ReadTemperatureHumidity()
+	if err != nil {
+		return err
+	}
+	d.temperature = temp
+	d.humidity = humidity / 10
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return d.humidity
+}
+
 // rawReadings returns the sensor's raw values of the temperature and humidity
 func (d *Device) rawReadings() (uint16, uint16, error) {
 	err := d.bus.Tx(uint16(d.Address), []byte{commandMeasurement}, nil)
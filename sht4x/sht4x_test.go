@@ -0,0 +1,51 @@
+package sht4x
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+func TestReadTemperatureHumidity(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewSHT4x(c, DefaultAddress)
+	fake.Temperature = 21300
+	fake.Humidity = 45600
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	temp, humidity, err := dev.ReadTemperatureHumidity()
+	c.Assert(err, qt.IsNil)
+	c.Assert(temp > 21290 && temp < 21310, qt.IsTrue, qt.Commentf("%d", temp))
+	c.Assert(humidity > 45590 && humidity < 45610, qt.IsTrue, qt.Commentf("%d", humidity))
+}
+
+func TestReadError(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewSHT4x(c, DefaultAddress)
+	fake.Err = errors.New("bus error")
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	_, _, err := dev.ReadTemperatureHumidity()
+	c.Assert(err, qt.Equals, fake.Err)
+}
+
+func TestUpdate(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewSHT4x(c, DefaultAddress)
+	fake.Temperature = 30000
+	fake.Humidity = 25000
+	bus.AddDevice(fake)
+
+	dev := New(bus)
+	c.Assert(dev.Update(drivers.Temperature), qt.IsNil)
+	c.Assert(dev.Temperature() > 29990 && dev.Temperature() < 30010, qt.IsTrue, qt.Commentf("%d", dev.Temperature()))
+	c.Assert(dev.Humidity() >= 2499 && dev.Humidity() <= 2501, qt.IsTrue, qt.Commentf("%d", dev.Humidity()))
+}
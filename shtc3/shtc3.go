@@ -13,7 +13,9 @@ import (
 
 // Device wraps an I2C connection to a SHT31 device.
 type Device struct {
-	bus drivers.I2C
+	bus         drivers.I2C
+	temperature int32
+	humidity    int32
 }
 
 // New creates a new SHTC3 connection. The I2C bus must already be
@@ -51,10 +53,40 @@ func (d *Device) ReadTemperatureHumidity() (tempMilliCelsius int32, relativeHumi
 	return tempMilliCelsius, relativeHumidity, err
 }
 
+// Update reads the sensor and stores the measurements for the Temperature
+// and Humidity methods. Both values come from a single measurement.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&(drivers.Temperature|drivers.Humidity) == 0 {
+		return nil
+	}
+	temp, humidity, err := d.ReadTemperatureHumidity()
+	if err != nil {
+		return err
+	}
+	d.temperature = temp
+	d.humidity = int32(humidity)
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
+
+// Humidity returns the relative humidity read in the last Update call, in
+// hundredths of a percent.
+func (d *Device) Humidity() int32 {
+	return d.humidity
+}
+
 // rawReadings returns the sensor's raw values of the temperature and humidity
 func (d *Device) rawReadings() (uint16, uint16, error) {
 	var data [6]byte
-	d.bus.Tx(SHTC3_ADDRESS, []byte(SHTC3_CMD_MEASURE_HP), data[:])
+	err := d.bus.Tx(SHTC3_ADDRESS, []byte(SHTC3_CMD_MEASURE_HP), data[:])
+	if err != nil {
+		return 0, 0, err
+	}
 	// ignore crc for now
 	return readUint(data[0], data[1]), readUint(data[3], data[4]), nil
 }
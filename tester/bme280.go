@@ -0,0 +1,287 @@
+package tester
+
+import "time"
+
+const (
+	bme280RegCalib00   = 0x88
+	bme280RegCalibH1   = 0xA1
+	bme280RegChipID    = 0xD0
+	bme280RegReset     = 0xE0
+	bme280RegCalib26   = 0xE1
+	bme280RegCtrlHum   = 0xF2
+	bme280RegStatus    = 0xF3
+	bme280RegCtrlMeas  = 0xF4
+	bme280RegConfig    = 0xF5
+	bme280RegData      = 0xF7
+	bme280RegLast      = 0xFE
+	bme280ChipID       = 0x60
+	bme280ResetCommand = 0xB6
+
+	bme280ModeForced = 0x01
+	bme280ModeNormal = 0x03
+)
+
+// bme280Calibration holds typical trimming parameters of a BME280.
+var bme280Calibration = struct {
+	t1                             uint16
+	t2, t3                         int16
+	p1                             uint16
+	p2, p3, p4, p5, p6, p7, p8, p9 int16
+	h1, h3                         uint8
+	h2, h4, h5                     int16
+	h6                             int8
+}{
+	t1: 27504, t2: 26435, t3: -1000,
+	p1: 36477, p2: -10685, p3: 3024, p4: 2855, p5: 140, p6: -7, p7: 15500, p8: -14600, p9: 6000,
+	h1: 75, h2: 362, h3: 0, h4: 313, h5: 50, h6: 30,
+}
+
+// BME280 simulates a BME280 humidity, pressure and temperature sensor.
+//
+// The sensor holds trimming parameters, and the raw measurements it reports
+// compensate to Temperature, Pressure and Humidity. In forced mode, a
+// measurement is started by writing ctrl_meas and completes after the
+// typical measurement time given by the datasheet, according to the time
+// source Now. In normal mode, data registers always hold a fresh
+// measurement. The IIR filter is not simulated.
+type BME280 struct {
+	I2CDevice8
+	// Now returns the host time measurements run on. It defaults to
+	// time.Now and can be replaced to control the simulated time.
+	Now func() time.Time
+	// Temperature is the measured temperature in milli degrees Celsius.
+	Temperature int32
+	// Pressure is the measured pressure in milli pascals.
+	Pressure int32
+	// Humidity is the measured relative humidity in milli percent.
+	Humidity int32
+
+	osrsH     uint8
+	measuring bool
+	readyAt   time.Time
+}
+
+// NewBME280 returns a simulated BME280 at the given address, in its
+// power-on state.
+func NewBME280(c Failer, addr uint8) *BME280 {
+	d := &BME280{
+		I2CDevice8: *NewI2CDevice8(c, addr),
+	}
+	cal := bme280Calibration
+	regs := d.Registers[bme280RegCalib00:]
+	for i, v := range []uint16{
+		cal.t1, uint16(cal.t2), uint16(cal.t3),
+		cal.p1, uint16(cal.p2), uint16(cal.p3), uint16(cal.p4), uint16(cal.p5),
+		uint16(cal.p6), uint16(cal.p7), uint16(cal.p8), uint16(cal.p9),
+	} {
+		regs[2*i] = uint8(v)
+		regs[2*i+1] = uint8(v >> 8)
+	}
+	d.Registers[bme280RegCalibH1] = cal.h1
+	regs = d.Registers[bme280RegCalib26:]
+	regs[0] = uint8(cal.h2)
+	regs[1] = uint8(cal.h2 >> 8)
+	regs[2] = cal.h3
+	regs[3] = uint8(cal.h4 >> 4)
+	regs[4] = uint8(cal.h4&0x0F) | uint8(cal.h5&0x0F)<<4
+	regs[5] = uint8(cal.h5 >> 4)
+	regs[6] = uint8(cal.h6)
+	d.Registers[bme280RegChipID] = bme280ChipID
+	d.reset()
+	return d
+}
+
+// readRegister implements I2C.ReadRegister.
+func (d *BME280) readRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.update()
+	return d.I2CDevice8.readRegister(r, buf)
+}
+
+// writeRegister implements I2C.WriteRegister.
+func (d *BME280) writeRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.update()
+	// Registers are written one by one, the address of each data byte
+	// being sent in a burst write.
+	for i, v := range buf {
+		switch reg := int(r) + i; reg {
+		case bme280RegReset:
+			if v == bme280ResetCommand {
+				d.reset()
+			}
+		case bme280RegCtrlHum:
+			d.Registers[reg] = v & 0x07
+		case bme280RegConfig:
+			d.Registers[reg] = v &^ 0x02
+		case bme280RegCtrlMeas:
+			d.Registers[reg] = v
+			// ctrl_hum changes only take effect after writing ctrl_meas.
+			d.osrsH = d.Registers[bme280RegCtrlHum]
+			d.start(now(d.Now))
+		default:
+			d.c.Fatalf("register write %#x is not a writable register", reg)
+		}
+	}
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *BME280) Tx(w, r []byte) error {
+	return registerTx(d.c, d, w, r)
+}
+
+// reset puts the sensor in its power-on state: sleep mode, with data
+// registers holding their reset values.
+func (d *BME280) reset() {
+	for r := bme280RegCtrlHum; r <= bme280RegConfig; r++ {
+		d.Registers[r] = 0
+	}
+	copy(d.Registers[bme280RegData:], []uint8{0x80, 0, 0, 0x80, 0, 0, 0x80, 0})
+	d.osrsH = 0
+	d.measuring = false
+}
+
+// start starts a measurement in forced mode.
+func (d *BME280) start(now time.Time) {
+	ctrl := d.Registers[bme280RegCtrlMeas]
+	if ctrl&0x03 != bme280ModeForced && ctrl&0x03 != 0x02 {
+		d.measuring = false
+		return
+	}
+	// Typical measurement time from the datasheet, section 9.1.
+	osr := func(v uint8) time.Duration {
+		if v == 0 {
+			return 0
+		}
+		if v > 5 {
+			v = 5
+		}
+		return time.Duration(1<<(v-1)) * 2 * time.Millisecond
+	}
+	duration := time.Millisecond + osr(ctrl>>5)
+	if p := osr(ctrl >> 2 & 0x07); p > 0 {
+		duration += p + 500*time.Microsecond
+	}
+	if h := osr(d.osrsH); h > 0 {
+		duration += h + 500*time.Microsecond
+	}
+	d.measuring = true
+	d.readyAt = now.Add(duration)
+}
+
+// update completes a pending measurement, or takes a new one in normal
+// mode.
+func (d *BME280) update() {
+	ctrl := d.Registers[bme280RegCtrlMeas]
+	switch {
+	case d.measuring && !now(d.Now).Before(d.readyAt):
+		d.measuring = false
+		d.Registers[bme280RegCtrlMeas] = ctrl &^ 0x03
+		d.measure()
+	case ctrl&0x03 == bme280ModeNormal:
+		d.measure()
+	}
+	d.Registers[bme280RegStatus] = 0
+	if d.measuring {
+		d.Registers[bme280RegStatus] = 1 << 3
+	}
+}
+
+// measure fills the data registers with raw values that compensate to the
+// simulated measurements.
+func (d *BME280) measure() {
+	ctrl := d.Registers[bme280RegCtrlMeas]
+	regs := d.Registers[bme280RegData:]
+
+	// Temperature is needed to compensate pressure and humidity, even
+	// when it is skipped.
+	adcT := bme280Search(0, 1<<20-1, d.Temperature/10, func(adc int32) int32 {
+		t, _ := bme280CompensateT(adc)
+		return t
+	})
+	_, tFine := bme280CompensateT(adcT)
+	if ctrl>>5 != 0 {
+		regs[3], regs[4], regs[5] = uint8(adcT>>12), uint8(adcT>>4), uint8(adcT<<4)
+	}
+	if ctrl>>2&0x07 != 0 {
+		// Pressure decreases with the raw value.
+		adcP := bme280Search(0, 1<<20-1, -d.Pressure/1000, func(adc int32) int32 {
+			return -bme280CompensateP(adc, tFine)
+		})
+		regs[0], regs[1], regs[2] = uint8(adcP>>12), uint8(adcP>>4), uint8(adcP<<4)
+	}
+	if d.osrsH != 0 {
+		adcH := bme280Search(0, 1<<16-1, d.Humidity*1024/1000, func(adc int32) int32 {
+			return bme280CompensateH(adc, tFine)
+		})
+		regs[6], regs[7] = uint8(adcH>>8), uint8(adcH)
+	}
+}
+
+// bme280Search returns the lowest raw value in [lo, hi] for which the
+// increasing function f reaches target.
+func bme280Search(lo, hi, target int32, f func(adc int32) int32) int32 {
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if f(mid) < target {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// bme280CompensateT returns the temperature in 0.01 °C and t_fine, with
+// the reference formula of the datasheet.
+func bme280CompensateT(adc int32) (int32, int32) {
+	cal := bme280Calibration
+	var1 := ((adc >> 3) - (int32(cal.t1) << 1)) * int32(cal.t2) >> 11
+	var2 := (((adc >> 4) - int32(cal.t1)) * ((adc >> 4) - int32(cal.t1)) >> 12) * int32(cal.t3) >> 14
+	tFine := var1 + var2
+	return (tFine*5 + 128) >> 8, tFine
+}
+
+// bme280CompensateP returns the pressure in Pa, with the reference formula
+// of the datasheet.
+func bme280CompensateP(adc, tFine int32) int32 {
+	cal := bme280Calibration
+	var1 := int64(tFine) - 128000
+	var2 := var1 * var1 * int64(cal.p6)
+	var2 += var1 * int64(cal.p5) << 17
+	var2 += int64(cal.p4) << 35
+	var1 = (var1 * var1 * int64(cal.p3) >> 8) + (var1 * int64(cal.p2) << 12)
+	var1 = ((int64(1) << 47) + var1) * int64(cal.p1) >> 33
+	if var1 == 0 {
+		return 0
+	}
+	p := int64(1048576 - adc)
+	p = ((p<<31 - var2) * 3125) / var1
+	var1 = int64(cal.p9) * (p >> 13) * (p >> 13) >> 25
+	var2 = int64(cal.p8) * p >> 19
+	p = (p+var1+var2)>>8 + int64(cal.p7)<<4
+	return int32(p / 256)
+}
+
+// bme280CompensateH returns the relative humidity in 1/1024 %, with the
+// reference formula of the datasheet.
+func bme280CompensateH(adc, tFine int32) int32 {
+	cal := bme280Calibration
+	v := tFine - 76800
+	x := (adc<<14 - int32(cal.h4)<<20 - int32(cal.h5)*v + 16384) >> 15
+	y := (((v*int32(cal.h6))>>10)*(((v*int32(cal.h3))>>11)+32768))>>10 + 2097152
+	v = x * ((y*int32(cal.h2) + 8192) >> 14)
+	v -= ((v >> 15) * (v >> 15) >> 7) * int32(cal.h1) >> 4
+	if v < 0 {
+		v = 0
+	}
+	if v > 419430400 {
+		v = 419430400
+	}
+	return v >> 12
+}
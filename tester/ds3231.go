@@ -0,0 +1,147 @@
+package tester
+
+import "time"
+
+const (
+	ds3231Address = 0x68
+
+	ds3231RegSeconds = 0x00
+	ds3231RegWeekday = 0x03
+	ds3231RegYear    = 0x06
+	ds3231RegControl = 0x0E
+	ds3231RegStatus  = 0x0F
+	ds3231RegTemp    = 0x11
+)
+
+// DS3231 simulates a DS3231 real-time clock.
+//
+// The clock runs from the time source Now. It reports an invalid time
+// (OSF flag) until its time is set, like after a power loss.
+type DS3231 struct {
+	I2CDevice8
+	// Now returns the host time the clock runs from. It defaults to
+	// time.Now and can be replaced to control the simulated time.
+	Now func() time.Time
+	// Temperature is the die temperature in milli degrees Celsius. It is
+	// reported with a 0.25 °C resolution.
+	Temperature int32
+
+	clock   rtc
+	weekday uint8
+}
+
+// NewDS3231 returns a simulated DS3231 at its fixed address, in its
+// power-on state.
+func NewDS3231(c Failer) *DS3231 {
+	d := &DS3231{
+		I2CDevice8: *NewI2CDevice8(c, ds3231Address),
+	}
+	d.Registers[ds3231RegControl] = 0x1C
+	d.Registers[ds3231RegStatus] = 0x88
+	d.clock.set = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.weekday = 1
+	return d
+}
+
+// Time returns the current time of the clock.
+func (d *DS3231) Time() time.Time {
+	return d.clock.time(now(d.Now))
+}
+
+// readRegister implements I2C.ReadRegister.
+func (d *DS3231) readRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.update()
+	return d.I2CDevice8.readRegister(r, buf)
+}
+
+// writeRegister implements I2C.WriteRegister.
+func (d *DS3231) writeRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.update()
+	status := d.Registers[ds3231RegStatus]
+	if err := d.I2CDevice8.writeRegister(r, buf); err != nil {
+		return err
+	}
+
+	end := int(r) + len(buf)
+	if int(r) <= ds3231RegYear && end > ds3231RegSeconds {
+		d.setTime()
+	}
+	if int(r) <= ds3231RegStatus && end > ds3231RegStatus {
+		// OSF and alarm flags can only be cleared, BSY is read-only.
+		w := d.Registers[ds3231RegStatus]
+		d.Registers[ds3231RegStatus] = status&w&0x83 | w&0x08 | status&0x04
+	}
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *DS3231) Tx(w, r []byte) error {
+	return registerTx(d.c, d, w, r)
+}
+
+// setTime starts the clock from the time registers.
+func (d *DS3231) setTime() {
+	regs := d.Registers[ds3231RegSeconds:]
+	year := 2000 + fromBCD(regs[6])
+	if regs[5]&0x80 != 0 {
+		year += 100
+	}
+	t := time.Date(year, time.Month(fromBCD(regs[5]&0x1F)), fromBCD(regs[4]&0x3F),
+		ds3231Hour(regs[2]), fromBCD(regs[1]&0x7F), fromBCD(regs[0]&0x7F), 0, time.UTC)
+	d.clock.setTime(t, now(d.Now))
+	d.weekday = regs[ds3231RegWeekday] & 0x07
+}
+
+// update refreshes the time and temperature registers.
+func (d *DS3231) update() {
+	t := d.clock.time(now(d.Now))
+	regs := d.Registers[ds3231RegSeconds:]
+	regs[0] = toBCD(t.Second())
+	regs[1] = toBCD(t.Minute())
+	if regs[2]&0x40 != 0 {
+		// 12-hour mode
+		h, pm := t.Hour()%12, uint8(0)
+		if t.Hour() >= 12 {
+			pm = 0x20
+		}
+		if h == 0 {
+			h = 12
+		}
+		regs[2] = 0x40 | pm | toBCD(h)
+	} else {
+		regs[2] = toBCD(t.Hour())
+	}
+	if days := d.clock.days(t); days != 0 {
+		regs[3] = uint8((int(d.weekday)+6+days)%7 + 1)
+	} else {
+		regs[3] = d.weekday
+	}
+	regs[4] = toBCD(t.Day())
+	regs[5] = toBCD(int(t.Month()))
+	if t.Year() >= 2100 {
+		regs[5] |= 0x80
+	}
+	regs[6] = toBCD(t.Year() % 100)
+
+	temp := int16(d.Temperature / 250 * 64)
+	d.Registers[ds3231RegTemp] = uint8(temp >> 8)
+	d.Registers[ds3231RegTemp+1] = uint8(temp)
+}
+
+// ds3231Hour decodes the hours register, in 12 or 24-hour mode.
+func ds3231Hour(v uint8) int {
+	if v&0x40 == 0 {
+		return fromBCD(v & 0x3F)
+	}
+	h := fromBCD(v&0x1F) % 12
+	if v&0x20 != 0 {
+		h += 12
+	}
+	return h
+}
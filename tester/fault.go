@@ -0,0 +1,67 @@
+package tester
+
+import "errors"
+
+// ErrNACK is returned by a mock I2C bus for a transaction that was not
+// acknowledged.
+var ErrNACK = errors.New("i2c mock: no acknowledge")
+
+// I2CTransaction describes a transaction on a mock I2C bus, as seen by
+// fault injection and clock stretching hooks.
+type I2CTransaction struct {
+	// N is the index of the transaction on the bus, starting at 1.
+	N int
+	// Addr is the addressed device.
+	Addr uint8
+	// W holds the written bytes. The first one is usually a register or
+	// a command.
+	W []byte
+	// ReadLen is the number of bytes to read.
+	ReadLen int
+}
+
+// Register returns the register accessed by the transaction, that is its
+// first written byte, if any.
+func (tx I2CTransaction) Register() (uint8, bool) {
+	if len(tx.W) == 0 {
+		return 0, false
+	}
+	return tx.W[0], true
+}
+
+// I2CFault returns the error to inject in a transaction on a mock I2C bus,
+// or nil to let it proceed.
+type I2CFault func(tx I2CTransaction) error
+
+// FailNth returns a fault that makes the nth transaction on the bus fail
+// with err. Transactions are counted from 1 since the bus creation.
+func FailNth(n int, err error) I2CFault {
+	return func(tx I2CTransaction) error {
+		if tx.N == n {
+			return err
+		}
+		return nil
+	}
+}
+
+// FailRegister returns a fault that makes every access to register reg of
+// the device at addr fail with err.
+func FailRegister(addr, reg uint8, err error) I2CFault {
+	return func(tx I2CTransaction) error {
+		if r, ok := tx.Register(); ok && tx.Addr == addr && r == reg {
+			return err
+		}
+		return nil
+	}
+}
+
+// NACKAddr returns a fault that makes every transaction to the device at
+// addr fail with ErrNACK, as if it was disconnected.
+func NACKAddr(addr uint8) I2CFault {
+	return func(tx I2CTransaction) error {
+		if tx.Addr == addr {
+			return ErrNACK
+		}
+		return nil
+	}
+}
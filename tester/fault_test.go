@@ -0,0 +1,69 @@
+package tester
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestI2CNACK(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	bus.NACK = true
+	bus.NewDevice(8)
+
+	c.Assert(bus.Tx(9, []byte{0}, make([]byte, 1)), qt.Equals, ErrNACK)
+	c.Assert(bus.Tx(8, []byte{0}, make([]byte, 1)), qt.IsNil)
+
+	bus.Faults = []I2CFault{NACKAddr(8)}
+	c.Assert(bus.ReadRegister(8, 0, make([]byte, 1)), qt.Equals, ErrNACK)
+}
+
+func TestI2CFailNth(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	d := bus.NewDevice(8)
+	errBus := errors.New("bus error")
+	bus.Faults = []I2CFault{FailNth(2, errBus)}
+
+	c.Assert(bus.WriteRegister(8, 1, []byte{0x11}), qt.IsNil)
+	c.Assert(bus.WriteRegister(8, 2, []byte{0x22}), qt.Equals, errBus)
+	c.Assert(bus.WriteRegister(8, 3, []byte{0x33}), qt.IsNil)
+	c.Assert(d.Registers[1:4], qt.DeepEquals, []uint8{0x11, 0, 0x33})
+}
+
+func TestI2CFailRegister(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	bus.NewDevice(8)
+	bus.NewDevice(9)
+	errBus := errors.New("bus error")
+	bus.Faults = []I2CFault{FailRegister(8, 0x10, errBus)}
+
+	c.Assert(bus.Tx(8, []byte{0x10}, make([]byte, 2)), qt.Equals, errBus)
+	c.Assert(bus.ReadRegister(8, 0x10, make([]byte, 2)), qt.Equals, errBus)
+	c.Assert(bus.ReadRegister(8, 0x11, make([]byte, 2)), qt.IsNil)
+	c.Assert(bus.ReadRegister(9, 0x10, make([]byte, 2)), qt.IsNil)
+}
+
+func TestI2CStretch(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	bus.NewDevice(8)
+	var slept []time.Duration
+	bus.Sleep = func(d time.Duration) { slept = append(slept, d) }
+	bus.Stretch = func(tx I2CTransaction) time.Duration {
+		if r, _ := tx.Register(); r == 0x20 {
+			return 5 * time.Millisecond
+		}
+		return 0
+	}
+
+	c.Assert(bus.ReadRegister(8, 0x20, make([]byte, 1)), qt.IsNil)
+	c.Assert(bus.ReadRegister(8, 0x21, make([]byte, 1)), qt.IsNil)
+	c.Assert(bus.ReadRegister(8, 0x20, make([]byte, 1)), qt.IsNil)
+	c.Assert(slept, qt.DeepEquals, []time.Duration{5 * time.Millisecond, 5 * time.Millisecond})
+	c.Assert(bus.Stretched, qt.Equals, 10*time.Millisecond)
+}
@@ -1,11 +1,30 @@
 package tester
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // I2CBus implements the I2C interface in memory for testing.
 type I2CBus struct {
 	c       Failer
 	devices []I2CDevice
+	count   int
+
+	// NACK, when true, makes transactions to an address with no device
+	// return ErrNACK instead of flagging an error.
+	NACK bool
+	// Faults are checked in order before each transaction. The first
+	// non-nil error is returned and the transaction is not performed.
+	Faults []I2CFault
+	// Stretch, if non-nil, returns how long the addressed device holds
+	// the clock low during a transaction.
+	Stretch func(tx I2CTransaction) time.Duration
+	// Sleep is used to wait for clock stretching. It defaults to
+	// time.Sleep.
+	Sleep func(d time.Duration)
+	// Stretched holds the total clock stretching time on the bus.
+	Stretched time.Duration
 }
 
 // NewI2CBus returns an I2CBus mock I2C instance that uses c to flag errors
@@ -38,26 +57,72 @@ func (bus *I2CBus) NewDevice(addr uint8) *I2CDevice8 {
 
 // ReadRegister implements I2C.ReadRegister.
 func (bus *I2CBus) ReadRegister(addr uint8, r uint8, buf []byte) error {
-	return bus.FindDevice(addr).readRegister(r, buf)
+	return bus.transaction(addr, []byte{r}, buf, func(dev I2CDevice) error {
+		return dev.readRegister(r, buf)
+	})
 }
 
 // WriteRegister implements I2C.WriteRegister.
 func (bus *I2CBus) WriteRegister(addr uint8, r uint8, buf []byte) error {
-	return bus.FindDevice(addr).writeRegister(r, buf)
+	w := append([]byte{r}, buf...)
+	return bus.transaction(addr, w, nil, func(dev I2CDevice) error {
+		return dev.writeRegister(r, buf)
+	})
 }
 
 // Tx implements I2C.Tx.
 func (bus *I2CBus) Tx(addr uint16, w, r []byte) error {
-	return bus.FindDevice(uint8(addr)).Tx(w, r)
+	return bus.transaction(uint8(addr), w, r, func(dev I2CDevice) error {
+		return dev.Tx(w, r)
+	})
 }
 
 // FindDevice returns the device with the given address.
 func (bus *I2CBus) FindDevice(addr uint8) I2CDevice {
+	if dev := bus.findDevice(addr); dev != nil {
+		return dev
+	}
+	bus.c.Fatalf("invalid device addr %#x passed to i2c bus", addr)
+	panic("unreachable")
+}
+
+func (bus *I2CBus) findDevice(addr uint8) I2CDevice {
 	for _, dev := range bus.devices {
 		if dev.Addr() == addr {
 			return dev
 		}
 	}
-	bus.c.Fatalf("invalid device addr %#x passed to i2c bus", addr)
-	panic("unreachable")
+	return nil
+}
+
+// transaction runs fn on the device at addr, after applying faults, NACK
+// and clock stretching.
+func (bus *I2CBus) transaction(addr uint8, w, r []byte, fn func(dev I2CDevice) error) error {
+	bus.count++
+	tx := I2CTransaction{N: bus.count, Addr: addr, W: w, ReadLen: len(r)}
+	for _, fault := range bus.Faults {
+		if err := fault(tx); err != nil {
+			return err
+		}
+	}
+
+	dev := bus.findDevice(addr)
+	if dev == nil && bus.NACK {
+		return ErrNACK
+	}
+	if dev == nil {
+		dev = bus.FindDevice(addr)
+	}
+
+	if bus.Stretch != nil {
+		if d := bus.Stretch(tx); d > 0 {
+			bus.Stretched += d
+			if bus.Sleep != nil {
+				bus.Sleep(d)
+			} else {
+				time.Sleep(d)
+			}
+		}
+	}
+	return fn(dev)
 }
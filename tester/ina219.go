@@ -0,0 +1,162 @@
+package tester
+
+const (
+	ina219RegConfig      = 0x0
+	ina219RegShunt       = 0x1
+	ina219RegBus         = 0x2
+	ina219RegPower       = 0x3
+	ina219RegCurrent     = 0x4
+	ina219RegCalibration = 0x5
+
+	ina219ConfigReset = 0x399F
+)
+
+// INA219 simulates an INA219 current and power monitor.
+//
+// Conversions are instantaneous: in continuous modes the measurement
+// registers follow BusVoltage and ShuntVoltage, in triggered modes they are
+// latched when the configuration register is written. Current and power are
+// computed from the calibration register like the real chip, and the
+// conversion ready (CNVR) and math overflow (OVF) flags of the bus voltage
+// register are maintained.
+type INA219 struct {
+	c Failer
+	// addr is the i2c device address.
+	addr uint8
+	// BusVoltage is the voltage of the bus, in millivolts.
+	BusVoltage int32
+	// ShuntVoltage is the voltage across the shunt resistor, in
+	// microvolts.
+	ShuntVoltage int32
+	// Registers holds the device registers. It can be inspected
+	// or changed as desired for testing.
+	Registers [6]uint16
+	// If Err is non-nil, it will be returned as the error from the
+	// I2C methods.
+	Err error
+
+	ready bool
+}
+
+// NewINA219 returns a simulated INA219 at the given address, in its
+// power-on state.
+func NewINA219(c Failer, addr uint8) *INA219 {
+	d := &INA219{
+		c:    c,
+		addr: addr,
+	}
+	d.Registers[ina219RegConfig] = ina219ConfigReset
+	return d
+}
+
+// Addr returns the Device address.
+func (d *INA219) Addr() uint8 {
+	return d.addr
+}
+
+// readRegister implements I2C.ReadRegister.
+func (d *INA219) readRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if int(r) >= len(d.Registers) {
+		d.c.Fatalf("register read [%#x] unknown register", r)
+	}
+	if len(buf) != 2 {
+		d.c.Fatalf("register read [%#x, %#x] mis-sized read", r, len(buf))
+	}
+	if mode := d.Registers[ina219RegConfig] & 0x7; mode > 4 {
+		d.convert(mode)
+	}
+
+	val := d.Registers[r]
+	switch r {
+	case ina219RegBus:
+		if d.ready {
+			val |= 1 << 1
+		}
+	case ina219RegPower:
+		d.ready = false
+	}
+	buf[0] = byte(val >> 8)
+	buf[1] = byte(val)
+	return nil
+}
+
+// writeRegister implements I2C.WriteRegister.
+func (d *INA219) writeRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if len(buf) != 2 {
+		d.c.Fatalf("register write [%#x, %#x] mis-sized write", r, len(buf))
+	}
+	val := uint16(buf[0])<<8 | uint16(buf[1])
+	switch r {
+	case ina219RegConfig:
+		if val&0x8000 != 0 {
+			d.Registers = [6]uint16{ina219RegConfig: ina219ConfigReset}
+			d.ready = false
+			return nil
+		}
+		d.Registers[r] = val
+		d.ready = false
+		if mode := val & 0x7; mode > 0 && mode < 4 {
+			d.convert(mode)
+		}
+	case ina219RegCalibration:
+		d.Registers[r] = val &^ 1
+	default:
+		d.c.Fatalf("register write [%#x] read-only register", r)
+	}
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *INA219) Tx(w, r []byte) error {
+	return registerTx(d.c, d, w, r)
+}
+
+// convert performs a conversion in the given operating mode.
+func (d *INA219) convert(mode uint16) {
+	config := d.Registers[ina219RegConfig]
+	if mode&0x1 != 0 {
+		// PGA range is 40 mV times the gain divider, in 10 µV steps.
+		max := int32(4000) << (config >> 11 & 0x3)
+		d.Registers[ina219RegShunt] = uint16(clamp(d.ShuntVoltage/10, -max, max))
+	}
+	if mode&0x2 != 0 {
+		max := int32(4000)
+		if config&(1<<13) != 0 {
+			max = 8000
+		}
+		d.Registers[ina219RegBus] = uint16(clamp(d.BusVoltage/4, 0, max)) << 3
+	}
+
+	shunt := int32(int16(d.Registers[ina219RegShunt]))
+	bus := int32(d.Registers[ina219RegBus] >> 3)
+	current := shunt * int32(d.Registers[ina219RegCalibration]) / 4096
+	power := current * bus / 5000
+	overflow := current > 32767 || current < -32768 || power > 65535 || power < -65535
+	d.Registers[ina219RegCurrent] = uint16(clamp(current, -32768, 32767))
+	if power < 0 {
+		power = -power
+	}
+	d.Registers[ina219RegPower] = uint16(clamp(power, 0, 65535))
+	d.Registers[ina219RegBus] &^= 1
+	if overflow {
+		d.Registers[ina219RegBus] |= 1
+	}
+	d.ready = true
+}
+
+// clamp limits v to the [min, max] range.
+func clamp(v, min, max int32) int32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package tester
+
+import "tinygo.org/x/drivers/lora"
+
+// LoraPacket is a packet sent or received by a mock LoRa radio, along with
+// the radio settings active at that time.
+type LoraPacket struct {
+	Data   []uint8
+	Config lora.Config
+}
+
+// LoraRadio implements the lora.Radio interface in memory for testing.
+// Transmitted packets are recorded, received packets are taken from a queue
+// filled by the test.
+type LoraRadio struct {
+	c Failer
+	// Config holds the current radio settings.
+	Config lora.Config
+	// Sent holds the packets transmitted with Tx.
+	Sent []LoraPacket
+	// Listened holds the radio settings of each Rx call, with the
+	// received packet, if any.
+	Listened []LoraPacket
+	// OnTx, if non-nil, is called on each transmission. It can be used to
+	// queue the answer of a network to the transmitted packet.
+	OnTx func(p LoraPacket)
+	// If Err is non-nil, it will be returned as the error from Tx and Rx.
+	Err error
+
+	rxQueue [][]uint8
+}
+
+// NewLoraRadio returns a new mock LoRa radio.
+func NewLoraRadio(c Failer) *LoraRadio {
+	return &LoraRadio{
+		c: c,
+	}
+}
+
+// QueueRx adds a packet to be returned by the next Rx call that does not
+// already have one. A nil packet makes the matching Rx call time out.
+func (r *LoraRadio) QueueRx(pkt []uint8) {
+	r.rxQueue = append(r.rxQueue, pkt)
+}
+
+// Reset implements lora.Radio.Reset.
+func (r *LoraRadio) Reset() {
+	r.Config = lora.Config{}
+}
+
+// Tx implements lora.Radio.Tx.
+func (r *LoraRadio) Tx(pkt []uint8, timeoutMs uint32) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(pkt) == 0 {
+		r.c.Fatalf("lora: empty packet sent")
+	}
+	if r.Config.Freq == 0 {
+		r.c.Fatalf("lora: packet sent with no frequency set")
+	}
+	p := LoraPacket{Data: append([]uint8{}, pkt...), Config: r.Config}
+	r.Sent = append(r.Sent, p)
+	if r.OnTx != nil {
+		r.OnTx(p)
+	}
+	return nil
+}
+
+// Rx implements lora.Radio.Rx. It returns the next queued packet, or nil
+// (timeout) if the queue is empty.
+func (r *LoraRadio) Rx(timeoutMs uint32) ([]uint8, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var pkt []uint8
+	if len(r.rxQueue) > 0 {
+		pkt = r.rxQueue[0]
+		r.rxQueue = r.rxQueue[1:]
+	}
+	r.Listened = append(r.Listened, LoraPacket{Data: pkt, Config: r.Config})
+	return pkt, nil
+}
+
+// SetFrequency implements lora.Radio.SetFrequency.
+func (r *LoraRadio) SetFrequency(freq uint32) {
+	r.Config.Freq = freq
+}
+
+// SetIqMode implements lora.Radio.SetIqMode.
+func (r *LoraRadio) SetIqMode(mode uint8) {
+	r.Config.Iq = mode
+}
+
+// SetCodingRate implements lora.Radio.SetCodingRate.
+func (r *LoraRadio) SetCodingRate(cr uint8) {
+	r.Config.Cr = cr
+}
+
+// SetBandwidth implements lora.Radio.SetBandwidth.
+func (r *LoraRadio) SetBandwidth(bw uint8) {
+	r.Config.Bw = bw
+}
+
+// SetCrc implements lora.Radio.SetCrc.
+func (r *LoraRadio) SetCrc(enable bool) {
+	r.Config.Crc = lora.CRCOff
+	if enable {
+		r.Config.Crc = lora.CRCOn
+	}
+}
+
+// SetSpreadingFactor implements lora.Radio.SetSpreadingFactor.
+func (r *LoraRadio) SetSpreadingFactor(sf uint8) {
+	r.Config.Sf = sf
+}
+
+// SetPreambleLength implements lora.Radio.SetPreambleLength.
+func (r *LoraRadio) SetPreambleLength(plen uint16) {
+	r.Config.Preamble = plen
+}
+
+// SetTxPower implements lora.Radio.SetTxPower.
+func (r *LoraRadio) SetTxPower(txpow int8) {
+	r.Config.LoraTxPowerDBm = txpow
+}
+
+// SetSyncWord implements lora.Radio.SetSyncWord.
+func (r *LoraRadio) SetSyncWord(syncWord uint16) {
+	r.Config.SyncWord = syncWord
+}
+
+// SetPublicNetwork implements lora.Radio.SetPublicNetwork.
+func (r *LoraRadio) SetPublicNetwork(enable bool) {
+	r.Config.SyncWord = lora.SyncPrivate
+	if enable {
+		r.Config.SyncWord = lora.SyncPublic
+	}
+}
+
+// SetHeaderType implements lora.Radio.SetHeaderType.
+func (r *LoraRadio) SetHeaderType(headerType uint8) {
+	r.Config.HeaderType = headerType
+}
+
+// LoraConfig implements lora.Radio.LoraConfig.
+func (r *LoraRadio) LoraConfig(cnf lora.Config) {
+	r.Config = cnf
+}
@@ -0,0 +1,64 @@
+package tester
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/lora"
+)
+
+var _ lora.Radio = (*LoraRadio)(nil)
+
+func TestLoraTx(t *testing.T) {
+	c := qt.New(t)
+	r := NewLoraRadio(c)
+
+	r.SetFrequency(868100000)
+	r.SetSpreadingFactor(lora.SpreadingFactor9)
+	r.SetBandwidth(lora.Bandwidth_125_0)
+	r.SetIqMode(lora.IQStandard)
+	c.Assert(r.Tx([]byte{1, 2, 3}, 1000), qt.IsNil)
+
+	r.SetSpreadingFactor(lora.SpreadingFactor7)
+	c.Assert(r.Tx([]byte{4}, 1000), qt.IsNil)
+
+	c.Assert(r.Sent, qt.HasLen, 2)
+	c.Assert(r.Sent[0].Data, qt.DeepEquals, []byte{1, 2, 3})
+	c.Assert(r.Sent[0].Config.Freq, qt.Equals, uint32(868100000))
+	c.Assert(r.Sent[0].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor9))
+	c.Assert(r.Sent[1].Config.Sf, qt.Equals, uint8(lora.SpreadingFactor7))
+}
+
+func TestLoraRx(t *testing.T) {
+	c := qt.New(t)
+	r := NewLoraRadio(c)
+	r.SetIqMode(lora.IQInverted)
+
+	// Answer to the transmitted packet, after one Rx timeout
+	r.OnTx = func(p LoraPacket) {
+		r.QueueRx(nil)
+		r.QueueRx(append([]byte{0xAA}, p.Data...))
+	}
+	r.SetFrequency(868100000)
+	c.Assert(r.Tx([]byte{1}, 1000), qt.IsNil)
+
+	pkt, err := r.Rx(1000)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pkt, qt.IsNil)
+	pkt, err = r.Rx(1000)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pkt, qt.DeepEquals, []byte{0xAA, 1})
+	pkt, err = r.Rx(1000)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pkt, qt.IsNil)
+
+	c.Assert(r.Listened, qt.HasLen, 3)
+	c.Assert(r.Listened[1].Data, qt.DeepEquals, []byte{0xAA, 1})
+	c.Assert(r.Listened[1].Config.Iq, qt.Equals, uint8(lora.IQInverted))
+
+	r.Err = errors.New("radio error")
+	_, err = r.Rx(1000)
+	c.Assert(err, qt.Equals, r.Err)
+	c.Assert(r.Tx([]byte{1}, 1000), qt.Equals, r.Err)
+}
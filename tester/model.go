@@ -0,0 +1,107 @@
+package tester
+
+import "time"
+
+// registerTx implements I2C.Tx for mock devices with 8-bit register
+// addresses, on top of their register accessors.
+func registerTx(c Failer, d I2CDevice, w, r []byte) error {
+	switch {
+	case len(w) == 0:
+		c.Fatalf("i2c mock: need a write byte")
+		return nil
+	case len(w) == 1:
+		return d.readRegister(w[0], r)
+	case len(r) > 0:
+		c.Fatalf("i2c mock: unsupported lengths in Tx(%d, %d)", len(w), len(r))
+		return nil
+	}
+	return d.writeRegister(w[0], w[1:])
+}
+
+// now returns the current time of a simulated device, from its time source
+// if set.
+func now(f func() time.Time) time.Time {
+	if f != nil {
+		return f()
+	}
+	return time.Now()
+}
+
+// crc8 computes the CRC used by Sensirion sensors to protect each data
+// word: polynomial 0x31, initialization 0xFF.
+func crc8(data []byte) uint8 {
+	crc := uint8(0xFF)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// appendWord appends a 16-bit big-endian word followed by its CRC, the way
+// Sensirion sensors send data.
+func appendWord(buf []byte, w uint16) []byte {
+	word := []byte{byte(w >> 8), byte(w)}
+	return append(buf, word[0], word[1], crc8(word))
+}
+
+// rtc keeps the time of a simulated real-time clock.
+type rtc struct {
+	set     time.Time // time last set on the device
+	setAt   time.Time // host time at which it was set
+	stopped bool
+}
+
+// time returns the time of the clock at host time now. A clock never set
+// starts running at its first use.
+func (c *rtc) time(now time.Time) time.Time {
+	if c.setAt.IsZero() {
+		c.setAt = now
+	}
+	if c.stopped {
+		return c.set
+	}
+	return c.set.Add(now.Sub(c.setAt))
+}
+
+// setTime sets the time of the clock at host time now.
+func (c *rtc) setTime(t, now time.Time) {
+	c.set = t
+	c.setAt = now
+}
+
+// run starts or stops the clock at host time now.
+func (c *rtc) run(running bool, now time.Time) {
+	if running == !c.stopped {
+		return
+	}
+	c.set = c.time(now)
+	c.setAt = now
+	c.stopped = !running
+}
+
+// days returns the number of calendar days between the time the clock was
+// set and t.
+func (c *rtc) days(t time.Time) int {
+	y, m, d := c.set.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = t.Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from) / (24 * time.Hour))
+}
+
+// toBCD converts a value to binary-coded decimal.
+func toBCD(v int) uint8 {
+	return uint8(v/10<<4 | v%10)
+}
+
+// fromBCD converts a binary-coded decimal value.
+func fromBCD(v uint8) int {
+	return int(v>>4)*10 + int(v&0x0F)
+}
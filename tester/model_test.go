@@ -0,0 +1,209 @@
+package tester
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// fakeClock is a time source for simulated devices that only advances when
+// told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func TestCRC8(t *testing.T) {
+	c := qt.New(t)
+	// Example from the SHT3x datasheet
+	c.Assert(crc8([]byte{0xBE, 0xEF}), qt.Equals, uint8(0x92))
+}
+
+func TestSHT3xMeasurement(t *testing.T) {
+	c := qt.New(t)
+	clock := newFakeClock()
+	bus := NewI2CBus(c)
+	d := NewSHT3x(c, 0x44)
+	d.Now = clock.Now
+	d.Temperature = 25000
+	d.Humidity = 50000
+	bus.AddDevice(d)
+
+	c.Assert(bus.Tx(0x44, []byte{0x24, 0x00}, nil), qt.IsNil)
+	buf := make([]byte, 6)
+	c.Assert(bus.Tx(0x44, nil, buf), qt.Equals, ErrNACK)
+
+	clock.Advance(20 * time.Millisecond)
+	c.Assert(bus.Tx(0x44, nil, buf), qt.IsNil)
+	c.Assert(buf[2], qt.Equals, crc8(buf[0:2]))
+	c.Assert(buf[5], qt.Equals, crc8(buf[3:5]))
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(26214))
+	c.Assert(uint16(buf[3])<<8|uint16(buf[4]), qt.Equals, uint16(32768))
+
+	// Results are read only once
+	c.Assert(bus.Tx(0x44, nil, buf), qt.Equals, ErrNACK)
+
+	// Status register, read with a repeated start
+	status := make([]byte, 3)
+	c.Assert(bus.Tx(0x44, []byte{0xF3, 0x2D}, status), qt.IsNil)
+	c.Assert(status, qt.DeepEquals, []byte{0x80, 0x10, crc8([]byte{0x80, 0x10})})
+}
+
+func TestSHT4xMeasurement(t *testing.T) {
+	c := qt.New(t)
+	clock := newFakeClock()
+	bus := NewI2CBus(c)
+	d := NewSHT4x(c, 0x44)
+	d.Now = clock.Now
+	d.Temperature = -10000
+	d.Humidity = 119000
+	d.Serial = 0x12345678
+	bus.AddDevice(d)
+
+	c.Assert(bus.Tx(0x44, []byte{0xE0}, nil), qt.IsNil)
+	clock.Advance(2 * time.Millisecond)
+	buf := make([]byte, 6)
+	c.Assert(bus.Tx(0x44, nil, buf), qt.IsNil)
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(13107))
+	// Humidity ticks saturate
+	c.Assert(uint16(buf[3])<<8|uint16(buf[4]), qt.Equals, uint16(65535))
+
+	c.Assert(bus.Tx(0x44, []byte{0x89}, nil), qt.IsNil)
+	clock.Advance(time.Millisecond)
+	c.Assert(bus.Tx(0x44, nil, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, []byte{0x12, 0x34, crc8([]byte{0x12, 0x34}), 0x56, 0x78, crc8([]byte{0x56, 0x78})})
+}
+
+func TestBME280Forced(t *testing.T) {
+	c := qt.New(t)
+	clock := newFakeClock()
+	bus := NewI2CBus(c)
+	d := NewBME280(c, 0x76)
+	d.Now = clock.Now
+	d.Temperature = 21500
+	d.Pressure = 100500000
+	d.Humidity = 40000
+	bus.AddDevice(d)
+
+	// Data registers hold reset values until a measurement
+	data := make([]byte, 8)
+	c.Assert(bus.ReadRegister(0x76, 0xF7, data), qt.IsNil)
+	c.Assert(data, qt.DeepEquals, []byte{0x80, 0, 0, 0x80, 0, 0, 0x80, 0})
+
+	c.Assert(bus.WriteRegister(0x76, 0xF2, []byte{0x01}), qt.IsNil)
+	c.Assert(bus.WriteRegister(0x76, 0xF4, []byte{0x20 | 0x04 | 0x01}), qt.IsNil)
+	status := make([]byte, 1)
+	c.Assert(bus.ReadRegister(0x76, 0xF3, status), qt.IsNil)
+	c.Assert(status[0]&0x08, qt.Equals, uint8(0x08))
+
+	// 1 + 2 + 2.5 + 2.5 ms
+	clock.Advance(8 * time.Millisecond)
+	c.Assert(bus.ReadRegister(0x76, 0xF3, status), qt.IsNil)
+	c.Assert(status[0], qt.Equals, uint8(0))
+	ctrl := make([]byte, 1)
+	c.Assert(bus.ReadRegister(0x76, 0xF4, ctrl), qt.IsNil)
+	c.Assert(ctrl[0], qt.Equals, uint8(0x24), qt.Commentf("back to sleep mode"))
+
+	c.Assert(bus.ReadRegister(0x76, 0xF7, data), qt.IsNil)
+	adcT := int32(data[3])<<12 | int32(data[4])<<4 | int32(data[5])>>4
+	temp, tFine := bme280CompensateT(adcT)
+	c.Assert(temp, qt.Equals, int32(2150))
+	adcP := int32(data[0])<<12 | int32(data[1])<<4 | int32(data[2])>>4
+	c.Assert(bme280CompensateP(adcP, tFine), qt.Equals, int32(100500))
+	adcH := int32(data[6])<<8 | int32(data[7])
+	humidity := bme280CompensateH(adcH, tFine) * 1000 / 1024
+	c.Assert(humidity >= 40000 && humidity < 40010, qt.IsTrue, qt.Commentf("%d", humidity))
+}
+
+func TestDS3231Clock(t *testing.T) {
+	c := qt.New(t)
+	clock := newFakeClock()
+	bus := NewI2CBus(c)
+	d := NewDS3231(c)
+	d.Now = clock.Now
+	bus.AddDevice(d)
+
+	// 2023-12-31 23:59:58, 12-hour mode, Sunday as weekday 1
+	c.Assert(bus.WriteRegister(0x68, 0x00, []byte{0x58, 0x59, 0x40 | 0x20 | 0x11, 0x01, 0x31, 0x12, 0x23}), qt.IsNil)
+	clock.Advance(3 * time.Second)
+	regs := make([]byte, 7)
+	c.Assert(bus.ReadRegister(0x68, 0x00, regs), qt.IsNil)
+	c.Assert(regs, qt.DeepEquals, []byte{0x01, 0x00, 0x40 | 0x12, 0x02, 0x01, 0x01, 0x24})
+	c.Assert(d.Time(), qt.Equals, time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC))
+
+	// OSF can only be cleared
+	status := make([]byte, 1)
+	c.Assert(bus.ReadRegister(0x68, 0x0F, status), qt.IsNil)
+	c.Assert(status[0], qt.Equals, uint8(0x88))
+	c.Assert(bus.WriteRegister(0x68, 0x0F, []byte{0x08}), qt.IsNil)
+	c.Assert(bus.WriteRegister(0x68, 0x0F, []byte{0x88}), qt.IsNil)
+	c.Assert(bus.ReadRegister(0x68, 0x0F, status), qt.IsNil)
+	c.Assert(status[0], qt.Equals, uint8(0x08))
+}
+
+func TestPCF8523Stop(t *testing.T) {
+	c := qt.New(t)
+	clock := newFakeClock()
+	bus := NewI2CBus(c)
+	d := NewPCF8523(c)
+	d.Now = clock.Now
+	bus.AddDevice(d)
+
+	// The clock runs from power-on, with the OS flag set
+	regs := make([]byte, 7)
+	c.Assert(bus.ReadRegister(0x68, 0x03, regs), qt.IsNil)
+	c.Assert(regs, qt.DeepEquals, []byte{0x80, 0, 0, 0x01, 6, 0x01, 0x00})
+	clock.Advance(time.Minute)
+	c.Assert(d.Time(), qt.Equals, time.Date(2000, 1, 1, 0, 1, 0, 0, time.UTC))
+
+	c.Assert(bus.WriteRegister(0x68, 0x00, []byte{0x20}), qt.IsNil)
+	clock.Advance(time.Minute)
+	c.Assert(d.Time(), qt.Equals, time.Date(2000, 1, 1, 0, 1, 0, 0, time.UTC))
+	c.Assert(bus.WriteRegister(0x68, 0x00, []byte{0x00}), qt.IsNil)
+	clock.Advance(time.Minute)
+	c.Assert(d.Time(), qt.Equals, time.Date(2000, 1, 1, 0, 2, 0, 0, time.UTC))
+}
+
+func TestINA219Conversion(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	d := NewINA219(c, 0x40)
+	d.BusVoltage = 5000
+	d.ShuntVoltage = 10000
+	bus.AddDevice(d)
+
+	buf := make([]byte, 2)
+	// Calibration LSB is read-only
+	c.Assert(bus.WriteRegister(0x40, 0x05, []byte{0x10, 0x01}), qt.IsNil)
+	c.Assert(bus.ReadRegister(0x40, 0x05, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, []byte{0x10, 0x00})
+
+	c.Assert(bus.ReadRegister(0x40, 0x02, buf), qt.IsNil)
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(1250<<3|0x2))
+	c.Assert(bus.ReadRegister(0x40, 0x04, buf), qt.IsNil)
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(1000))
+	c.Assert(bus.ReadRegister(0x40, 0x03, buf), qt.IsNil)
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(250))
+
+	// Triggered bus voltage conversion
+	d.BusVoltage = 3300
+	c.Assert(bus.WriteRegister(0x40, 0x00, []byte{0x39, 0x9A}), qt.IsNil)
+	d.BusVoltage = 1200
+	c.Assert(bus.ReadRegister(0x40, 0x02, buf), qt.IsNil)
+	c.Assert(uint16(buf[0])<<8|uint16(buf[1]), qt.Equals, uint16(825<<3|0x2))
+	c.Assert(bus.ReadRegister(0x40, 0x03, buf), qt.IsNil)
+	c.Assert(bus.ReadRegister(0x40, 0x02, buf), qt.IsNil)
+	c.Assert(buf[1]&0x2, qt.Equals, uint8(0), qt.Commentf("CNVR cleared by reading power"))
+}
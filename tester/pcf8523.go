@@ -0,0 +1,141 @@
+package tester
+
+import "time"
+
+const (
+	pcf8523Address = 0x68
+
+	pcf8523RegControl1 = 0x00
+	pcf8523RegControl3 = 0x02
+	pcf8523RegSeconds  = 0x03
+	pcf8523RegWeekday  = 0x07
+	pcf8523RegYears    = 0x09
+	pcf8523RegTmrCtrl  = 0x0F
+	pcf8523RegLast     = 0x13
+
+	pcf8523Stop  = 0x20 // Control_1 STOP bit
+	pcf8523OS    = 0x80 // Seconds OS flag
+	pcf8523Reset = 0x58 // software reset command
+)
+
+// PCF8523 simulates a PCF8523 real-time clock.
+//
+// The clock runs from the time source Now, unless stopped with the STOP
+// bit. It reports an oscillator stop (OS flag) until its seconds are
+// written, like after a power loss.
+type PCF8523 struct {
+	I2CDevice8
+	// Now returns the host time the clock runs from. It defaults to
+	// time.Now and can be replaced to control the simulated time.
+	Now func() time.Time
+
+	clock   rtc
+	weekday uint8
+}
+
+// NewPCF8523 returns a simulated PCF8523 at its fixed address, in its
+// power-on state.
+func NewPCF8523(c Failer) *PCF8523 {
+	d := &PCF8523{
+		I2CDevice8: *NewI2CDevice8(c, pcf8523Address),
+	}
+	d.reset()
+	d.Registers[pcf8523RegSeconds] = pcf8523OS
+	d.clock.set = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.weekday = 6
+	return d
+}
+
+// Time returns the current time of the clock.
+func (d *PCF8523) Time() time.Time {
+	return d.clock.time(now(d.Now))
+}
+
+// readRegister implements I2C.ReadRegister.
+func (d *PCF8523) readRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.assertRange(r, buf)
+	d.update()
+	return d.I2CDevice8.readRegister(r, buf)
+}
+
+// writeRegister implements I2C.WriteRegister.
+func (d *PCF8523) writeRegister(r uint8, buf []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	d.assertRange(r, buf)
+	if r == pcf8523RegControl1 && len(buf) > 0 && buf[0] == pcf8523Reset {
+		d.reset()
+		return nil
+	}
+	d.update()
+	if err := d.I2CDevice8.writeRegister(r, buf); err != nil {
+		return err
+	}
+
+	end := int(r) + len(buf)
+	if int(r) <= pcf8523RegYears && end > pcf8523RegSeconds {
+		d.setTime()
+	}
+	if r == pcf8523RegControl1 {
+		d.clock.run(d.Registers[pcf8523RegControl1]&pcf8523Stop == 0, now(d.Now))
+	}
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *PCF8523) Tx(w, r []byte) error {
+	return registerTx(d.c, d, w, r)
+}
+
+// reset sets the control registers to their reset values. Time registers
+// are kept.
+func (d *PCF8523) reset() {
+	for r := pcf8523RegControl1; r <= pcf8523RegControl3; r++ {
+		d.Registers[r] = 0
+	}
+	d.Registers[pcf8523RegControl3] = 0xE0
+	for r := pcf8523RegYears + 1; r <= pcf8523RegLast; r++ {
+		d.Registers[r] = 0
+	}
+	for r := pcf8523RegYears + 1; r < pcf8523RegYears+5; r++ {
+		// alarms disabled
+		d.Registers[r] = 0x80
+	}
+	d.Registers[pcf8523RegTmrCtrl] = 0x38
+	d.Registers[pcf8523RegTmrCtrl+1] = 0x07
+	d.Registers[pcf8523RegTmrCtrl+3] = 0x07
+	d.clock.run(true, now(d.Now))
+}
+
+// setTime starts the clock from the time registers.
+func (d *PCF8523) setTime() {
+	regs := d.Registers[pcf8523RegSeconds:]
+	t := time.Date(2000+fromBCD(regs[6]), time.Month(fromBCD(regs[5]&0x1F)), fromBCD(regs[3]&0x3F),
+		fromBCD(regs[2]&0x3F), fromBCD(regs[1]&0x7F), fromBCD(regs[0]&0x7F), 0, time.UTC)
+	d.clock.setTime(t, now(d.Now))
+	d.weekday = regs[pcf8523RegWeekday-pcf8523RegSeconds] & 0x07
+}
+
+// update refreshes the time registers.
+func (d *PCF8523) update() {
+	t := d.clock.time(now(d.Now))
+	regs := d.Registers[pcf8523RegSeconds:]
+	regs[0] = regs[0]&pcf8523OS | toBCD(t.Second())
+	regs[1] = toBCD(t.Minute())
+	regs[2] = toBCD(t.Hour())
+	regs[3] = toBCD(t.Day())
+	regs[4] = uint8((int(d.weekday) + d.clock.days(t)) % 7)
+	regs[5] = toBCD(int(t.Month()))
+	regs[6] = toBCD(t.Year() % 100)
+}
+
+// assertRange asserts that the accessed registers exist.
+func (d *PCF8523) assertRange(r uint8, buf []byte) {
+	if int(r)+len(buf) > pcf8523RegLast+1 {
+		d.c.Fatalf("register read/write [%#x, %#x] out of range", r, int(r)+len(buf))
+	}
+}
@@ -0,0 +1,228 @@
+package tester
+
+import "time"
+
+// SHT3x simulates a SHT3x humidity and temperature sensor, in single shot
+// mode.
+//
+// A measurement is available once its duration has elapsed according to the
+// time source Now. Until then the sensor does not acknowledge reads, unless
+// the measurement was started with clock stretching enabled. Data words are
+// followed by their CRC.
+type SHT3x struct {
+	c Failer
+	// addr is the i2c device address.
+	addr uint8
+	// Now returns the host time measurements run on. It defaults to
+	// time.Now and can be replaced to control the simulated time.
+	Now func() time.Time
+	// Temperature is the measured temperature in milli degrees Celsius.
+	Temperature int32
+	// Humidity is the measured relative humidity in milli percent.
+	Humidity int32
+	// Status holds the status register.
+	Status uint16
+	// If Err is non-nil, it will be returned as the error from Tx.
+	Err error
+
+	response []byte
+	readyAt  time.Time
+	stretch  bool
+}
+
+// NewSHT3x returns a simulated SHT3x at the given address, in its power-on
+// state.
+func NewSHT3x(c Failer, addr uint8) *SHT3x {
+	return &SHT3x{
+		c:      c,
+		addr:   addr,
+		Status: 0x8010,
+	}
+}
+
+// Addr returns the Device address.
+func (d *SHT3x) Addr() uint8 {
+	return d.addr
+}
+
+func (d *SHT3x) readRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("sht3x mock: not a register device")
+	return nil
+}
+
+func (d *SHT3x) writeRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("sht3x mock: not a register device")
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *SHT3x) Tx(w, r []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if len(w) > 0 {
+		if len(w) != 2 {
+			d.c.Fatalf("sht3x mock: command [%#x] is not 16-bit", w)
+		}
+		d.command(uint16(w[0])<<8 | uint16(w[1]))
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return sensirionRead(d.c, &d.response, r, d.stretch || !now(d.Now).Before(d.readyAt))
+}
+
+// command executes a command sent to the sensor.
+func (d *SHT3x) command(cmd uint16) {
+	d.response = nil
+	d.stretch = false
+	switch cmd {
+	case 0x2400, 0x2C06: // high repeatability
+		d.measure(15500*time.Microsecond, cmd&0x0800 != 0)
+	case 0x240B, 0x2C0D: // medium repeatability
+		d.measure(6500*time.Microsecond, cmd&0x0800 != 0)
+	case 0x2416, 0x2C10: // low repeatability
+		d.measure(4500*time.Microsecond, cmd&0x0800 != 0)
+	case 0xF32D: // read status
+		d.response = appendWord(nil, d.Status)
+		d.readyAt = time.Time{}
+	case 0x3041: // clear status
+		d.Status &^= 0x8C10
+	case 0x306D: // heater on
+		d.Status |= 1 << 13
+	case 0x3066: // heater off
+		d.Status &^= 1 << 13
+	case 0x30A2: // soft reset
+		d.Status = 0x0010
+	default:
+		d.c.Fatalf("sht3x mock: unknown command %#04x", cmd)
+	}
+}
+
+// measure starts a measurement lasting duration.
+func (d *SHT3x) measure(duration time.Duration, stretch bool) {
+	d.response = appendWord(nil, scaleTicks(d.Temperature, -45000, 175000))
+	d.response = appendWord(d.response, scaleTicks(d.Humidity, 0, 100000))
+	d.readyAt = now(d.Now).Add(duration)
+	d.stretch = stretch
+}
+
+// SHT4x simulates a SHT4x humidity and temperature sensor.
+//
+// A measurement is available once its duration has elapsed according to the
+// time source Now. Until then the sensor does not acknowledge reads. Data
+// words are followed by their CRC.
+type SHT4x struct {
+	c Failer
+	// addr is the i2c device address.
+	addr uint8
+	// Now returns the host time measurements run on. It defaults to
+	// time.Now and can be replaced to control the simulated time.
+	Now func() time.Time
+	// Temperature is the measured temperature in milli degrees Celsius.
+	Temperature int32
+	// Humidity is the measured relative humidity in milli percent.
+	Humidity int32
+	// Serial is the serial number returned by the sensor.
+	Serial uint32
+	// If Err is non-nil, it will be returned as the error from Tx.
+	Err error
+
+	response []byte
+	readyAt  time.Time
+}
+
+// NewSHT4x returns a simulated SHT4x at the given address.
+func NewSHT4x(c Failer, addr uint8) *SHT4x {
+	return &SHT4x{
+		c:    c,
+		addr: addr,
+	}
+}
+
+// Addr returns the Device address.
+func (d *SHT4x) Addr() uint8 {
+	return d.addr
+}
+
+func (d *SHT4x) readRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("sht4x mock: not a register device")
+	return nil
+}
+
+func (d *SHT4x) writeRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("sht4x mock: not a register device")
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *SHT4x) Tx(w, r []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if len(w) > 0 {
+		if len(w) != 1 {
+			d.c.Fatalf("sht4x mock: command [%#x] is not 8-bit", w)
+		}
+		d.command(w[0])
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return sensirionRead(d.c, &d.response, r, !now(d.Now).Before(d.readyAt))
+}
+
+// command executes a command sent to the sensor.
+func (d *SHT4x) command(cmd uint8) {
+	d.response = nil
+	d.readyAt = time.Time{}
+	switch cmd {
+	case 0xFD: // high precision
+		d.measure(8300 * time.Microsecond)
+	case 0xF6: // medium precision
+		d.measure(4500 * time.Microsecond)
+	case 0xE0: // low precision
+		d.measure(1700 * time.Microsecond)
+	case 0x89: // read serial number
+		d.response = appendWord(nil, uint16(d.Serial>>16))
+		d.response = appendWord(d.response, uint16(d.Serial))
+		d.readyAt = now(d.Now).Add(time.Millisecond)
+	case 0x94: // soft reset
+	default:
+		d.c.Fatalf("sht4x mock: unknown command %#02x", cmd)
+	}
+}
+
+// measure starts a measurement lasting duration.
+func (d *SHT4x) measure(duration time.Duration) {
+	d.response = appendWord(nil, scaleTicks(d.Temperature, -45000, 175000))
+	d.response = appendWord(d.response, scaleTicks(d.Humidity, -6000, 125000))
+	d.readyAt = now(d.Now).Add(duration)
+}
+
+// sensirionRead reads a pending response of a Sensirion sensor into r. The
+// read is not acknowledged if there is no response or it is not ready yet.
+func sensirionRead(c Failer, response *[]byte, r []byte, ready bool) error {
+	if len(*response) == 0 || !ready {
+		return ErrNACK
+	}
+	if len(r) > len(*response) {
+		c.Fatalf("read too large (expected: <= %#x, got: %#x)", len(*response), len(r))
+	}
+	copy(r, *response)
+	*response = nil
+	return nil
+}
+
+// scaleTicks converts a value to the 16-bit ticks of Sensirion sensors,
+// where 0 and 65535 map to offset and offset+span.
+func scaleTicks(v, offset, span int32) uint16 {
+	ticks := (int64(v-offset)*65535 + int64(span)/2) / int64(span)
+	if ticks < 0 {
+		return 0
+	}
+	if ticks > 65535 {
+		return 65535
+	}
+	return uint16(ticks)
+}
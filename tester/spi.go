@@ -0,0 +1,127 @@
+package tester
+
+import "bytes"
+
+// SPITransfer is a single transaction on a mock SPI bus: the bytes written
+// by the controller and the bytes received at the same time.
+type SPITransfer struct {
+	W []byte
+	R []byte
+}
+
+// SPIDevice is a mock device on a mock SPI bus.
+type SPIDevice interface {
+	// Tx handles one transaction. w holds the written bytes and r, of the
+	// same length, receives the device response.
+	Tx(w, r []byte) error
+}
+
+// SPIBus implements the drivers.SPI interface in memory for testing.
+//
+// SPI has no addressing and chip select is driven outside of the bus, so a
+// SPIBus talks to a single device. When no device has been added, the bus
+// follows the script of transactions set up with Expect.
+type SPIBus struct {
+	c      Failer
+	device SPIDevice
+	script []SPITransfer
+	// Transcript holds every transaction seen on the bus. Bytes written
+	// by a receive-only Tx are recorded as zeros.
+	Transcript []SPITransfer
+	// If Err is non-nil, it will be returned as the error from Tx and
+	// Transfer.
+	Err error
+}
+
+// NewSPIBus returns a new mock SPI bus that uses c to flag errors.
+func NewSPIBus(c Failer) *SPIBus {
+	return &SPIBus{
+		c: c,
+	}
+}
+
+// AddDevice connects a mock device to the mock SPI bus.
+// It panics if a device has already been added.
+func (bus *SPIBus) AddDevice(d SPIDevice) {
+	if bus.device != nil {
+		panic("spi mock: device already added")
+	}
+	bus.device = d
+}
+
+// Expect adds a transaction to the script of the bus: the next unscripted
+// transaction must write w, and receives r. A nil r answers with zeros.
+func (bus *SPIBus) Expect(w, r []byte) *SPIBus {
+	if r != nil && len(r) != len(w) {
+		panic("spi mock: scripted response length differs from write length")
+	}
+	bus.script = append(bus.script, SPITransfer{W: w, R: r})
+	return bus
+}
+
+// AssertDone flags an error if scripted transactions were not performed.
+func (bus *SPIBus) AssertDone() {
+	if len(bus.script) > 0 {
+		bus.c.Fatalf("spi mock: %d expected transactions not performed, next is [%#x]", len(bus.script), bus.script[0].W)
+	}
+}
+
+// Tx implements SPI.Tx.
+func (bus *SPIBus) Tx(w, r []byte) error {
+	if bus.Err != nil {
+		return bus.Err
+	}
+	switch {
+	case w == nil && r == nil:
+		bus.c.Fatalf("spi mock: Tx with no buffer")
+	case w != nil && r != nil && len(w) != len(r):
+		bus.c.Fatalf("spi mock: Tx buffers of different lengths (%d, %d)", len(w), len(r))
+	}
+
+	tx := SPITransfer{W: w, R: r}
+	if w == nil {
+		tx.W = make([]byte, len(r))
+	}
+	if r == nil {
+		tx.R = make([]byte, len(w))
+	}
+	if err := bus.handle(tx); err != nil {
+		return err
+	}
+	bus.Transcript = append(bus.Transcript, SPITransfer{
+		W: append([]byte{}, tx.W...),
+		R: append([]byte{}, tx.R...),
+	})
+	return nil
+}
+
+// Transfer implements SPI.Transfer.
+func (bus *SPIBus) Transfer(b byte) (byte, error) {
+	var r [1]byte
+	err := bus.Tx([]byte{b}, r[:])
+	return r[0], err
+}
+
+// handle passes a transaction to the device, or checks it against the
+// script.
+func (bus *SPIBus) handle(tx SPITransfer) error {
+	if bus.device != nil {
+		return bus.device.Tx(tx.W, tx.R)
+	}
+	if len(bus.script) == 0 {
+		bus.c.Fatalf("spi mock: unexpected transaction [%#x]", tx.W)
+	}
+	next := bus.script[0]
+	bus.script = bus.script[1:]
+	if !bytes.Equal(tx.W, next.W) {
+		bus.c.Fatalf("spi mock: unexpected transaction [%#x], expected [%#x]", tx.W, next.W)
+	}
+	if next.R != nil {
+		copy(tx.R, next.R)
+	} else {
+		for i := range tx.R {
+			tx.R[i] = 0
+		}
+	}
+	return nil
+}
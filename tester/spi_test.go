@@ -0,0 +1,133 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+)
+
+var _ drivers.SPI = (*SPIBus)(nil)
+
+func TestSPIScript(t *testing.T) {
+	c := qt.New(t)
+	bus := NewSPIBus(c)
+	bus.Expect([]byte{0x01, 0x80, 0x00}, []byte{0x00, 0x02, 0x34}).
+		Expect([]byte{0x9F}, nil)
+
+	r := make([]byte, 3)
+	c.Assert(bus.Tx([]byte{0x01, 0x80, 0x00}, r), qt.IsNil)
+	c.Assert(r, qt.DeepEquals, []byte{0x00, 0x02, 0x34})
+	b, err := bus.Transfer(0x9F)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b, qt.Equals, byte(0))
+	bus.AssertDone()
+
+	c.Assert(bus.Transcript, qt.DeepEquals, []SPITransfer{
+		{W: []byte{0x01, 0x80, 0x00}, R: []byte{0x00, 0x02, 0x34}},
+		{W: []byte{0x9F}, R: []byte{0x00}},
+	})
+}
+
+func TestSPIScriptUnexpected(t *testing.T) {
+	c := qt.New(t)
+	f := &fatalRecorder{}
+	bus := NewSPIBus(f)
+	bus.Expect([]byte{0x01}, nil)
+
+	f.run(func() { bus.Tx([]byte{0x02}, nil) })
+	c.Assert(f.msg, qt.Equals, "spi mock: unexpected transaction [0x02], expected [0x01]")
+	f.run(func() { bus.Tx([]byte{0x03}, nil) })
+	c.Assert(f.msg, qt.Equals, "spi mock: unexpected transaction [0x03]")
+	f.run(func() { bus.Tx([]byte{0x03}, make([]byte, 2)) })
+	c.Assert(f.msg, qt.Equals, "spi mock: Tx buffers of different lengths (1, 2)")
+
+	bus.Expect([]byte{0x01}, nil)
+	f.run(bus.AssertDone)
+	c.Assert(f.msg, qt.Equals, "spi mock: 1 expected transactions not performed, next is [0x01]")
+
+	bus.Err = errors.New("bus error")
+	c.Assert(bus.Tx([]byte{0x01}, nil), qt.Equals, bus.Err)
+}
+
+func TestSPIDevice8(t *testing.T) {
+	c := qt.New(t)
+	bus := NewSPIBus(c)
+	d := NewSPIDevice8(c)
+	bus.AddDevice(d)
+	d.Registers[0x0F] = 0x33
+
+	// Read with the command byte in the same transaction
+	r := make([]byte, 2)
+	c.Assert(bus.Tx([]byte{0x8F, 0x00}, r), qt.IsNil)
+	c.Assert(r, qt.DeepEquals, []byte{0x00, 0x33})
+
+	// Burst write, then read with the command byte sent separately
+	c.Assert(bus.Tx([]byte{0x20, 0x47, 0x48}, nil), qt.IsNil)
+	c.Assert(d.Registers[0x20:0x22], qt.DeepEquals, []byte{0x47, 0x48})
+	c.Assert(bus.Tx([]byte{0xA0}, nil), qt.IsNil)
+	r = make([]byte, 2)
+	c.Assert(bus.Tx(nil, r), qt.IsNil)
+	c.Assert(r, qt.DeepEquals, []byte{0x47, 0x48})
+
+	c.Assert(bus.Transcript, qt.HasLen, 4)
+	c.Assert(bus.Transcript[3], qt.DeepEquals, SPITransfer{W: []byte{0, 0}, R: []byte{0x47, 0x48}})
+}
+
+func TestSPIDevice8NoCommand(t *testing.T) {
+	c := qt.New(t)
+	f := &fatalRecorder{}
+	bus := NewSPIBus(f)
+	bus.AddDevice(NewSPIDevice8(f))
+
+	f.run(func() { bus.Tx([]byte{}, nil) })
+	c.Assert(f.msg, qt.Equals, "spi mock: transaction without command byte")
+}
+
+func TestSPIDevice8WriteFlag(t *testing.T) {
+	c := qt.New(t)
+	bus := NewSPIBus(c)
+	d := NewSPIDevice8(c)
+	d.ReadFlag, d.WriteFlag = 0, 0x80
+	bus.AddDevice(d)
+
+	// sx127x style register access, one byte at a time
+	_, err := bus.Transfer(0x81)
+	c.Assert(err, qt.IsNil)
+	_, err = bus.Transfer(0x8C)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Registers[0x01], qt.Equals, uint8(0x8C))
+
+	_, err = bus.Transfer(0x01)
+	c.Assert(err, qt.IsNil)
+	b, err := bus.Transfer(0x00)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b, qt.Equals, uint8(0x8C))
+}
+
+// fatalRecorder is a Failer that records the message of a failure and
+// stops the function under test.
+type fatalRecorder struct {
+	msg string
+}
+
+type fatalError struct{}
+
+func (f *fatalRecorder) Fatalf(format string, a ...interface{}) {
+	f.msg = fmt.Sprintf(format, a...)
+	panic(fatalError{})
+}
+
+func (f *fatalRecorder) run(fn func()) {
+	f.msg = ""
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(fatalError); !ok {
+				panic(r)
+			}
+		}
+	}()
+	fn()
+}
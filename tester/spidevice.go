@@ -0,0 +1,81 @@
+package tester
+
+// SPIDevice8 represents a mock SPI device with 8-bit registers.
+//
+// Each transaction starts with a command byte holding the register address
+// and the read/write flag, followed by data bytes read from or written to
+// consecutive registers. Many drivers send the command byte and the data in
+// separate Tx calls while holding chip select low, so a transaction made of
+// the command byte alone is continued by the next one.
+type SPIDevice8 struct {
+	c Failer
+	// Registers holds the device registers. It can be inspected
+	// or changed as desired for testing.
+	Registers [MaxRegisters]uint8
+	// ReadFlag is the bit set in the command byte of register reads.
+	// It is only used when WriteFlag is zero.
+	ReadFlag uint8
+	// WriteFlag is the bit set in the command byte of register writes.
+	WriteFlag uint8
+	// If Err is non-nil, it will be returned as the error from Tx.
+	Err error
+
+	pending bool
+	write   bool
+	reg     int
+}
+
+// NewSPIDevice8 returns a new mock SPI device. It uses the most common
+// command format, where bit 7 of the command byte is set for reads.
+func NewSPIDevice8(c Failer) *SPIDevice8 {
+	return &SPIDevice8{
+		c:        c,
+		ReadFlag: 0x80,
+	}
+}
+
+// Tx implements SPIDevice.Tx.
+func (d *SPIDevice8) Tx(w, r []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	for i := range r {
+		r[i] = 0
+	}
+	if !d.pending {
+		if len(w) == 0 {
+			d.c.Fatalf("spi mock: transaction without command byte")
+			return nil
+		}
+		d.command(w[0])
+		w, r = w[1:], r[1:]
+		if len(w) == 0 {
+			d.pending = true
+			return nil
+		}
+	}
+	d.pending = false
+
+	if d.reg+len(w) > len(d.Registers) {
+		d.c.Fatalf("register read/write [%#x, %#x] end out of range", d.reg, d.reg+len(w))
+	}
+	if d.write {
+		copy(d.Registers[d.reg:], w)
+	} else {
+		copy(r, d.Registers[d.reg:])
+	}
+	return nil
+}
+
+// command decodes the command byte of a transaction.
+func (d *SPIDevice8) command(cmd uint8) {
+	if d.WriteFlag != 0 {
+		d.write = cmd&d.WriteFlag != 0
+	} else {
+		d.write = cmd&d.ReadFlag == 0
+	}
+	d.reg = int(cmd &^ (d.ReadFlag | d.WriteFlag))
+	if d.reg >= len(d.Registers) {
+		d.c.Fatalf("register read/write %#x start out of range", d.reg)
+	}
+}
@@ -0,0 +1,186 @@
+package tester
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"tinygo.org/x/drivers"
+)
+
+// I2CTraceEntry is a transaction of an I2C trace.
+//
+// In a trace file, each transaction takes a line with the device address,
+// the written bytes, the read bytes if any, and the error if the
+// transaction failed:
+//
+//	0x76 w=d0 r=60
+//	0x76 w=f460
+//	0x44 w= r=0000 err="i2c: no acknowledge"
+//
+// Empty lines and lines starting with # are ignored.
+type I2CTraceEntry struct {
+	Addr uint16
+	W    []byte
+	R    []byte
+	Err  string
+}
+
+// String returns the trace line of the transaction.
+func (e I2CTraceEntry) String() string {
+	s := fmt.Sprintf("%#02x w=%x", e.Addr, e.W)
+	if len(e.R) > 0 {
+		s += fmt.Sprintf(" r=%x", e.R)
+	}
+	if e.Err != "" {
+		s += " err=" + strconv.Quote(e.Err)
+	}
+	return s
+}
+
+// ParseI2CTraceEntry parses a trace line.
+func ParseI2CTraceEntry(line string) (e I2CTraceEntry, err error) {
+	addr, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	a, err := strconv.ParseUint(addr, 0, 16)
+	if err != nil {
+		return e, fmt.Errorf("invalid address %q", addr)
+	}
+	e.Addr = uint16(a)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		var field string
+		field, rest, _ = strings.Cut(rest, "=")
+		switch field {
+		case "w", "r":
+			var value string
+			value, rest, _ = strings.Cut(rest, " ")
+			b, err := hex.DecodeString(value)
+			if err != nil {
+				return e, fmt.Errorf("invalid %s bytes %q", field, value)
+			}
+			if field == "w" {
+				e.W = b
+			} else {
+				e.R = b
+			}
+		case "err":
+			value, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return e, fmt.Errorf("invalid error %q", rest)
+			}
+			rest = rest[len(value):]
+			e.Err, _ = strconv.Unquote(value)
+		default:
+			return e, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return e, nil
+}
+
+// ReadI2CTrace reads all the transactions of a trace.
+func ReadI2CTrace(r io.Reader) ([]I2CTraceEntry, error) {
+	var trace []I2CTraceEntry
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		e, err := ParseI2CTraceEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		trace = append(trace, e)
+	}
+	return trace, s.Err()
+}
+
+// I2CRecorder wraps an I2C bus and writes every transaction going through
+// it to a trace. It can be used on hardware, with any bus passed to a
+// driver, to capture a trace for I2CReplay.
+type I2CRecorder struct {
+	bus drivers.I2C
+	w   io.Writer
+	err error
+}
+
+// NewI2CRecorder returns a recorder of the transactions on bus, that
+// writes the trace to w.
+func NewI2CRecorder(bus drivers.I2C, w io.Writer) *I2CRecorder {
+	return &I2CRecorder{
+		bus: bus,
+		w:   w,
+	}
+}
+
+// Tx implements I2C.Tx.
+func (rec *I2CRecorder) Tx(addr uint16, w, r []byte) error {
+	err := rec.bus.Tx(addr, w, r)
+	e := I2CTraceEntry{Addr: addr, W: w, R: r}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	if _, werr := io.WriteString(rec.w, e.String()+"\n"); werr != nil && rec.err == nil {
+		rec.err = werr
+	}
+	return err
+}
+
+// Err returns the first error that happened while writing the trace.
+func (rec *I2CRecorder) Err() error {
+	return rec.err
+}
+
+// I2CReplay implements the I2C interface by playing back a trace. Each
+// transaction must match the next one of the trace: same address, same
+// written bytes and same read length. It then receives the recorded bytes
+// and error.
+type I2CReplay struct {
+	c     Failer
+	trace []I2CTraceEntry
+	pos   int
+}
+
+// NewI2CReplay returns an I2C bus that plays back the trace read from r.
+func NewI2CReplay(c Failer, r io.Reader) *I2CReplay {
+	trace, err := ReadI2CTrace(r)
+	if err != nil {
+		c.Fatalf("i2c replay: %v", err)
+	}
+	return &I2CReplay{
+		c:     c,
+		trace: trace,
+	}
+}
+
+// Tx implements I2C.Tx.
+func (rp *I2CReplay) Tx(addr uint16, w, r []byte) error {
+	got := I2CTraceEntry{Addr: addr, W: w, R: make([]byte, len(r))}
+	if rp.pos >= len(rp.trace) {
+		rp.c.Fatalf("i2c replay: unexpected transaction after the end of the trace: %v", got)
+	}
+	e := rp.trace[rp.pos]
+	rp.pos++
+	if addr != e.Addr || !bytes.Equal(w, e.W) || len(r) != len(e.R) {
+		want := e
+		want.R = make([]byte, len(e.R))
+		want.Err = ""
+		rp.c.Fatalf("i2c replay: transaction %d diverges from the trace\ngot:  %v\nwant: %v", rp.pos, got, want)
+	}
+	copy(r, e.R)
+	if e.Err != "" {
+		return errors.New(e.Err)
+	}
+	return nil
+}
+
+// AssertDone flags an error if transactions of the trace were not played.
+func (rp *I2CReplay) AssertDone() {
+	if rp.pos < len(rp.trace) {
+		rp.c.Fatalf("i2c replay: %d transactions of the trace not played, next is %v", len(rp.trace)-rp.pos, rp.trace[rp.pos])
+	}
+}
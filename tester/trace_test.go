@@ -0,0 +1,69 @@
+package tester
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+)
+
+var _ drivers.I2C = (*I2CRecorder)(nil)
+var _ drivers.I2C = (*I2CReplay)(nil)
+
+func TestI2CRecord(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	bus.NACK = true
+	d := bus.NewDevice(0x76)
+	d.Registers[0xD0] = 0x60
+
+	var trace strings.Builder
+	rec := NewI2CRecorder(bus, &trace)
+	buf := make([]byte, 1)
+	c.Assert(rec.Tx(0x76, []byte{0xD0}, buf), qt.IsNil)
+	c.Assert(rec.Tx(0x76, []byte{0xF4, 0x27}, nil), qt.IsNil)
+	c.Assert(rec.Tx(0x77, []byte{0xD0}, make([]byte, 1)), qt.Equals, ErrNACK)
+	c.Assert(rec.Err(), qt.IsNil)
+
+	c.Assert(trace.String(), qt.Equals, `0x76 w=d0 r=60
+0x76 w=f427
+0x77 w=d0 r=00 err="i2c mock: no acknowledge"
+`)
+}
+
+func TestI2CReplay(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CReplay(c, strings.NewReader(`
+# BME280 chip ID
+0x76 w=d0 r=60
+0x76 w=f427
+
+0x44 w= r=000000 err="timeout"
+`))
+
+	buf := make([]byte, 1)
+	c.Assert(bus.Tx(0x76, []byte{0xD0}, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, []byte{0x60})
+	c.Assert(bus.Tx(0x76, []byte{0xF4, 0x27}, nil), qt.IsNil)
+	c.Assert(bus.Tx(0x44, nil, make([]byte, 3)), qt.ErrorMatches, "timeout")
+	bus.AssertDone()
+}
+
+func TestI2CReplayDivergence(t *testing.T) {
+	c := qt.New(t)
+	f := &fatalRecorder{}
+	bus := NewI2CReplay(f, strings.NewReader("0x76 w=d0 r=60\n"))
+
+	f.run(func() { bus.Tx(0x76, []byte{0xD1}, make([]byte, 1)) })
+	c.Assert(f.msg, qt.Equals, "i2c replay: transaction 1 diverges from the trace\ngot:  0x76 w=d1 r=00\nwant: 0x76 w=d0 r=00")
+	f.run(func() { bus.Tx(0x76, []byte{0xD0}, make([]byte, 1)) })
+	c.Assert(f.msg, qt.Equals, "i2c replay: unexpected transaction after the end of the trace: 0x76 w=d0 r=00")
+
+	bus = NewI2CReplay(f, strings.NewReader("0x76 w=d0 r=60\n"))
+	f.run(bus.AssertDone)
+	c.Assert(f.msg, qt.Equals, "i2c replay: 1 transactions of the trace not played, next is 0x76 w=d0 r=60")
+
+	f.run(func() { NewI2CReplay(f, strings.NewReader("0x76 w=d0 x=60\n")) })
+	c.Assert(f.msg, qt.Equals, `i2c replay: line 1: unknown field "x"`)
+}
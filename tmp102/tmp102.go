@@ -11,8 +11,9 @@ import (
 
 // Device holds the already configured I2C bus and the address of the sensor.
 type Device struct {
-	bus     drivers.I2C
-	address uint8
+	bus         drivers.I2C
+	address     uint8
+	temperature int32
 }
 
 // Config is the configuration for the TMP102.
@@ -69,3 +70,22 @@ func (d *Device) ReadTemperature() (temperature int32, err error) {
 
 	return temperature / 10, nil
 }
+
+// Update reads the temperature and stores it for the Temperature method.
+func (d *Device) Update(which drivers.Measurement) error {
+	if which&drivers.Temperature == 0 {
+		return nil
+	}
+	temperature, err := d.ReadTemperature()
+	if err != nil {
+		return err
+	}
+	d.temperature = temperature
+	return nil
+}
+
+// Temperature returns the temperature read in the last Update call, in
+// celsius milli degrees (°C/1000).
+func (d *Device) Temperature() int32 {
+	return d.temperature
+}
@@ -0,0 +1,13 @@
+package wifinina
+
+import "net"
+
+// hardwareAddr returns the MAC address sent by NINA, least significant byte
+// first. The address is copied, as b is usually the shared SPI buffer.
+func hardwareAddr(b []byte) net.HardwareAddr {
+	mac := make(net.HardwareAddr, len(b))
+	for i := range b {
+		mac[i] = b[len(b)-1-i]
+	}
+	return mac
+}
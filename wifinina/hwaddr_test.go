@@ -0,0 +1,21 @@
+package wifinina
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestHardwareAddr(t *testing.T) {
+	c := qt.New(t)
+
+	// BSSIDs of two scanned networks, read one after the other in the
+	// same SPI buffer
+	buf := []byte{0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	first := hardwareAddr(buf)
+	copy(buf, []byte{0x16, 0x15, 0x14, 0x13, 0x12, 0x11})
+	second := hardwareAddr(buf)
+
+	c.Assert(first.String(), qt.Equals, "01:02:03:04:05:06")
+	c.Assert(second.String(), qt.Equals, "11:12:13:14:15:16")
+}
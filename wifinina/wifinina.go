@@ -1,3 +1,5 @@
+//go:build tinygo
+
 // Package wifinina implements TCP wireless communication over SPI with an
 // attached separate ESP32 SoC using the Arduino WiFiNINA protocol.
 //
@@ -213,6 +215,7 @@ type wifinina struct {
 	driverShown  bool
 	deviceShown  bool
 	spiSetup     bool
+	started      bool
 
 	killWatchdog chan bool
 	fault        error
@@ -406,11 +409,13 @@ func (w *wifinina) start() {
 
 	w.gpio0.Low()
 	w.gpio0.Configure(machine.PinConfig{Mode: machine.PinInput})
+	w.started = true
 }
 
 func (w *wifinina) stop() {
 	w.resetn.Low()
 	w.cs.Configure(machine.PinConfig{Mode: machine.PinInput})
+	w.started = false
 }
 
 func (w *wifinina) showDevice() {
@@ -571,6 +576,73 @@ func (w *wifinina) NetNotify(cb func(netlink.Event)) {
 	w.notifyCb = cb
 }
 
+func toAuthType(enc encryptionType) netlink.AuthType {
+	switch enc {
+	case encTypeNone:
+		return netlink.AuthTypeOpen
+	case encTypeWEP:
+		return netlink.AuthTypeWEP
+	case encTypeTKIP:
+		return netlink.AuthTypeWPA
+	case encTypeCCMP:
+		return netlink.AuthTypeWPA2
+	case encTypeAuto:
+		return netlink.AuthTypeWPA2Mixed
+	}
+	return netlink.AuthTypeUnknown
+}
+
+func (w *wifinina) NetScan() ([]netlink.AccessPoint, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[NetScan]\r\n")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Start the device, if not running yet. Starting it again would
+	// reset it.
+	if !w.started {
+		w.showDriver()
+		w.setupSPI()
+		w.start()
+	}
+
+	if w.startScanNetworks() != 1 {
+		return nil, netlink.ErrScanFailed
+	}
+
+	// The scan takes a few seconds, until then no networks are found
+	var n uint8
+	for i := 0; i < 10 && n == 0; i++ {
+		// Unlock while we sleep, so others can make progress
+		w.mu.Unlock()
+		time.Sleep(1 * time.Second)
+		w.mu.Lock()
+
+		n = w.scanNetworks()
+	}
+
+	// Check if we've faulted
+	if w.fault != nil {
+		return nil, w.fault
+	}
+
+	aps := make([]netlink.AccessPoint, n)
+	for i := range aps {
+		aps[i] = netlink.AccessPoint{
+			Ssid:     w.getNetworkSSID(i),
+			Bssid:    w.getNetworkBSSID(i),
+			Rssi:     int(w.getNetworkRSSI(i)),
+			Channel:  int(w.getNetworkChannel(i)),
+			AuthType: toAuthType(w.getNetworkEncrType(i)),
+		}
+	}
+
+	return aps, nil
+}
+
 func (w *wifinina) GetHostByName(name string) (netip.Addr, error) {
 
 	if debugging(debugNetdev) {
@@ -1003,6 +1075,12 @@ func (w *wifinina) Recv(sockfd int, buf []byte, flags int,
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	return w.recv(sockfd, buf, deadline)
+}
+
+// recv receives into buf, holding the lock
+func (w *wifinina) recv(sockfd int, buf []byte, deadline time.Time) (int, error) {
+
 	socket, ok := w.sockets[sockfd]
 	if !ok {
 		return -1, netdev.ErrInvalidSocketFd
@@ -1062,6 +1140,77 @@ func (w *wifinina) Recv(sockfd int, buf []byte, flags int,
 	}
 }
 
+// RecvFrom receives a datagram from any address on a bound UDP socket, and
+// returns its source address
+func (w *wifinina) RecvFrom(sockfd int, buf []byte, flags int,
+	deadline time.Time) (int, netip.AddrPort, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[RecvFrom] sockfd: %d, len(buf): %d, flags: %d\r\n",
+			sockfd, len(buf), flags)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	socket, ok := w.sockets[sockfd]
+	if !ok {
+		return -1, netip.AddrPort{}, netdev.ErrInvalidSocketFd
+	}
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netip.AddrPort{}, netdev.ErrProtocolNotSupported
+	}
+
+	n, err := w.recv(sockfd, buf, deadline)
+	if err != nil {
+		return n, netip.AddrPort{}, err
+	}
+
+	// The remote address of the datagram just received
+	from := w.getRemoteData(socket.sock)
+	if w.fault != nil {
+		return -1, netip.AddrPort{}, w.fault
+	}
+
+	return n, from, nil
+}
+
+// SendTo sends a datagram to any address on a bound UDP socket
+func (w *wifinina) SendTo(sockfd int, buf []byte, flags int, to netip.AddrPort,
+	deadline time.Time) (int, error) {
+
+	if debugging(debugNetdev) {
+		fmt.Printf("[SendTo] sockfd: %d, len(buf): %d, flags: %d, to: %s\r\n",
+			sockfd, len(buf), flags, to)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	socket, ok := w.sockets[sockfd]
+	if !ok {
+		return -1, netdev.ErrInvalidSocketFd
+	}
+
+	if socket.protocol != netdev.IPPROTO_UDP {
+		return -1, netdev.ErrProtocolNotSupported
+	}
+
+	if socket.sock == noSocketAvail {
+		return -1, fmt.Errorf("Must Bind before Sending")
+	}
+
+	// Check if we've timed out
+	if !deadline.IsZero() {
+		if time.Now().After(deadline) {
+			return -1, netdev.ErrTimeout
+		}
+	}
+
+	return w.sendUDP(socket.sock, to, buf, deadline)
+}
+
 func (w *wifinina) Close(sockfd int) error {
 
 	if debugging(debugNetdev) {
@@ -1568,12 +1717,7 @@ func (w *wifinina) getFloat32(l uint8) float32 {
 
 func (w *wifinina) getMACAddress(l uint8) net.HardwareAddr {
 	if l == 6 {
-		mac := w.buf[0:6]
-		// Reverse the bytes
-		for i, j := 0, len(mac)-1; i < j; i, j = i+1, j-1 {
-			mac[i], mac[j] = mac[j], mac[i]
-		}
-		return mac
+		return hardwareAddr(w.buf[0:6])
 	}
 	w.faultf("expected length 6, was actually %d", l)
 	return net.HardwareAddr{}
@@ -1957,7 +2101,10 @@ func (w *wifinina) waitRspStr(cmd uint8, sl []string) (numRead uint8) {
 
 	numRead = w.transfer(dummyData)
 	if numRead == 0 {
-		w.faultf("waitRspStr numRead == 0")
+		// No networks is not a fault while scanning
+		if cmd != cmdScanNetworks {
+			w.faultf("waitRspStr numRead == 0")
+		}
 		return
 	}
 